@@ -1,6 +1,7 @@
 package component
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	accountTypes "github.com/drep-project/drepcli/accounts/types"
@@ -14,6 +15,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -146,6 +148,27 @@ func (fs FileStore) JoinPath(filename string) string {
 	return filepath.Join(fs.keysDirPath, filename)
 }
 
+// ListKeyStoreAddresses returns the addresses of the keys in the keystore directory
+// without decrypting them, key files are named by their address.
+func ListKeyStoreAddresses(keyStoreDir string) ([]crypto.CommonAddress, error) {
+	addresses := []crypto.CommonAddress{}
+	if !common.IsDirExists(keyStoreDir) {
+		return addresses, nil
+	}
+	err := common.EachChildFile(keyStoreDir, func(path string) (bool, error) {
+		name := filepath.Base(path)
+		if len(name) != 2*crypto.AddressLength || strings.HasPrefix(name, ".") {
+			return true, nil
+		}
+		if _, err := hex.DecodeString(name); err != nil {
+			return true, nil
+		}
+		addresses = append(addresses, crypto.Hex2Address(name))
+		return true, nil
+	})
+	return addresses, err
+}
+
 func writeTemporaryKeyFile(file string, content []byte) (string, error) {
 	// Create the keystore directory with appropriate permissions
 	// in case it is not present yet.
@@ -6,7 +6,6 @@ import (
 	"github.com/drep-project/drepcli/crypto"
 	"github.com/drep-project/drepcli/crypto/secp256k1"
 	"github.com/drep-project/drepcli/crypto/sha3"
-	"github.com/pkg/errors"
 	"sync/atomic"
 )
 
@@ -40,7 +39,7 @@ func NewWallet(config *accountTypes.Config, chainId common.ChainIdType) (*Wallet
 
 func (wallet *Wallet) Open(password string) error {
 	if wallet.cacheStore != nil {
-		return errors.New("wallet is already open")
+		return accountTypes.ErrWalletAlreadyOpen
 	}
 	cryptedPassword := wallet.cryptoPassword(password)
 	accountCacheStore, err := NewAccountCache(wallet.config.KeyStoreDir, cryptedPassword)
@@ -70,14 +69,14 @@ func (wallet *Wallet) NewAccount() (*accountTypes.Node, error) {
 
 func (wallet *Wallet) GetAccountByAddress(addr *crypto.CommonAddress) (*accountTypes.Node, error) {
 	if err := wallet.checkWallet(RPERMISSION); err != nil {
-		return nil, errors.New("wallet is not open")
+		return nil, accountTypes.ErrWalletNotOpen
 	}
 	return wallet.cacheStore.GetKey(addr, wallet.password)
 }
 
 func (wallet *Wallet) GetAccountByPubkey(pubkey *secp256k1.PublicKey) (*accountTypes.Node, error) {
 	if err := wallet.checkWallet(RPERMISSION); err != nil {
-		return nil, errors.New("wallet is not open")
+		return nil, accountTypes.ErrWalletNotOpen
 	}
 	addr := crypto.PubKey2Address(pubkey)
 	return wallet.GetAccountByAddress(&addr)
@@ -85,7 +84,7 @@ func (wallet *Wallet) GetAccountByPubkey(pubkey *secp256k1.PublicKey) (*accountT
 
 func (wallet *Wallet) ListAddress() ([]*crypto.CommonAddress, error) {
 	if err := wallet.checkWallet(RPERMISSION); err != nil {
-		return nil, errors.New("wallet is not open")
+		return nil, accountTypes.ErrWalletNotOpen
 	}
 	nodes, err := wallet.cacheStore.ExportKey(wallet.password)
 	if err != nil {
@@ -142,11 +141,11 @@ func (wallet *Wallet) unLock(password string) error {
 
 func (wallet *Wallet) checkWallet(op int) error {
 	if wallet.cacheStore == nil {
-		return errors.New("wallet is not open")
+		return accountTypes.ErrWalletNotOpen
 	}
 	if op == WPERMISSION {
 		if wallet.IsLock() {
-			return errors.New("wallet is locked")
+			return accountTypes.ErrWalletLocked
 		}
 	}
 	return nil
@@ -2,9 +2,9 @@ package service
 
 import (
 	accountCommponent "github.com/drep-project/drepcli/accounts/component"
+	accountTypes "github.com/drep-project/drepcli/accounts/types"
 	"github.com/drep-project/drepcli/crypto"
 	"github.com/drep-project/drepcli/crypto/secp256k1"
-	"github.com/pkg/errors"
 )
 
 type AccountApi struct {
@@ -13,7 +13,7 @@ type AccountApi struct {
 
 func (accountapi *AccountApi) AddressList() ([]*crypto.CommonAddress, error) {
 	if !accountapi.Wallet.IsOpen() {
-		return nil, errors.New("wallet is not open")
+		return nil, accountTypes.ErrWalletNotOpen
 	}
 	return accountapi.Wallet.ListAddress()
 }
@@ -21,7 +21,7 @@ func (accountapi *AccountApi) AddressList() ([]*crypto.CommonAddress, error) {
 // CreateAccount create a new account and return address
 func (accountapi *AccountApi) CreateAccount() (*crypto.CommonAddress, error) {
 	if !accountapi.Wallet.IsOpen() {
-		return nil, errors.New("wallet is not open")
+		return nil, accountTypes.ErrWalletNotOpen
 	}
 	newAaccount, err := accountapi.Wallet.NewAccount()
 	if err != nil {
@@ -33,10 +33,10 @@ func (accountapi *AccountApi) CreateAccount() (*crypto.CommonAddress, error) {
 // DumpPrikey dumpPrivate
 func (accountapi *AccountApi) DumpPrikey(address *crypto.CommonAddress) (*secp256k1.PrivateKey, error) {
 	if !accountapi.Wallet.IsOpen() {
-		return nil, errors.New("wallet is not open")
+		return nil, accountTypes.ErrWalletNotOpen
 	}
 	if accountapi.Wallet.IsLock() {
-		return nil, errors.New("wallet has locked")
+		return nil, accountTypes.ErrWalletLocked
 	}
 
 	node, err := accountapi.Wallet.GetAccountByAddress(address)
@@ -49,23 +49,23 @@ func (accountapi *AccountApi) DumpPrikey(address *crypto.CommonAddress) (*secp25
 // Lock lock the wallet to protect private key
 func (accountapi *AccountApi) Lock() error {
 	if !accountapi.Wallet.IsOpen() {
-		return errors.New("wallet is not open")
+		return accountTypes.ErrWalletNotOpen
 	}
 	if !accountapi.Wallet.IsLock() {
 		return accountapi.Wallet.Lock()
 	}
-	return errors.New("wallet is already locked")
+	return accountTypes.ErrWalletAlreadyLocked
 }
 
 // UnLock unlock the wallet
 func (accountapi *AccountApi) UnLock(password string) error {
 	if !accountapi.Wallet.IsOpen() {
-		return errors.New("wallet is not open")
+		return accountTypes.ErrWalletNotOpen
 	}
 	if accountapi.Wallet.IsLock() {
 		return accountapi.Wallet.UnLock(password)
 	}
-	return errors.New("wallet is already unlock")
+	return accountTypes.ErrWalletAlreadyUnlocked
 }
 
 func (accountapi *AccountApi) Open(password string) error {
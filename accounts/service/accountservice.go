@@ -77,11 +77,21 @@ func (accountService *AccountService) Init(executeContext *app.ExecuteContext) e
 				Wallet: accountService.wallet,
 			},
 			Public: true,
+			ParamNames: map[string][]string{
+				"dumpPrikey": {"address"},
+				"unLock":     {"password"},
+				"open":       {"password"},
+			},
 		},
 	}
 	return nil
 }
 
+// KeyStoreDir returns the directory of the local keystore, it is known after Init
+func (accountService *AccountService) KeyStoreDir() string {
+	return accountService.config.KeyStoreDir
+}
+
 func (accountService *AccountService) Start(executeContext *app.ExecuteContext) error {
 	return nil
 }
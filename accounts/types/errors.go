@@ -0,0 +1,19 @@
+package types
+
+// WalletError is an error of the wallet state. It carries a JSON-RPC error code,
+// so clients of the account API tell the states apart without matching messages.
+type WalletError struct {
+	Code    int
+	Message string
+}
+
+func (e *WalletError) Error() string  { return e.Message }
+func (e *WalletError) ErrorCode() int { return e.Code }
+
+var (
+	ErrWalletNotOpen         = &WalletError{-32010, "wallet is not open"}
+	ErrWalletLocked          = &WalletError{-32011, "wallet is locked"}
+	ErrWalletAlreadyOpen     = &WalletError{-32012, "wallet is already open"}
+	ErrWalletAlreadyLocked   = &WalletError{-32013, "wallet is already locked"}
+	ErrWalletAlreadyUnlocked = &WalletError{-32014, "wallet is already unlocked"}
+)
@@ -103,6 +103,11 @@ func (mApp DrepApp) Run() error {
 	mApp.Flags = append(mApp.Flags, ConfigFileFlag)
 	mApp.Flags = append(mApp.Flags, mApp.Context.GetFlags()...)
 	mApp.Action = mApp.action
+	for _, service := range mApp.Context.Services {
+		if commandService, ok := service.(CommandService); ok {
+			mApp.Commands = append(mApp.Commands, commandService.Commands(mApp.Context)...)
+		}
+	}
 	if err := mApp.App.Run(os.Args); err != nil {
 		return err
 	}
@@ -152,7 +157,12 @@ func (mApp DrepApp) before(ctx *cli.Context) error {
 	mApp.Context.CommonConfig = &CommonConfig{
 		HomeDir: homeDir,
 	}
-	phaseConfig, err := loadConfigFile(ctx, homeDir)
+	configFile, err := configFilePath(ctx, homeDir)
+	if err != nil {
+		return err
+	}
+	mApp.Context.ConfigFile = configFile
+	phaseConfig, err := loadConfigFile(configFile)
 
 	if err != nil {
 		return err
@@ -162,19 +172,23 @@ func (mApp DrepApp) before(ctx *cli.Context) error {
 	return nil
 }
 
-//	loadConfigFile sed to read configuration files
-func loadConfigFile(ctx *cli.Context, configPath string) (map[string]json.RawMessage, error) {
+// configFilePath returns the configuration file selected by the command line.
+func configFilePath(ctx *cli.Context, configPath string) (string, error) {
 	configFile := filepath.Join(configPath, "config.json")
 
 	if ctx.GlobalIsSet(ConfigFileFlag.Name) {
 		file := ctx.GlobalString(ConfigFileFlag.Name)
 		if common.IsFileExists(file) {
 			//report error when user specify
-			return nil, errors.New("specify config file not exist")
+			return "", errors.New("specify config file not exist")
 		}
 		configFile = file
 	}
+	return configFile, nil
+}
 
+//	loadConfigFile sed to read configuration files
+func loadConfigFile(configFile string) (map[string]json.RawMessage, error) {
 	if !common.IsFileExists(configFile) {
 		//use default
 		return nil, errors.New("config file not found")
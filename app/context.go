@@ -3,6 +3,8 @@ package app
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
 	"github.com/pkg/errors"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -48,6 +50,18 @@ type API struct {
 	Version   string      // api version for DApp's
 	Service   interface{} // receiver instance which holds the methods
 	Public    bool        // indication if the methods must be considered safe for public use
+
+	// ParamNames maps method names to their parameter names, in signature order.
+	// Methods listed here can also be called with by-name (object) params.
+	ParamNames map[string][]string
+
+	// MethodFilter restricts the exposed methods, given as "namespace_method".
+	// All methods are exposed if it is nil.
+	MethodFilter func(method string) bool
+
+	// Timeouts limits the execution time of methods, keyed by method name. The
+	// timeout under "" applies to all methods without their own.
+	Timeouts map[string]time.Duration
 }
 
 // Services can customize their own configuration, command parameters, interfaces, services
@@ -61,10 +75,18 @@ type Service interface {
 	Stop(executeContext *ExecuteContext) error
 }
 
+// CommandService is implemented by services which add subcommands to the application.
+// The commands run instead of the default action, so they are responsible to
+// initialize the services they depend on.
+type CommandService interface {
+	Commands(executeContext *ExecuteContext) []cli.Command
+}
+
 // ExecuteContext centralizes all the data and global parameters of application execution,
 // and each service can read the part it needs.
 type ExecuteContext struct {
 	ConfigPath   string
+	ConfigFile   string        // configuration file the phase configs were read from
 	CommonConfig *CommonConfig //
 	PhaseConfig  map[string]json.RawMessage
 	CliContext   *cli.Context
@@ -103,6 +125,17 @@ func (econtext *ExecuteContext) GetConfig(phaseName string) json.RawMessage {
 	}
 }
 
+// ReloadConfig reads the configuration file again. Services pick up the new
+// phase configs through GetConfig.
+func (econtext *ExecuteContext) ReloadConfig() error {
+	phaseConfig, err := loadConfigFile(econtext.ConfigFile)
+	if err != nil {
+		return err
+	}
+	econtext.PhaseConfig = phaseConfig
+	return nil
+}
+
 // GetFlags aggregate command configuration items required for each service
 func (econtext *ExecuteContext) GetFlags() []cli.Flag {
 	flags := []cli.Flag{}
@@ -121,6 +154,24 @@ func (econtext *ExecuteContext) GetApis() []API {
 	return apis
 }
 
+// InitServices initializes all services except the skipped ones, in the order they were added.
+// It is used by commands which only need part of the application.
+func (econtext *ExecuteContext) InitServices(skip ...string) error {
+	skipped := make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	for _, service := range econtext.Services {
+		if skipped[service.Name()] {
+			continue
+		}
+		if err := service.Init(econtext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //	RequireService When a service depends on another service, RequireService is used to obtain the dependent service.
 func (econtext *ExecuteContext) RequireService(name string) Service {
 	for _, service := range econtext.Services {
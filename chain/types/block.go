@@ -0,0 +1,86 @@
+package types
+
+import (
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+)
+
+// BlockHeader is the header of a DREP block as returned by the node.
+type BlockHeader struct {
+	ChainId      common.ChainIdType
+	Version      int32
+	PreviousHash *crypto.Hash
+	GasLimit     *common.Big
+	GasUsed      *common.Big
+	Height       uint64
+	Timestamp    uint64
+	StateRoot    common.Bytes
+	TxRoot       common.Bytes
+	LeaderPubKey common.Bytes
+	MinorPubKeys []common.Bytes
+}
+
+// BlockData holds the transactions of a block.
+type BlockData struct {
+	TxCount int32
+	TxList  []*Transaction
+}
+
+// MultiSignature is the aggregated signature of the block producers.
+type MultiSignature struct {
+	Sig    common.Bytes
+	Bitmap common.Bytes
+}
+
+// Block is a DREP block as returned by the db api and the newBlocks subscription.
+type Block struct {
+	Header   *BlockHeader
+	Data     *BlockData
+	MultiSig *MultiSignature
+}
+
+// TransactionData is the signed content of a transaction.
+type TransactionData struct {
+	Version   int32
+	Nonce     uint64
+	Type      int32
+	To        crypto.CommonAddress
+	ChainId   common.ChainIdType
+	Amount    *common.Big
+	GasPrice  *common.Big
+	GasLimit  *common.Big
+	Timestamp int64
+	Data      common.Bytes
+}
+
+// Transaction is a signed DREP transaction.
+type Transaction struct {
+	Data *TransactionData
+	Sig  common.Bytes
+}
+
+// Log is an event emitted by a contract while executing a transaction.
+type Log struct {
+	Address crypto.CommonAddress
+	ChainId common.ChainIdType
+	TxHash  crypto.Hash
+	Topics  []crypto.Hash
+	Data    common.Bytes
+	Height  uint64
+}
+
+// LogFilter selects the logs delivered by the logs subscription. Empty fields
+// match everything, Topics match by position.
+type LogFilter struct {
+	Addresses []crypto.CommonAddress `json:",omitempty"`
+	Topics    [][]crypto.Hash        `json:",omitempty"`
+}
+
+// AccountEvent is delivered by the account subscription whenever the state of
+// the watched account changes.
+type AccountEvent struct {
+	Address crypto.CommonAddress
+	Height  uint64
+	Balance *common.Big
+	Nonce   uint64
+}
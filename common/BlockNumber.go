@@ -14,6 +14,13 @@ const (
 	EarliestBlockNumber = BlockNumber(0)
 )
 
+// BlockTags are the names of the special block numbers.
+var BlockTags = map[string]BlockNumber{
+	"earliest": EarliestBlockNumber,
+	"latest":   LatestBlockNumber,
+	"pending":  PendingBlockNumber,
+}
+
 // UnmarshalJSON parses the given JSON fragment into a BlockNumber. It supports:
 // - "latest", "earliest" or "pending" as string arguments
 // - the block number
@@ -26,15 +33,8 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 		input = input[1 : len(input)-1]
 	}
 
-	switch input {
-	case "earliest":
-		*bn = EarliestBlockNumber
-		return nil
-	case "latest":
-		*bn = LatestBlockNumber
-		return nil
-	case "pending":
-		*bn = PendingBlockNumber
+	if number, ok := BlockTags[input]; ok {
+		*bn = number
 		return nil
 	}
 
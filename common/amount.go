@@ -0,0 +1,139 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Unit is a denomination of DREP amounts. Amounts are sent to nodes as
+// integers of the smallest unit, attodrep.
+type Unit struct {
+	Name     string
+	Decimals int // the unit is 10^Decimals attodrep
+}
+
+// Denominations of DREP.
+var (
+	Attodrep  = Unit{"attodrep", 0}
+	Femtodrep = Unit{"femtodrep", 3}
+	Picodrep  = Unit{"picodrep", 6}
+	Nanodrep  = Unit{"nanodrep", 9}
+	Microdrep = Unit{"microdrep", 12}
+	Millidrep = Unit{"millidrep", 15}
+	Drep      = Unit{"drep", 18}
+
+	// Units lists the denominations from the smallest to the largest.
+	Units = []Unit{Attodrep, Femtodrep, Picodrep, Nanodrep, Microdrep, Millidrep, Drep}
+)
+
+// LookupUnit returns the unit with the given name, ignoring case.
+func LookupUnit(name string) (Unit, bool) {
+	for _, unit := range Units {
+		if strings.EqualFold(name, unit.Name) {
+			return unit, true
+		}
+	}
+	return Unit{}, false
+}
+
+// unitNames returns the names of the units for error messages.
+func unitNames() string {
+	names := make([]string, len(Units))
+	for i, unit := range Units {
+		names[i] = unit.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseAmount parses an amount with an optional unit suffix, e.g. "1.5 drep",
+// "250millidrep" or "1e3 nanodrep", and returns it in attodrep. Amounts
+// without unit are in attodrep and may also be written in 0x prefixed hex.
+// Amounts which are negative, exceed 256 bits or have more decimals than the
+// unit has are an error, they aren't rounded.
+func ParseAmount(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	number := strings.TrimRightFunc(s, isLetter)
+	unit := Attodrep
+	if name := s[len(number):]; name != "" && !strings.HasPrefix(strings.ToLower(s), "0x") {
+		var ok bool
+		if unit, ok = LookupUnit(name); !ok {
+			return nil, fmt.Errorf("unknown unit %q in amount %q, expected one of %s", name, s, unitNames())
+		}
+	} else {
+		number = s
+	}
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return nil, fmt.Errorf("missing number in amount %q", s)
+	}
+
+	var amount *big.Int
+	if strings.HasPrefix(number, "0x") || strings.HasPrefix(number, "0X") {
+		if unit != Attodrep {
+			return nil, fmt.Errorf("invalid amount %q, hex amounts are in attodrep", s)
+		}
+		var ok bool
+		if amount, ok = new(big.Int).SetString(number[2:], 16); !ok {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+	} else {
+		if strings.ContainsAny(number, "/") {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		value, ok := new(big.Rat).SetString(number)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		value.Mul(value, new(big.Rat).SetInt(BigPow(10, int64(unit.Decimals))))
+		if !value.IsInt() && unit == Attodrep {
+			return nil, fmt.Errorf("amount %q isn't an integer of attodrep, add a unit, e.g. \"%s drep\"", s, number)
+		}
+		if !value.IsInt() {
+			return nil, fmt.Errorf("amount %q has more than %d decimals, the precision of %s", s, unit.Decimals, unit.Name)
+		}
+		amount = value.Num()
+	}
+	if amount.Sign() < 0 {
+		return nil, fmt.Errorf("negative amount %q", s)
+	}
+	if amount.BitLen() > 256 {
+		return nil, fmt.Errorf("amount %q exceeds 256 bits", s)
+	}
+	return amount, nil
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// FormatAmount formats an amount of attodrep in the given unit, with all the
+// decimals needed to represent it exactly.
+func FormatAmount(amount *big.Int, unit Unit) string {
+	quo, rem := new(big.Int).QuoRem(new(big.Int).Abs(amount), BigPow(10, int64(unit.Decimals)), new(big.Int))
+	s := quo.String()
+	if rem.Sign() != 0 {
+		decimals := fmt.Sprintf("%0*s", unit.Decimals, rem.String())
+		s += "." + strings.TrimRight(decimals, "0")
+	}
+	if amount.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]string{
+		"10":                         "10",
+		"0x0a":                       "10",
+		"1.5 drep":                   "1500000000000000000",
+		"1.5DREP":                    "1500000000000000000",
+		"250millidrep":               "250000000000000000",
+		"1e3 nanodrep":               "1000000000000",
+		" 0.000000000000000001 drep": "1",
+	}
+	for input, want := range tests {
+		amount, err := ParseAmount(input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+		} else if amount.String() != want {
+			t.Errorf("%q: got %s, want %s", input, amount, want)
+		}
+	}
+
+	for _, input := range []string{"", "drep", "1.5", "1.0000000000000000001 drep", "-1 drep", "1 ether", "0x0a drep", "1/2 drep", "1e78"} {
+		if amount, err := ParseAmount(input); err == nil {
+			t.Errorf("%q: expected an error, got %s", input, amount)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount *big.Int
+		unit   Unit
+		want   string
+	}{
+		{MustParseBig256("1500000000000000000"), Drep, "1.5"},
+		{MustParseBig256("1000000000000000001"), Drep, "1.000000000000000001"},
+		{big.NewInt(-2500), Femtodrep, "-2.5"},
+		{big.NewInt(0), Drep, "0"},
+		{big.NewInt(7), Attodrep, "7"},
+	}
+	for _, test := range tests {
+		if got := FormatAmount(test.amount, test.unit); got != test.want {
+			t.Errorf("%s in %s: got %s, want %s", test.amount, test.unit.Name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package component
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	accountTypes "github.com/drep-project/drepcli/accounts/types"
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+	"github.com/drep-project/drepcli/crypto/sha3"
+)
+
+// Transaction types of the ledger
+const (
+	TransferTx int32 = iota
+	CreateContractTx
+	CallContractTx
+)
+
+var (
+	ErrBlockNotFound       = errors.New("block not found")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrNoContract          = errors.New("no contract at address")
+	ErrTxNotFound          = errors.New("transaction not found")
+)
+
+// Ledger is an in-memory chain for local development. Transactions are not
+// signed, the ledger keeps their senders itself. Contracts are stored but their
+// code is never executed.
+type Ledger struct {
+	mu       sync.Mutex
+	chainId  common.ChainIdType
+	coinbase crypto.CommonAddress
+	blocks   []*chainTypes.Block
+	accounts map[crypto.CommonAddress]*accountTypes.Storage
+	pending  []*pendingTx
+	instant  bool // seal a block for every transaction
+
+	subMu  sync.Mutex
+	nextID int
+	subs   map[int]*ledgerSub
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+type pendingTx struct {
+	hash crypto.Hash
+	from crypto.CommonAddress
+	tx   *chainTypes.Transaction
+}
+
+// ledgerSub receives the events of the ledger, unset handlers are skipped
+type ledgerSub struct {
+	block       func(*chainTypes.Block)
+	transaction func(*chainTypes.Transaction)
+	account     func(*chainTypes.AccountEvent)
+}
+
+// NewLedger creates a ledger with a genesis block funding the alloc accounts.
+// The coinbase is the account transactions of the node are sent from.
+func NewLedger(chainId common.ChainIdType, coinbase crypto.CommonAddress, alloc map[crypto.CommonAddress]*big.Int) *Ledger {
+	ledger := &Ledger{
+		chainId:  chainId,
+		coinbase: coinbase,
+		accounts: make(map[crypto.CommonAddress]*accountTypes.Storage),
+		subs:     make(map[int]*ledgerSub),
+	}
+	for addr, balance := range alloc {
+		storage := ledger.storage(addr)
+		storage.Balance.Set(balance)
+	}
+	ledger.blocks = append(ledger.blocks, ledger.newBlock(nil))
+	return ledger
+}
+
+// Start produces a block every interval, a zero interval seals a block for every
+// transaction instead.
+func (ledger *Ledger) Start(interval time.Duration) {
+	if interval <= 0 {
+		ledger.mu.Lock()
+		ledger.instant = true
+		ledger.mu.Unlock()
+		return
+	}
+	ledger.quit = make(chan struct{})
+	ledger.wg.Add(1)
+	go func() {
+		defer ledger.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ledger.Seal()
+			case <-ledger.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the block production
+func (ledger *Ledger) Stop() {
+	if ledger.quit != nil {
+		close(ledger.quit)
+		ledger.wg.Wait()
+		ledger.quit = nil
+	}
+}
+
+// ChainId returns the chain id of the ledger
+func (ledger *Ledger) ChainId() common.ChainIdType {
+	return ledger.chainId
+}
+
+// Coinbase returns the account of the node
+func (ledger *Ledger) Coinbase() crypto.CommonAddress {
+	return ledger.coinbase
+}
+
+// Height returns the height of the latest block
+func (ledger *Ledger) Height() uint64 {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	return uint64(len(ledger.blocks) - 1)
+}
+
+// Block returns the block at the given height
+func (ledger *Ledger) Block(height uint64) (*chainTypes.Block, error) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	if height >= uint64(len(ledger.blocks)) {
+		return nil, ErrBlockNotFound
+	}
+	return ledger.blocks[height], nil
+}
+
+// Blocks returns up to count blocks starting at the given height
+func (ledger *Ledger) Blocks(from, count uint64) []*chainTypes.Block {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	blocks := []*chainTypes.Block{}
+	for height := from; height < uint64(len(ledger.blocks)) && height-from < count; height++ {
+		blocks = append(blocks, ledger.blocks[height])
+	}
+	return blocks
+}
+
+// Logs returns the logs emitted by the sealed transaction with the given hash.
+// They are always empty as contract code isn't executed.
+func (ledger *Ledger) Logs(hash crypto.Hash) ([]*chainTypes.Log, error) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	for _, block := range ledger.blocks {
+		for _, tx := range block.Data.TxList {
+			if txHash(tx) == hash {
+				return []*chainTypes.Log{}, nil
+			}
+		}
+	}
+	return nil, ErrTxNotFound
+}
+
+// Account returns a copy of the state of the account, unknown accounts are empty
+func (ledger *Ledger) Account(addr crypto.CommonAddress) *accountTypes.Storage {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	storage, ok := ledger.accounts[addr]
+	if !ok {
+		storage := accountTypes.NewStorage()
+		storage.Reputation = new(big.Int)
+		return storage
+	}
+	account := *storage
+	account.Balance = new(big.Int).Set(storage.Balance)
+	account.Reputation = new(big.Int).Set(storage.Reputation)
+	return &account
+}
+
+// Transfer queues a transfer of amount from one account to another and returns
+// the transaction hash.
+func (ledger *Ledger) Transfer(from, to crypto.CommonAddress, amount *big.Int) (crypto.Hash, error) {
+	p, err := ledger.submit(from, TransferTx, to, amount, nil)
+	if err != nil {
+		return crypto.Hash{}, err
+	}
+	return p.hash, nil
+}
+
+// CreateContract queues the creation of a contract with the given code and
+// returns the address the contract will have.
+func (ledger *Ledger) CreateContract(from crypto.CommonAddress, code []byte) (crypto.CommonAddress, error) {
+	p, err := ledger.submit(from, CreateContractTx, crypto.CommonAddress{}, new(big.Int), code)
+	if err != nil {
+		return crypto.CommonAddress{}, err
+	}
+	return p.contractAddress(), nil
+}
+
+// CallContract queues a call of the contract, transferring amount to it.
+func (ledger *Ledger) CallContract(from, contract crypto.CommonAddress, input []byte, amount *big.Int) (crypto.Hash, error) {
+	if len(ledger.Account(contract).ByteCode) == 0 {
+		return crypto.Hash{}, ErrNoContract
+	}
+	p, err := ledger.submit(from, CallContractTx, contract, amount, input)
+	if err != nil {
+		return crypto.Hash{}, err
+	}
+	return p.hash, nil
+}
+
+func (ledger *Ledger) submit(from crypto.CommonAddress, txType int32, to crypto.CommonAddress, amount *big.Int, data []byte) (*pendingTx, error) {
+	if amount.Sign() < 0 {
+		return nil, errors.New("negative amount")
+	}
+	ledger.mu.Lock()
+	if ledger.spendable(from).Cmp(amount) < 0 {
+		ledger.mu.Unlock()
+		return nil, ErrInsufficientBalance
+	}
+	tx := &chainTypes.Transaction{
+		Data: &chainTypes.TransactionData{
+			Version:   1,
+			Nonce:     ledger.nextNonce(from),
+			Type:      txType,
+			To:        to,
+			ChainId:   ledger.chainId,
+			Amount:    (*common.Big)(new(big.Int).Set(amount)),
+			GasPrice:  new(common.Big),
+			GasLimit:  new(common.Big),
+			Timestamp: time.Now().Unix(),
+			Data:      data,
+		},
+	}
+	p := &pendingTx{hash: txHash(tx), from: from, tx: tx}
+	ledger.pending = append(ledger.pending, p)
+	instant := ledger.instant
+	ledger.mu.Unlock()
+
+	ledger.notify(func(sub *ledgerSub) {
+		if sub.transaction != nil {
+			sub.transaction(tx)
+		}
+	})
+	if instant {
+		ledger.Seal()
+	}
+	return p, nil
+}
+
+// Seal applies the pending transactions and appends them as a new block.
+// Transactions which became invalid are dropped.
+func (ledger *Ledger) Seal() *chainTypes.Block {
+	ledger.mu.Lock()
+	touched := make(map[crypto.CommonAddress]bool)
+	var included []*chainTypes.Transaction
+	for _, p := range ledger.pending {
+		if err := ledger.apply(p); err != nil {
+			continue
+		}
+		included = append(included, p.tx)
+		touched[p.from] = true
+		if p.tx.Data.Type == CreateContractTx {
+			touched[p.contractAddress()] = true
+		} else {
+			touched[p.tx.Data.To] = true
+		}
+	}
+	ledger.pending = nil
+	block := ledger.newBlock(included)
+	ledger.blocks = append(ledger.blocks, block)
+	events := make([]*chainTypes.AccountEvent, 0, len(touched))
+	for addr := range touched {
+		storage := ledger.storage(addr)
+		events = append(events, &chainTypes.AccountEvent{
+			Address: addr,
+			Height:  block.Header.Height,
+			Balance: (*common.Big)(new(big.Int).Set(storage.Balance)),
+			Nonce:   uint64(storage.Nonce),
+		})
+	}
+	ledger.mu.Unlock()
+
+	ledger.notify(func(sub *ledgerSub) {
+		if sub.block != nil {
+			sub.block(block)
+		}
+		if sub.account != nil {
+			for _, event := range events {
+				sub.account(event)
+			}
+		}
+	})
+	return block
+}
+
+// SubscribeBlocks calls fn with every new block until unsubscribe is called
+func (ledger *Ledger) SubscribeBlocks(fn func(*chainTypes.Block)) (unsubscribe func()) {
+	return ledger.subscribe(&ledgerSub{block: fn})
+}
+
+// SubscribeTransactions calls fn with every transaction entering the pending
+// pool until unsubscribe is called
+func (ledger *Ledger) SubscribeTransactions(fn func(*chainTypes.Transaction)) (unsubscribe func()) {
+	return ledger.subscribe(&ledgerSub{transaction: fn})
+}
+
+// SubscribeAccounts calls fn with the state of every account changed by a new
+// block until unsubscribe is called
+func (ledger *Ledger) SubscribeAccounts(fn func(*chainTypes.AccountEvent)) (unsubscribe func()) {
+	return ledger.subscribe(&ledgerSub{account: fn})
+}
+
+func (ledger *Ledger) subscribe(sub *ledgerSub) func() {
+	ledger.subMu.Lock()
+	defer ledger.subMu.Unlock()
+	id := ledger.nextID
+	ledger.nextID++
+	ledger.subs[id] = sub
+	return func() {
+		ledger.subMu.Lock()
+		delete(ledger.subs, id)
+		ledger.subMu.Unlock()
+	}
+}
+
+// notify delivers an event to all subscriptions, without holding the ledger lock
+// or the subscription lock, so callbacks may subscribe and unsubscribe. A
+// subscription ended during the delivery may still receive the event.
+func (ledger *Ledger) notify(deliver func(*ledgerSub)) {
+	ledger.subMu.Lock()
+	subs := make([]*ledgerSub, 0, len(ledger.subs))
+	for _, sub := range ledger.subs {
+		subs = append(subs, sub)
+	}
+	ledger.subMu.Unlock()
+	for _, sub := range subs {
+		deliver(sub)
+	}
+}
+
+// apply executes a transaction on the account states, the ledger lock is held
+func (ledger *Ledger) apply(p *pendingTx) error {
+	data := p.tx.Data
+	sender := ledger.storage(p.from)
+	amount := data.Amount.ToInt()
+	if sender.Balance.Cmp(amount) < 0 {
+		return ErrInsufficientBalance
+	}
+	switch data.Type {
+	case CreateContractTx:
+		contract := ledger.storage(p.contractAddress())
+		contract.ByteCode = crypto.ByteCode(data.Data)
+		contract.CodeHash = crypto.GetByteCodeHash(contract.ByteCode)
+	case CallContractTx:
+		if len(ledger.storage(data.To).ByteCode) == 0 {
+			return ErrNoContract
+		}
+		fallthrough
+	default:
+		receiver := ledger.storage(data.To)
+		sender.Balance.Sub(sender.Balance, amount)
+		receiver.Balance.Add(receiver.Balance, amount)
+	}
+	sender.Nonce++
+	return nil
+}
+
+// contractAddress returns the address of the contract a transaction creates
+func (p *pendingTx) contractAddress() crypto.CommonAddress {
+	return crypto.GetByteCodeAddress(p.from, int64(p.tx.Data.Nonce))
+}
+
+// storage returns the state of the account, creating it if needed
+func (ledger *Ledger) storage(addr crypto.CommonAddress) *accountTypes.Storage {
+	storage, ok := ledger.accounts[addr]
+	if !ok {
+		storage = accountTypes.NewStorage()
+		storage.Reputation = new(big.Int)
+		ledger.accounts[addr] = storage
+	}
+	return storage
+}
+
+// nextNonce returns the nonce of the next transaction of the account, counting
+// its pending transactions
+func (ledger *Ledger) nextNonce(addr crypto.CommonAddress) uint64 {
+	nonce := uint64(ledger.storage(addr).Nonce)
+	for _, p := range ledger.pending {
+		if p.from == addr {
+			nonce++
+		}
+	}
+	return nonce
+}
+
+// spendable returns the balance of the account minus its pending spendings
+func (ledger *Ledger) spendable(addr crypto.CommonAddress) *big.Int {
+	balance := new(big.Int).Set(ledger.storage(addr).Balance)
+	for _, p := range ledger.pending {
+		if p.from == addr {
+			balance.Sub(balance, p.tx.Data.Amount.ToInt())
+		}
+	}
+	return balance
+}
+
+// newBlock creates the block following the latest one
+func (ledger *Ledger) newBlock(txs []*chainTypes.Transaction) *chainTypes.Block {
+	header := &chainTypes.BlockHeader{
+		ChainId:   ledger.chainId,
+		Version:   1,
+		GasLimit:  new(common.Big),
+		GasUsed:   new(common.Big),
+		Height:    uint64(len(ledger.blocks)),
+		Timestamp: uint64(time.Now().Unix()),
+	}
+	if len(ledger.blocks) > 0 {
+		previous := headerHash(ledger.blocks[len(ledger.blocks)-1].Header)
+		header.PreviousHash = &previous
+	}
+	var hashes []byte
+	for _, tx := range txs {
+		hash := txHash(tx)
+		hashes = append(hashes, hash[:]...)
+	}
+	header.TxRoot = sha3.Hash256(hashes)
+	if txs == nil {
+		txs = []*chainTypes.Transaction{}
+	}
+	return &chainTypes.Block{
+		Header:   header,
+		Data:     &chainTypes.BlockData{TxCount: int32(len(txs)), TxList: txs},
+		MultiSig: &chainTypes.MultiSignature{},
+	}
+}
+
+func txHash(tx *chainTypes.Transaction) crypto.Hash {
+	return jsonHash(tx.Data)
+}
+
+func headerHash(header *chainTypes.BlockHeader) crypto.Hash {
+	return jsonHash(header)
+}
+
+func jsonHash(v interface{}) crypto.Hash {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("can't hash %T: %v", v, err))
+	}
+	return crypto.Bytes2Hash(sha3.Hash256(b))
+}
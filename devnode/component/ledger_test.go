@@ -0,0 +1,122 @@
+package component
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+)
+
+var (
+	alice = crypto.Hex2Address("772dec19e0b0b2d63a57a3a7fb03fc066d915e6b")
+	bob   = crypto.Hex2Address("0000000000000000000000000000000000000b0b")
+)
+
+func newTestLedger() *Ledger {
+	return NewLedger(common.ChainIdType{}, alice, map[crypto.CommonAddress]*big.Int{alice: big.NewInt(100)})
+}
+
+func TestLedgerTransfer(t *testing.T) {
+	ledger := newTestLedger()
+	var blocks []*chainTypes.Block
+	var events []*chainTypes.AccountEvent
+	defer ledger.SubscribeBlocks(func(block *chainTypes.Block) { blocks = append(blocks, block) })()
+	defer ledger.SubscribeAccounts(func(event *chainTypes.AccountEvent) { events = append(events, event) })()
+
+	if _, err := ledger.Transfer(alice, bob, big.NewInt(30)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.Transfer(alice, bob, big.NewInt(80)); err != ErrInsufficientBalance {
+		t.Errorf("expected insufficient balance counting the pending transfer, got %v", err)
+	}
+	if balance := ledger.Account(bob).Balance; balance.Sign() != 0 {
+		t.Errorf("pending transfer changed the balance to %v", balance)
+	}
+
+	block := ledger.Seal()
+	if block.Header.Height != 1 || block.Data.TxCount != 1 || ledger.Height() != 1 {
+		t.Fatalf("wrong block %d with %d transactions", block.Header.Height, block.Data.TxCount)
+	}
+	genesis, _ := ledger.Block(0)
+	if previous := headerHash(genesis.Header); *block.Header.PreviousHash != previous {
+		t.Error("block doesn't link to the genesis block")
+	}
+	if a, b := ledger.Account(alice), ledger.Account(bob); a.Balance.Int64() != 70 || a.Nonce != 1 || b.Balance.Int64() != 30 {
+		t.Errorf("wrong balances after transfer: alice %v nonce %d, bob %v", a.Balance, a.Nonce, b.Balance)
+	}
+	if len(blocks) != 1 || len(events) != 2 {
+		t.Errorf("got %d block and %d account events, want 1 and 2", len(blocks), len(events))
+	}
+	if _, err := ledger.Block(2); err != ErrBlockNotFound {
+		t.Errorf("expected block not found, got %v", err)
+	}
+}
+
+func TestLedgerInstantSeal(t *testing.T) {
+	ledger := newTestLedger()
+	ledger.Start(0)
+	defer ledger.Stop()
+
+	contract, err := ledger.CreateContract(alice, []byte{0x60, 0x80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ledger.Height() != 1 || len(ledger.Account(contract).ByteCode) != 2 {
+		t.Fatalf("contract creation wasn't sealed at once, height %d", ledger.Height())
+	}
+	if _, err := ledger.CallContract(alice, contract, nil, big.NewInt(5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.CallContract(alice, bob, nil, big.NewInt(5)); err != ErrNoContract {
+		t.Errorf("expected no contract error, got %v", err)
+	}
+	if balance := ledger.Account(contract).Balance; balance.Int64() != 5 || ledger.Height() != 2 {
+		t.Errorf("contract balance %v at height %d, want 5 at 2", balance, ledger.Height())
+	}
+	if blocks := ledger.Blocks(1, 10); len(blocks) != 2 {
+		t.Errorf("got %d blocks from height 1, want 2", len(blocks))
+	}
+}
+
+func TestLedgerCallbacksSubscribe(t *testing.T) {
+	ledger := newTestLedger()
+	var unsubscribe func()
+	var blocks int
+	unsubscribe = ledger.SubscribeBlocks(func(block *chainTypes.Block) {
+		// callbacks may unsubscribe and subscribe
+		unsubscribe()
+		ledger.SubscribeBlocks(func(*chainTypes.Block) { blocks++ })
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ledger.Seal()
+		ledger.Seal()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscribing from a callback deadlocked")
+	}
+	if blocks != 1 {
+		t.Errorf("got %d blocks in the subscription of the callback, want 1", blocks)
+	}
+}
+
+func TestLedgerLogs(t *testing.T) {
+	ledger := newTestLedger()
+	hash, err := ledger.Transfer(alice, bob, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.Logs(hash); err != ErrTxNotFound {
+		t.Errorf("expected the pending transaction not to be found, got %v", err)
+	}
+	ledger.Seal()
+	if logs, err := ledger.Logs(hash); err != nil || logs == nil || len(logs) != 0 {
+		t.Errorf("got logs %v, %v, want none", logs, err)
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+	devnodeComponent "github.com/drep-project/drepcli/devnode/component"
+	"github.com/drep-project/drepcli/drepclient/component/jsre/deps"
+	rpcComponent "github.com/drep-project/drepcli/rpc/component"
+	rpcService "github.com/drep-project/drepcli/rpc/service"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+var (
+	coinbase = crypto.Hex2Address("772dec19e0b0b2d63a57a3a7fb03fc066d915e6b")
+	bob      = crypto.Hex2Address("0000000000000000000000000000000000000b0b")
+)
+
+// newTestNode serves the dev node APIs of a ledger sealing a block for every
+// transaction.
+func newTestNode(t *testing.T) (*rpcTypes.Server, *rpcComponent.Client) {
+	ledger := devnodeComponent.NewLedger(common.ChainIdType{}, coinbase, map[crypto.CommonAddress]*big.Int{coinbase: big.NewInt(100)})
+	ledger.Start(0)
+	server := rpcTypes.NewServer()
+	for _, api := range DevNodeAPIs(ledger) {
+		if err := server.RegisterName(api.Namespace, api.Service, rpcService.RegisterOptions(api)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := rpcComponent.DialInProc(server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+		ledger.Stop()
+	})
+	return server, client
+}
+
+func TestDevNodeServesConsoleMethods(t *testing.T) {
+	server, _ := newTestNode(t)
+	served := make(map[string]bool)
+	for _, method := range server.Describe() {
+		served[method.Name] = true
+	}
+	for _, method := range deps.Methods() {
+		if (method.Namespace == "db" || method.Namespace == "chain") && !served[method.Call] {
+			t.Errorf("%s of drep.js isn't served", method.Call)
+		}
+	}
+}
+
+func TestDevNodeAPI(t *testing.T) {
+	_, client := newTestNode(t)
+	var hash crypto.Hash
+	if err := client.Call(&hash, "chain_send", bob, "0x00", "30"); err != nil {
+		t.Fatal(err)
+	}
+
+	var height, nonce uint64
+	var balance common.Big
+	if err := client.Call(&height, "chain_h"); err != nil || height != 1 {
+		t.Errorf("chain_h returned %d, %v, want 1", height, err)
+	}
+	if err := client.Call(&nonce, "chain_n"); err != nil || nonce != 1 {
+		t.Errorf("chain_n returned %d, %v, want 1", nonce, err)
+	}
+	if err := client.Call(&balance, "db_getBalance", bob, "0x00"); err != nil || balance.ToInt().Int64() != 30 {
+		t.Errorf("db_getBalance returned %v, %v, want 30", balance.ToInt(), err)
+	}
+	for addr, want := range map[crypto.CommonAddress]bool{coinbase: true, bob: false} {
+		var miner bool
+		if err := client.Call(&miner, "chain_miner", addr, "0x00"); err != nil || miner != want {
+			t.Errorf("chain_miner(%x) returned %v, %v, want %v", addr, miner, err, want)
+		}
+	}
+	var blocks []*chainTypes.Block
+	if err := client.Call(&blocks, "chain_travel"); err != nil || len(blocks) != 2 {
+		t.Errorf("chain_travel returned %d blocks, %v, want 2", len(blocks), err)
+	}
+
+	logs := []*chainTypes.Log{nil}
+	if err := client.Call(&logs, "db_getLogs", hash, "0x00"); err != nil || len(logs) != 0 {
+		t.Errorf("db_getLogs returned %v, %v, want no logs", logs, err)
+	}
+	if err := client.Call(&logs, "db_getLogs", crypto.Hash{}, "0x00"); err == nil || err.Error() != devnodeComponent.ErrTxNotFound.Error() {
+		t.Errorf("db_getLogs of an unknown transaction returned %v, want %v", err, devnodeComponent.ErrTxNotFound)
+	}
+}
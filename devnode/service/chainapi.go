@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+	devnodeComponent "github.com/drep-project/drepcli/devnode/component"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// ChainApi serves the chain namespace, transactions are sent from the coinbase
+// of the ledger. The dev node runs a single chain, chain ids are ignored.
+type ChainApi struct {
+	Ledger *devnodeComponent.Ledger
+}
+
+// MeInfo describes the account of the node
+type MeInfo struct {
+	Addr    crypto.CommonAddress `json:"addr"`
+	Balance *common.Big          `json:"balance"`
+	ChainId common.ChainIdType   `json:"chainId"`
+	Nonce   uint64               `json:"nonce"`
+}
+
+// AccountState is the state of an account as returned by check
+type AccountState struct {
+	Balance    *common.Big
+	ByteCode   common.Bytes
+	CodeHash   crypto.Hash
+	Nonce      uint64
+	Reputation *common.Big
+}
+
+// Me returns the account of the node
+func (chainapi *ChainApi) Me() *MeInfo {
+	coinbase := chainapi.Ledger.Coinbase()
+	account := chainapi.Ledger.Account(coinbase)
+	return &MeInfo{
+		Addr:    coinbase,
+		Balance: (*common.Big)(account.Balance),
+		ChainId: chainapi.Ledger.ChainId(),
+		Nonce:   uint64(account.Nonce),
+	}
+}
+
+// Check returns the state of the account
+func (chainapi *ChainApi) Check(address crypto.CommonAddress, chainId common.ChainIdType) *AccountState {
+	account := chainapi.Ledger.Account(address)
+	return &AccountState{
+		Balance:    (*common.Big)(account.Balance),
+		ByteCode:   common.Bytes(account.ByteCode),
+		CodeHash:   account.CodeHash,
+		Nonce:      uint64(account.Nonce),
+		Reputation: (*common.Big)(account.Reputation),
+	}
+}
+
+// CheckBalance returns the balance of the account
+func (chainapi *ChainApi) CheckBalance(address crypto.CommonAddress) *common.Big {
+	return (*common.Big)(chainapi.Ledger.Account(address).Balance)
+}
+
+// CheckNonce returns the number of transactions sent by the account
+func (chainapi *ChainApi) CheckNonce(address crypto.CommonAddress) uint64 {
+	return uint64(chainapi.Ledger.Account(address).Nonce)
+}
+
+// H returns the height of the latest block
+func (chainapi *ChainApi) H() uint64 {
+	return chainapi.Ledger.Height()
+}
+
+// N returns the number of transactions sent by the account of the node
+func (chainapi *ChainApi) N() uint64 {
+	return uint64(chainapi.Ledger.Account(chainapi.Ledger.Coinbase()).Nonce)
+}
+
+// Miner reports whether the address seals the blocks of the chain, which only
+// the account of the node does
+func (chainapi *ChainApi) Miner(address crypto.CommonAddress, chainId common.ChainIdType) bool {
+	return address == chainapi.Ledger.Coinbase()
+}
+
+// Travel returns all blocks from the genesis block on
+func (chainapi *ChainApi) Travel() []*chainTypes.Block {
+	return chainapi.Ledger.Blocks(0, chainapi.Ledger.Height()+1)
+}
+
+// Send transfers a decimal or 0x prefixed hex amount to the address and returns
+// the transaction hash
+func (chainapi *ChainApi) Send(to crypto.CommonAddress, chainId common.ChainIdType, amount string) (crypto.Hash, error) {
+	value, err := parseAmount(amount)
+	if err != nil {
+		return crypto.Hash{}, err
+	}
+	return chainapi.Ledger.Transfer(chainapi.Ledger.Coinbase(), to, value)
+}
+
+// Create deploys a contract with the hex encoded code and returns its address
+func (chainapi *ChainApi) Create(code string) (crypto.CommonAddress, error) {
+	byteCode, err := parseHex(code)
+	if err != nil {
+		return crypto.CommonAddress{}, err
+	}
+	if len(byteCode) == 0 {
+		return crypto.CommonAddress{}, fmt.Errorf("empty contract code")
+	}
+	return chainapi.Ledger.CreateContract(chainapi.Ledger.Coinbase(), byteCode)
+}
+
+// Call invokes the contract at address with the hex encoded input. Read-only
+// calls return the output, which is always empty as the dev node doesn't run
+// contract code. Other calls return the transaction hash.
+func (chainapi *ChainApi) Call(address crypto.CommonAddress, chainId common.ChainIdType, input string, amount string, readOnly bool) (common.Bytes, error) {
+	data, err := parseHex(input)
+	if err != nil {
+		return nil, err
+	}
+	if readOnly {
+		if len(chainapi.Ledger.Account(address).ByteCode) == 0 {
+			return nil, devnodeComponent.ErrNoContract
+		}
+		return common.Bytes{}, nil
+	}
+	value, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := chainapi.Ledger.CallContract(chainapi.Ledger.Coinbase(), address, data, value)
+	if err != nil {
+		return nil, err
+	}
+	return common.Bytes(hash.Bytes()), nil
+}
+
+// NewBlocks notifies about every block appended to the chain
+func (chainapi *ChainApi) NewBlocks(ctx context.Context) (*rpcTypes.Subscription, error) {
+	return subscribe(ctx, func(notify func(interface{})) func() {
+		return chainapi.Ledger.SubscribeBlocks(func(block *chainTypes.Block) { notify(block) })
+	})
+}
+
+// PendingTransactions notifies about every transaction sent to the node
+func (chainapi *ChainApi) PendingTransactions(ctx context.Context) (*rpcTypes.Subscription, error) {
+	return subscribe(ctx, func(notify func(interface{})) func() {
+		return chainapi.Ledger.SubscribeTransactions(func(tx *chainTypes.Transaction) { notify(tx) })
+	})
+}
+
+// Account notifies about balance and nonce changes of the account
+func (chainapi *ChainApi) Account(ctx context.Context, address crypto.CommonAddress) (*rpcTypes.Subscription, error) {
+	return subscribe(ctx, func(notify func(interface{})) func() {
+		return chainapi.Ledger.SubscribeAccounts(func(event *chainTypes.AccountEvent) {
+			if event.Address == address {
+				notify(event)
+			}
+		})
+	})
+}
+
+// subscribe creates a subscription fed by a ledger subscription, which ends
+// when the client unsubscribes or disconnects
+func subscribe(ctx context.Context, start func(notify func(interface{})) (unsubscribe func())) (*rpcTypes.Subscription, error) {
+	notifier, supported := rpcTypes.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpcTypes.ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	unsubscribe := start(func(data interface{}) {
+		notifier.Notify(subscription.ID, data)
+	})
+	go func() {
+		defer unsubscribe()
+		select {
+		case <-subscription.Err():
+		case <-notifier.Closed():
+		}
+	}()
+	return subscription, nil
+}
+
+// parseAmount parses a decimal or 0x prefixed hex amount
+func parseAmount(amount string) (*big.Int, error) {
+	value, ok := common.ParseBig256(strings.TrimSpace(amount))
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	return value, nil
+}
+
+// parseHex decodes hex data with or without 0x prefix
+func parseHex(input string) ([]byte, error) {
+	input = strings.TrimPrefix(strings.TrimPrefix(input, "0x"), "0X")
+	data, err := hex.DecodeString(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data: %v", err)
+	}
+	return data, nil
+}
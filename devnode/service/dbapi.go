@@ -0,0 +1,73 @@
+package service
+
+import (
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+	devnodeComponent "github.com/drep-project/drepcli/devnode/component"
+)
+
+// DbApi serves the chain queries of the db namespace from the ledger. The dev
+// node runs a single chain, chain ids are ignored.
+type DbApi struct {
+	Ledger *devnodeComponent.Ledger
+}
+
+// GetMaxHeight returns the height of the latest block
+func (dbapi *DbApi) GetMaxHeight() uint64 {
+	return dbapi.Ledger.Height()
+}
+
+// GetBlock returns the block at the given height
+func (dbapi *DbApi) GetBlock(height uint64) (*chainTypes.Block, error) {
+	return dbapi.Ledger.Block(height)
+}
+
+// GetHighestBlock returns the latest block
+func (dbapi *DbApi) GetHighestBlock() (*chainTypes.Block, error) {
+	return dbapi.Ledger.Block(dbapi.Ledger.Height())
+}
+
+// GetAllBlocks returns all blocks from the genesis block on
+func (dbapi *DbApi) GetAllBlocks() []*chainTypes.Block {
+	return dbapi.Ledger.Blocks(0, dbapi.Ledger.Height()+1)
+}
+
+// GetBlocksFrom returns up to size blocks starting at the given height
+func (dbapi *DbApi) GetBlocksFrom(start, size uint64) []*chainTypes.Block {
+	return dbapi.Ledger.Blocks(start, size)
+}
+
+// GetMostRecentBlocks returns the latest count blocks, oldest first
+func (dbapi *DbApi) GetMostRecentBlocks(count uint64) []*chainTypes.Block {
+	height := dbapi.Ledger.Height()
+	if count > height+1 {
+		count = height + 1
+	}
+	return dbapi.Ledger.Blocks(height+1-count, count)
+}
+
+// GetBalance returns the balance of the account
+func (dbapi *DbApi) GetBalance(address crypto.CommonAddress, chainId common.ChainIdType) *common.Big {
+	return (*common.Big)(dbapi.Ledger.Account(address).Balance)
+}
+
+// GetNonce returns the number of transactions sent by the account
+func (dbapi *DbApi) GetNonce(address crypto.CommonAddress, chainId common.ChainIdType) uint64 {
+	return uint64(dbapi.Ledger.Account(address).Nonce)
+}
+
+// GetByteCode returns the code of the contract at the address
+func (dbapi *DbApi) GetByteCode(address crypto.CommonAddress, chainId common.ChainIdType) common.Bytes {
+	return common.Bytes(dbapi.Ledger.Account(address).ByteCode)
+}
+
+// GetCodeHash returns the hash of the code of the contract at the address
+func (dbapi *DbApi) GetCodeHash(address crypto.CommonAddress, chainId common.ChainIdType) crypto.Hash {
+	return dbapi.Ledger.Account(address).CodeHash
+}
+
+// GetLogs returns the logs emitted by the transaction with the given hash
+func (dbapi *DbApi) GetLogs(txHash crypto.Hash, chainId common.ChainIdType) ([]*chainTypes.Log, error) {
+	return dbapi.Ledger.Logs(txHash)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gopkg.in/urfave/cli.v1"
+
+	accountComponent "github.com/drep-project/drepcli/accounts/component"
+	accountService "github.com/drep-project/drepcli/accounts/service"
+	accountTypes "github.com/drep-project/drepcli/accounts/types"
+	"github.com/drep-project/drepcli/app"
+	"github.com/drep-project/drepcli/crypto"
+	devnodeComponent "github.com/drep-project/drepcli/devnode/component"
+	"github.com/drep-project/drepcli/log"
+	rpcService "github.com/drep-project/drepcli/rpc/service"
+)
+
+var (
+	BlockTimeFlag = cli.DurationFlag{
+		Name:  "blocktime",
+		Usage: "Interval between blocks, 0 seals a block for every transaction",
+		Value: 5 * time.Second,
+	}
+	FundFlag = cli.StringFlag{
+		Name:  "fund",
+		Usage: "Balance every account of the local keystore starts with, decimal or 0x prefixed hex",
+		Value: "1000000000000000000000",
+	}
+)
+
+// DevNodeService runs an in-memory drep node for local development and tests
+type DevNodeService struct {
+	Account *accountService.AccountService `service:"account"`
+	Rpc     *rpcService.RpcService         `service:"rpc"`
+}
+
+// Name name
+func (devNodeService *DevNodeService) Name() string {
+	return "devnode"
+}
+
+// Api api none, the dev node APIs are only served by the devnode command
+func (devNodeService *DevNodeService) Api() []app.API {
+	return nil
+}
+
+// Flags flags none, the flags belong to the devnode command
+func (devNodeService *DevNodeService) Flags() []cli.Flag {
+	return nil
+}
+
+func (devNodeService *DevNodeService) Init(executeContext *app.ExecuteContext) error {
+	return nil
+}
+
+func (devNodeService *DevNodeService) Start(executeContext *app.ExecuteContext) error {
+	return nil
+}
+
+func (devNodeService *DevNodeService) Stop(executeContext *app.ExecuteContext) error {
+	return nil
+}
+
+// Commands returns the commands offered by the dev node service
+func (devNodeService *DevNodeService) Commands(executeContext *app.ExecuteContext) []cli.Command {
+	return []cli.Command{
+		{
+			Name:  "devnode",
+			Usage: "Run an in-memory drep node for local development",
+			Description: `Serves the db and chain APIs used by the console from an in-memory ledger,
+next to the local account API, on the HTTP, WS, TCP and IPC endpoints. HTTP is
+enabled if none of HTTP, WS and TCP are enabled. Every account of the local
+keystore is funded, the first one is the account of the node. Contracts are
+stored but not executed.`,
+			Flags: []cli.Flag{BlockTimeFlag, FundFlag},
+			Action: func(ctx *cli.Context) error {
+				return devNodeService.runDevNode(executeContext, ctx)
+			},
+		},
+	}
+}
+
+// runDevNode serves the ledger until interrupted
+func (devNodeService *DevNodeService) runDevNode(executeContext *app.ExecuteContext, ctx *cli.Context) error {
+	fund, ok := new(big.Int).SetString(ctx.String(FundFlag.Name), 0)
+	if !ok || fund.Sign() < 0 {
+		return fmt.Errorf("invalid --%s amount %q", FundFlag.Name, ctx.String(FundFlag.Name))
+	}
+	if err := executeContext.InitServices("cli"); err != nil {
+		return err
+	}
+
+	addresses, err := accountComponent.ListKeyStoreAddresses(devNodeService.Account.KeyStoreDir())
+	if err != nil {
+		return err
+	}
+	if len(addresses) == 0 {
+		node := accountTypes.NewNode(nil, accountTypes.RootChain)
+		addresses = append(addresses, *node.Address)
+		log.Warn("No keystore accounts, using a temporary account for the node", "address", node.Address.Hex())
+	}
+	alloc := make(map[crypto.CommonAddress]*big.Int)
+	for _, addr := range addresses {
+		alloc[addr] = fund
+	}
+	ledger := devnodeComponent.NewLedger(accountTypes.RootChain, addresses[0], alloc)
+	ledger.Start(ctx.Duration(BlockTimeFlag.Name))
+	defer ledger.Stop()
+
+	rpc := devNodeService.Rpc
+	if !rpc.RpcConfig.HTTPEnabled && !rpc.RpcConfig.WSEnabled && !rpc.RpcConfig.TCPEnabled {
+		rpc.RpcConfig.HTTPEnabled = true
+	}
+	if err := rpc.StartEndpoints(append(executeContext.GetApis(), DevNodeAPIs(ledger)...)); err != nil {
+		return err
+	}
+	defer rpc.Stop(executeContext)
+	rpc.WatchReload()
+	log.Info("Dev node started", "coinbase", addresses[0].Hex(), "accounts", len(addresses), "blocktime", ctx.Duration(BlockTimeFlag.Name))
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	<-sigc
+	return nil
+}
+
+// DevNodeAPIs returns the db and chain APIs served from the ledger
+func DevNodeAPIs(ledger *devnodeComponent.Ledger) []app.API {
+	return []app.API{
+		{
+			Namespace: "db",
+			Version:   "1.0",
+			Service:   &DbApi{Ledger: ledger},
+			Public:    true,
+			ParamNames: map[string][]string{
+				"getBlock":            {"height"},
+				"getBlocksFrom":       {"start", "size"},
+				"getMostRecentBlocks": {"count"},
+				"getBalance":          {"address", "chainId"},
+				"getNonce":            {"address", "chainId"},
+				"getByteCode":         {"address", "chainId"},
+				"getCodeHash":         {"address", "chainId"},
+				"getLogs":             {"txHash", "chainId"},
+			},
+		},
+		{
+			Namespace: "chain",
+			Version:   "1.0",
+			Service:   &ChainApi{Ledger: ledger},
+			Public:    true,
+			ParamNames: map[string][]string{
+				"check":        {"address", "chainId"},
+				"checkBalance": {"address"},
+				"checkNonce":   {"address"},
+				"send":         {"to", "chainId", "amount"},
+				"create":       {"code"},
+				"call":         {"address", "chainId", "input", "amount", "readOnly"},
+				"miner":        {"address", "chainId"},
+			},
+		},
+	}
+}
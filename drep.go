@@ -9,6 +9,7 @@ import (
 	rpcService "github.com/drep-project/drepcli/rpc/service"
 	accountService "github.com/drep-project/drepcli/accounts/service"
 	cliService "github.com/drep-project/drepcli/drepclient/service"
+	devnodeService "github.com/drep-project/drepcli/devnode/service"
 )
 
 func main() {
@@ -18,6 +19,7 @@ func main() {
 		reflect.TypeOf(accountService.AccountService{}),
 		reflect.TypeOf(rpcService.RpcService{}),
 		reflect.TypeOf(cliService.CliService{}),
+		reflect.TypeOf(devnodeService.DevNodeService{}),
 	)
 	if err != nil {
 		fmt.Println(err)
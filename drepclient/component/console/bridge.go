@@ -16,11 +16,20 @@
 package console
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math/big"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	accountTypes "github.com/drep-project/drepcli/accounts/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/drepclient/component/jsre"
+	"github.com/drep-project/drepcli/drepclient/component/jsre/deps"
 	"github.com/drep-project/drepcli/log"
 	"github.com/robertkrimen/otto"
 
@@ -32,55 +41,141 @@ import (
 // environment and the Go RPC connection backing the remote method calls.
 type bridge struct {
 	client   *rpcComponent.Client // RPC client to execute Ethereum requests through
+	jsre     *jsre.JSRE           // JavaScript runtime subscription callbacks are scheduled on
 	prompter UserPrompter         // Input prompter to allow interactive user feedback
 	printer  io.Writer            // Output writer to serialize any display strings to
+
+	batch      *pendingBatch         // calls collected by a running batch(), nil outside of batch()
+	formatters map[string]otto.Value // output formatters of the drep.js methods by RPC method
+
+	subsLock sync.Mutex
+	subs     map[string]*rpcComponent.ClientSubscription // active drep.subscribe subscriptions
 }
 
 // newBridge creates a new JavaScript wrapper around an RPC client.
-func newBridge(client *rpcComponent.Client, prompter UserPrompter, printer io.Writer) *bridge {
+func newBridge(client *rpcComponent.Client, re *jsre.JSRE, prompter UserPrompter, printer io.Writer) *bridge {
 	return &bridge{
-		client:   client,
-		prompter: prompter,
-		printer:  printer,
+		client:     client,
+		jsre:       re,
+		prompter:   prompter,
+		printer:    printer,
+		formatters: make(map[string]otto.Value),
+		subs:       make(map[string]*rpcComponent.ClientSubscription),
 	}
 }
 
-// NewAccount is a wrapper around the personal.newAccount RPC method that uses a
-// non-echoing password prompt to acquire the passphrase and executes the original
-// RPC method (saved in jeth.newAccount) with it to actually execute the RPC call.
-func (b *bridge) NewAccount(call otto.FunctionCall) (response otto.Value) {
-	var (
-		password string
-		confirm  string
-		err      error
-	)
+// OpenWallet implements account.open([password]). Without password it is read
+// with a non-echoing prompt, which keeps it out of the console history.
+func (b *bridge) OpenWallet(call otto.FunctionCall) (response otto.Value) {
+	password := b.passwordArgument(call, "Wallet passphrase: ")
+	return b.call(call.Otto, "account_open", password)
+}
+
+// UnlockWallet implements account.unLock([password]), prompting for the password
+// like OpenWallet.
+func (b *bridge) UnlockWallet(call otto.FunctionCall) (response otto.Value) {
+	password := b.passwordArgument(call, "Wallet passphrase: ")
+	return b.call(call.Otto, "account_unLock", password)
+}
+
+// LockWallet implements account.lock().
+func (b *bridge) LockWallet(call otto.FunctionCall) (response otto.Value) {
+	return b.call(call.Otto, "account_lock")
+}
+
+// CloseWallet implements account.close().
+func (b *bridge) CloseWallet(call otto.FunctionCall) (response otto.Value) {
+	return b.call(call.Otto, "account_close")
+}
+
+// CreateAccount implements account.createAccount([password]). A given password
+// unlocks the wallet, or opens it if it is closed, before the account is
+// created; it is refused if the wallet is unlocked already instead of being
+// ignored. Without password the account is created right away, a closed or
+// locked wallet is opened or unlocked with a prompted password first. The
+// wallet state is told by the error codes of the account API.
+func (b *bridge) CreateAccount(call otto.FunctionCall) (response otto.Value) {
+	if len(call.ArgumentList) > 1 || (len(call.ArgumentList) == 1 && !call.Argument(0).IsString()) {
+		throwJSException("usage: account.createAccount([password])")
+	}
+	if len(call.ArgumentList) == 1 {
+		password, _ := call.Argument(0).ToString()
+		switch err := b.client.Call(nil, "account_unLock", password); {
+		case isWalletError(err, accountTypes.ErrWalletNotOpen):
+			b.call(call.Otto, "account_open", password)
+		case isWalletError(err, accountTypes.ErrWalletAlreadyUnlocked):
+			throwJSException("the wallet is unlocked already, create the account without password")
+		case err != nil:
+			throwJSException(err.Error())
+		}
+		return b.call(call.Otto, "account_createAccount")
+	}
+	var result json.RawMessage
+	switch err := b.client.Call(&result, "account_createAccount"); {
+	case err == nil:
+		return parseResult(call.Otto, result)
+	case isWalletError(err, accountTypes.ErrWalletNotOpen):
+		b.call(call.Otto, "account_open", b.passwordArgument(call, "Wallet passphrase: "))
+	case isWalletError(err, accountTypes.ErrWalletLocked):
+		b.call(call.Otto, "account_unLock", b.passwordArgument(call, "Wallet passphrase: "))
+	default:
+		throwJSException(err.Error())
+	}
+	return b.call(call.Otto, "account_createAccount")
+}
+
+// isWalletError reports whether err is the wallet error walletErr returned by
+// the account API.
+func isWalletError(err error, walletErr *accountTypes.WalletError) bool {
+	rpcErr, ok := err.(rpcTypes.Error)
+	return ok && rpcErr.ErrorCode() == walletErr.Code
+}
+
+// passwordArgument returns the single string argument of the call or, without
+// arguments, prompts the user for it.
+func (b *bridge) passwordArgument(call otto.FunctionCall, prompt string) string {
 	switch {
 	// No password was specified, prompt the user for it
 	case len(call.ArgumentList) == 0:
-		if password, err = b.prompter.PromptPassword("Passphrase: "); err != nil {
-			throwJSException(err.Error())
+		if b.prompter == nil {
+			throwJSException("a password is required, the console isn't interactive")
 		}
-		if confirm, err = b.prompter.PromptPassword("Repeat passphrase: "); err != nil {
+		password, err := b.prompter.PromptPassword(prompt)
+		if err != nil {
 			throwJSException(err.Error())
 		}
-		if password != confirm {
-			throwJSException("passphrases don't match!")
-		}
+		return password
 
 	// A single string password was specified, use that
 	case len(call.ArgumentList) == 1 && call.Argument(0).IsString():
-		password, _ = call.Argument(0).ToString()
-
+		password, _ := call.Argument(0).ToString()
+		return password
+	}
 	// Otherwise fail with some error
-	default:
-		throwJSException("expected 0 or 1 string argument")
+	throwJSException("expected 0 or 1 string argument")
+	return ""
+}
+
+// call executes the RPC method and returns its result, RPC errors are thrown as
+// JavaScript exceptions.
+func (b *bridge) call(vm *otto.Otto, method string, args ...interface{}) otto.Value {
+	var result json.RawMessage
+	if err := b.client.Call(&result, method, args...); err != nil {
+		throwJSException(err.Error())
+	}
+	return parseResult(vm, result)
+}
+
+// parseResult converts a JSON result to a JavaScript value.
+func parseResult(vm *otto.Otto, result json.RawMessage) otto.Value {
+	if len(result) == 0 {
+		return otto.NullValue()
 	}
-	// Password acquired, execute the call and return
-	ret, err := call.Otto.Call("jeth.newAccount", nil, password)
+	value, err := jsre.ParseJSON(vm, result)
 	if err != nil {
 		throwJSException(err.Error())
 	}
-	return ret
+	return value
 }
 
 // Sleep will block the console for the specified number of seconds.
@@ -99,9 +194,68 @@ type jsonrpcCall struct {
 	Params []interface{}
 }
 
+// pendingBatch holds the calls collected by batch() until they are sent.
+type pendingBatch struct {
+	calls     []jsonrpcCall
+	callbacks []otto.Value // callback of every call, undefined for synchronous calls
+	latest    *uint64      // height the latest tag stands for within the batch, once asked for
+}
+
 // Send implements the web3 provider "send" method.
 func (b *bridge) Send(call otto.FunctionCall) (response otto.Value) {
-	// Remarshal the request into a Go value.
+	reqs, batch := b.decodeRequests(call)
+
+	// Single calls made while batch() collects calls are answered with a null
+	// result, their actual result is delivered by batch().
+	if b.batch != nil && !batch {
+		b.batch.calls = append(b.batch.calls, reqs[0])
+		b.batch.callbacks = append(b.batch.callbacks, call.Argument(1))
+		if fn := call.Argument(1); fn.Class() == "Function" {
+			return otto.UndefinedValue()
+		}
+		resp, _ := call.Otto.Object(`({"jsonrpc":"2.0","result":null})`)
+		resp.Set("id", reqs[0].ID)
+		return resp.Value()
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	results, errs := b.perform(reqs, batch)
+	response = providerResponse(b.responses(call.Otto, JSON, reqs, results, errs), batch)
+
+	// Return the responses either to the callback (if supplied)
+	// or directly as the return value.
+	if fn := call.Argument(1); fn.Class() == "Function" {
+		fn.Call(otto.NullValue(), otto.NullValue(), response)
+		return otto.UndefinedValue()
+	}
+	return response
+}
+
+// SendAsync implements the web3 provider "sendAsync" method. The requests are
+// performed on a goroutine and the callback is invoked on the event loop, so
+// the console isn't blocked and several calls are in flight at once. Without
+// callback, and while batch() collects calls, it behaves like Send.
+func (b *bridge) SendAsync(call otto.FunctionCall) (response otto.Value) {
+	callback := call.Argument(1)
+	if callback.Class() != "Function" || b.batch != nil {
+		return b.Send(call)
+	}
+	reqs, batch := b.decodeRequests(call)
+	release := b.jsre.Hold()
+	go func() {
+		defer release()
+		results, errs := b.perform(reqs, batch)
+		b.jsre.Do(func(vm *otto.Otto) {
+			JSON, _ := vm.Object("JSON")
+			response := providerResponse(b.responses(vm, JSON, reqs, results, errs), batch)
+			b.jsre.CallFunction(vm, callback, otto.NullValue(), response)
+		})
+	}()
+	return otto.UndefinedValue()
+}
+
+// decodeRequests remarshals the request or batch of requests passed to the
+// provider into Go values.
+func (b *bridge) decodeRequests(call otto.FunctionCall) (reqs []jsonrpcCall, batch bool) {
 	JSON, _ := call.Otto.Object("JSON")
 	reqVal, err := JSON.Call("stringify", call.Argument(0))
 	if err != nil {
@@ -110,8 +264,6 @@ func (b *bridge) Send(call otto.FunctionCall) (response otto.Value) {
 	var (
 		rawReq = reqVal.String()
 		dec    = json.NewDecoder(strings.NewReader(rawReq))
-		reqs   []jsonrpcCall
-		batch  bool
 	)
 	dec.UseNumber() // avoid float64s
 	if rawReq[0] == '[' {
@@ -122,22 +274,215 @@ func (b *bridge) Send(call otto.FunctionCall) (response otto.Value) {
 		reqs = make([]jsonrpcCall, 1)
 		dec.Decode(&reqs[0])
 	}
+	if err := b.convertParams(reqs); err != nil {
+		throwJSException(err.Error())
+	}
+	return reqs, batch
+}
+
+// maxSafeInteger is the largest integer a JavaScript number holds exactly.
+var maxSafeInteger = new(big.Rat).SetInt64(1<<53 - 1)
 
-	// Execute the requests.
-	resps, _ := call.Otto.Object("new Array()")
+// convertParams converts the parameters of the requests to the values nodes
+// expect. Amounts may have a unit suffix like "1.5 drep" and are sent as
+// attodrep strings, the block tags latest and earliest are sent as heights.
+func (b *bridge) convertParams(reqs []jsonrpcCall) error {
 	for _, req := range reqs {
-		resp, _ := call.Otto.Object(`({"jsonrpc":"2.0"})`)
+		method, ok := deps.Lookup(req.Method)
+		if !ok {
+			continue
+		}
+		for i, param := range method.Params {
+			if i >= len(req.Params) {
+				break
+			}
+			if tag, ok := req.Params[i].(string); ok && param.Type == deps.ParamBlock {
+				height, err := b.blockHeight(tag)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %v", req.Method, param.Name, err)
+				}
+				req.Params[i] = height
+				continue
+			}
+			if param.Type != deps.ParamAmount {
+				continue
+			}
+			var raw string
+			switch value := req.Params[i].(type) {
+			case string:
+				raw = value
+			case json.Number:
+				if err := checkExactNumber(string(value)); err != nil {
+					return fmt.Errorf("%s: %s: %v", req.Method, param.Name, err)
+				}
+				raw = string(value)
+			default:
+				return fmt.Errorf("%s: %s: invalid amount %v", req.Method, param.Name, value)
+			}
+			amount, err := common.ParseAmount(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %v", req.Method, param.Name, err)
+			}
+			req.Params[i] = amount.String()
+		}
+	}
+	return nil
+}
+
+// blockHeight returns the height of a block tag or of a height passed as string.
+// The latest tag needs the height of the node, which is asked for when the call
+// is made. Within batch() it is asked for once, before the batch is sent rather
+// than as part of it, so all calls of the batch refer to the same block.
+func (b *bridge) blockHeight(tag string) (uint64, error) {
+	switch number, ok := common.BlockTags[tag]; {
+	case !ok:
+		height, err := strconv.ParseUint(tag, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid block %q, expected a height, latest or earliest", tag)
+		}
+		return height, nil
+	case number == common.LatestBlockNumber:
+		if b.batch != nil && b.batch.latest != nil {
+			return *b.batch.latest, nil
+		}
+		var height uint64
+		if err := b.client.Call(&height, "db_getMaxHeight"); err != nil {
+			return 0, err
+		}
+		if b.batch != nil {
+			b.batch.latest = &height
+		}
+		return height, nil
+	case number == common.EarliestBlockNumber:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("nodes don't store %s blocks", tag)
+}
+
+// checkExactNumber returns an error for numbers beyond the integers a
+// JavaScript number represents exactly, their digits were lost already.
+func checkExactNumber(number string) error {
+	value, ok := new(big.Rat).SetString(number)
+	if ok && new(big.Rat).Abs(value).Cmp(maxSafeInteger) <= 0 {
+		return nil
+	}
+	return fmt.Errorf("%s isn't exact as a JavaScript number, pass the amount as a string, e.g. \"1.5 drep\"", number)
+}
+
+// amountArgument returns the text of a number, BigNumber or string amount.
+func amountArgument(value otto.Value) string {
+	switch {
+	case value.IsNumber():
+		if err := checkExactNumber(value.String()); err != nil {
+			throwJSException(err.Error())
+		}
+	case value.IsObject():
+		// BigNumber.toString uses the exponential notation for large numbers
+		if toFixed, _ := value.Object().Get("toFixed"); toFixed.IsFunction() {
+			fixed, _ := value.Object().Call("toFixed")
+			return fixed.String()
+		}
+	}
+	return value.String()
+}
+
+// ToUnit implements toUnit(amount, [unit]). It converts an amount of attodrep
+// to a decimal string in unit, drep by default.
+func (b *bridge) ToUnit(call otto.FunctionCall) otto.Value {
+	amount, err := common.ParseAmount(amountArgument(call.Argument(0)))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	value, _ := call.Otto.ToValue(common.FormatAmount(amount, unitArgument(call.Argument(1))))
+	return value
+}
+
+// FromUnit implements fromUnit(value, [unit]). It converts a value in unit,
+// drep by default, to a string of attodrep. The unit may also be a suffix of
+// the value, e.g. fromUnit("1.5 millidrep").
+func (b *bridge) FromUnit(call otto.FunctionCall) otto.Value {
+	text := strings.TrimSpace(amountArgument(call.Argument(0)))
+	if call.Argument(1).IsDefined() || !strings.HasSuffix(strings.ToLower(text), "drep") {
+		text += " " + unitArgument(call.Argument(1)).Name
+	}
+	amount, err := common.ParseAmount(text)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	value, _ := call.Otto.ToValue(amount.String())
+	return value
+}
+
+// unitArgument returns the unit named by an optional argument, drep by default.
+func unitArgument(arg otto.Value) common.Unit {
+	if !arg.IsDefined() {
+		return common.Drep
+	}
+	unit, ok := common.LookupUnit(arg.String())
+	if !ok {
+		throwJSException(fmt.Sprintf("unknown unit %q", arg.String()))
+	}
+	return unit
+}
+
+// providerResponse returns the array of responses for a batch and the single
+// response otherwise.
+func providerResponse(resps *otto.Object, batch bool) otto.Value {
+	if batch {
+		return resps.Value()
+	}
+	response, _ := resps.Get("0")
+	return response
+}
+
+// execute performs the requests and returns an array of their responses. A
+// batch is sent to the server as a single batch request.
+func (b *bridge) execute(vm *otto.Otto, JSON *otto.Object, reqs []jsonrpcCall, batch bool) *otto.Object {
+	results, errs := b.perform(reqs, batch)
+	return b.responses(vm, JSON, reqs, results, errs)
+}
+
+// perform sends the requests and returns their raw results and errors. It
+// doesn't touch the JavaScript runtime and may run on any goroutine.
+func (b *bridge) perform(reqs []jsonrpcCall, batch bool) ([]json.RawMessage, []error) {
+	results := make([]json.RawMessage, len(reqs))
+	errs := make([]error, len(reqs))
+	if batch {
+		elems := make([]rpcComponent.BatchElem, len(reqs))
+		for i, req := range reqs {
+			elems[i] = rpcComponent.BatchElem{Method: req.Method, Args: req.Params, Result: &results[i]}
+		}
+		if err := b.client.BatchCall(elems); err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+		} else {
+			for i, elem := range elems {
+				errs[i] = elem.Error
+			}
+		}
+	} else {
+		for i, req := range reqs {
+			errs[i] = b.client.Call(&results[i], req.Method, req.Params...)
+		}
+	}
+	return results, errs
+}
+
+// responses converts results and errors to an array of JSON-RPC responses.
+func (b *bridge) responses(vm *otto.Otto, JSON *otto.Object, reqs []jsonrpcCall, results []json.RawMessage, errs []error) *otto.Object {
+	resps, _ := vm.Object("new Array()")
+	for i, req := range reqs {
+		resp, _ := vm.Object(`({"jsonrpc":"2.0"})`)
 		resp.Set("id", req.ID)
-		var result json.RawMessage
-		err = b.client.Call(&result, req.Method, req.Params...)
-		switch err := err.(type) {
+		switch err := errs[i].(type) {
 		case nil:
-			if result == nil {
+			if results[i] == nil {
 				// Special case null because it is decoded as an empty
 				// raw message for some reason.
 				resp.Set("result", otto.NullValue())
 			} else {
-				resultVal, err := JSON.Call("parse", string(result))
+				resultVal, err := jsre.ParseJSON(vm, results[i])
 				if err != nil {
 					setError(resp, -32603, err.Error())
 				} else {
@@ -151,19 +496,178 @@ func (b *bridge) Send(call otto.FunctionCall) (response otto.Value) {
 		}
 		resps.Call("push", resp)
 	}
+	return resps
+}
 
-	// Return the responses either to the callback (if supplied)
-	// or directly as the return value.
-	if batch {
-		response = resps.Value()
-	} else {
-		response, _ = resps.Get("0")
+// SetFormatter implements jeth.setFormatter(method, formatter), through which
+// the drep.js methods register their output formatter for batch().
+func (b *bridge) SetFormatter(call otto.FunctionCall) (response otto.Value) {
+	if formatter := call.Argument(1); formatter.Class() == "Function" {
+		b.formatters[call.Argument(0).String()] = formatter
 	}
-	if fn := call.Argument(1); fn.Class() == "Function" {
-		fn.Call(otto.NullValue(), otto.NullValue(), response)
-		return otto.UndefinedValue()
+	return otto.UndefinedValue()
+}
+
+// Batch implements batch(function(){...}). The RPC calls made by the function
+// are collected instead of sent, they return null. Once the function returns
+// they are sent as a single batch request. Batch returns one object per call,
+// in call order, holding the method and either its result, formatted like the
+// result of a single call, or its error. Callbacks of asynchronous calls are
+// invoked with their own response.
+func (b *bridge) Batch(call otto.FunctionCall) (response otto.Value) {
+	fn := call.Argument(0)
+	if fn.Class() != "Function" {
+		throwJSException("usage: batch(function(){...})")
+	}
+	if b.batch != nil {
+		throwJSException("batch() can't be nested")
+	}
+	// an interrupted function panics through Call, the batch ends all the same
+	b.batch = new(pendingBatch)
+	defer func() { b.batch = nil }()
+	if _, err := fn.Call(otto.NullValue()); err != nil {
+		throwJSException(err.Error())
+	}
+	pending := b.batch
+	b.batch = nil
+
+	JSON, _ := call.Otto.Object("JSON")
+	results, _ := call.Otto.Object("new Array()")
+	if len(pending.calls) == 0 {
+		return results.Value()
+	}
+	resps := b.execute(call.Otto, JSON, pending.calls, true)
+	for i, req := range pending.calls {
+		resp, _ := resps.Get(strconv.Itoa(i))
+		if callback := pending.callbacks[i]; callback.Class() == "Function" {
+			callback.Call(otto.NullValue(), otto.NullValue(), resp)
+		}
+		result, _ := call.Otto.Object(`({})`)
+		result.Set("method", req.Method)
+		if errVal, _ := resp.Object().Get("error"); errVal.IsDefined() {
+			result.Set("error", errVal)
+		} else {
+			resultVal, _ := resp.Object().Get("result")
+			if formatter, ok := b.formatters[req.Method]; ok {
+				formatted, err := formatter.Call(otto.NullValue(), resultVal)
+				if err != nil {
+					throwJSException(err.Error())
+				}
+				resultVal = formatted
+			}
+			result.Set("result", resultVal)
+		}
+		results.Call("push", result)
+	}
+	return results.Value()
+}
+
+// Subscribe implements drep.subscribe(name, [args...], callback). It subscribes to
+// the chain events with the given name and invokes callback(error, event) on the
+// JavaScript event loop for every notification. It returns an object holding the
+// subscription id and an unsubscribe method.
+func (b *bridge) Subscribe(call otto.FunctionCall) (response otto.Value) {
+	if len(call.ArgumentList) < 2 || !call.Argument(0).IsString() {
+		throwJSException("usage: drep.subscribe(name, [args...], callback)")
+	}
+	callback := call.ArgumentList[len(call.ArgumentList)-1]
+	if callback.Class() != "Function" {
+		throwJSException("last argument to drep.subscribe must be a callback function")
+	}
+	name, _ := call.Argument(0).ToString()
+
+	// Remarshal the subscription arguments into Go values.
+	JSON, _ := call.Otto.Object("JSON")
+	args := []interface{}{name}
+	for _, arg := range call.ArgumentList[1 : len(call.ArgumentList)-1] {
+		argVal, err := JSON.Call("stringify", arg)
+		if err != nil {
+			throwJSException(err.Error())
+		}
+		args = append(args, json.RawMessage(argVal.String()))
+	}
+
+	events := make(chan json.RawMessage)
+	sub, err := b.client.Subscribe(context.Background(), rpcComponent.ChainNamespace, events, args...)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	b.subsLock.Lock()
+	b.subs[sub.ID()] = sub
+	b.subsLock.Unlock()
+	go b.forward(sub, events, callback)
+
+	obj, _ := call.Otto.Object(`({})`)
+	obj.Set("id", sub.ID())
+	obj.Set("unsubscribe", func(call otto.FunctionCall) otto.Value {
+		b.unsubscribe(sub.ID())
+		return otto.TrueValue()
+	})
+	return obj.Value()
+}
+
+// Unsubscribe implements drep.unsubscribe(id).
+func (b *bridge) Unsubscribe(call otto.FunctionCall) (response otto.Value) {
+	id, err := call.Argument(0).ToString()
+	if err != nil {
+		throwJSException("usage: drep.unsubscribe(id)")
+	}
+	if !b.unsubscribe(id) {
+		return otto.FalseValue()
+	}
+	return otto.TrueValue()
+}
+
+// forward delivers the events of a subscription to its callback until the
+// subscription ends.
+func (b *bridge) forward(sub *rpcComponent.ClientSubscription, events chan json.RawMessage, callback otto.Value) {
+	for {
+		select {
+		case event := <-events:
+			b.jsre.Do(func(vm *otto.Otto) {
+				eventVal, err := jsre.ParseJSON(vm, event)
+				if err != nil {
+					fmt.Fprintln(b.printer, "subscription error:", err)
+					return
+				}
+				b.jsre.CallFunction(vm, callback, otto.NullValue(), eventVal)
+			})
+		case err, ok := <-sub.Err():
+			if ok && err != nil {
+				b.jsre.Do(func(vm *otto.Otto) {
+					callback.Call(otto.NullValue(), err.Error())
+				})
+			}
+			b.subsLock.Lock()
+			delete(b.subs, sub.ID())
+			b.subsLock.Unlock()
+			return
+		}
+	}
+}
+
+// unsubscribe ends the subscription with the given id, it reports whether the
+// subscription was active.
+func (b *bridge) unsubscribe(id string) bool {
+	b.subsLock.Lock()
+	sub, ok := b.subs[id]
+	delete(b.subs, id)
+	b.subsLock.Unlock()
+	if ok {
+		sub.Unsubscribe()
+	}
+	return ok
+}
+
+// closeSubscriptions ends all active subscriptions.
+func (b *bridge) closeSubscriptions() {
+	b.subsLock.Lock()
+	subs := b.subs
+	b.subs = make(map[string]*rpcComponent.ClientSubscription)
+	b.subsLock.Unlock()
+	for _, sub := range subs {
+		sub.Unsubscribe()
 	}
-	return response
 }
 
 func setError(resp *otto.Object, code int, msg string) {
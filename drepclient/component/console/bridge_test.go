@@ -0,0 +1,126 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"testing"
+
+	accountTypes "github.com/drep-project/drepcli/accounts/types"
+)
+
+// AccountTestService is a wallet answering like the account API of a node.
+type AccountTestService struct {
+	open, locked bool
+	password     string // password the wallet was last opened or unlocked with
+	created      int
+	listed       int // number of account_addressList calls
+}
+
+func (s *AccountTestService) Open(password string) error {
+	if s.open {
+		return accountTypes.ErrWalletAlreadyOpen
+	}
+	s.open, s.locked, s.password = true, false, password
+	return nil
+}
+
+func (s *AccountTestService) UnLock(password string) error {
+	switch {
+	case !s.open:
+		return accountTypes.ErrWalletNotOpen
+	case !s.locked:
+		return accountTypes.ErrWalletAlreadyUnlocked
+	}
+	s.locked, s.password = false, password
+	return nil
+}
+
+func (s *AccountTestService) CreateAccount() (string, error) {
+	switch {
+	case !s.open:
+		return "", accountTypes.ErrWalletNotOpen
+	case s.locked:
+		return "", accountTypes.ErrWalletLocked
+	}
+	s.created++
+	return testAddress, nil
+}
+
+func (s *AccountTestService) AddressList() ([]string, error) {
+	s.listed++
+	if !s.open {
+		return nil, accountTypes.ErrWalletNotOpen
+	}
+	return []string{testAddress}, nil
+}
+
+func TestCreateAccount(t *testing.T) {
+	tests := []struct {
+		name         string
+		open, locked bool
+		call         string
+		prompted     int    // number of password prompts
+		password     string // password the wallet ends up with
+		fails        bool
+	}{
+		{name: "closed, prompted", call: "account.createAccount()", prompted: 1, password: "prompted"},
+		{name: "locked, prompted", open: true, locked: true, call: "account.createAccount()", prompted: 1, password: "prompted"},
+		{name: "unlocked", open: true, call: "account.createAccount()", password: "old"},
+		{name: "closed, given", call: `account.createAccount("given")`, password: "given"},
+		{name: "locked, given", open: true, locked: true, call: `account.createAccount("given")`, password: "given"},
+		{name: "unlocked, given", open: true, call: `account.createAccount("given")`, password: "old", fails: true},
+	}
+	for _, test := range tests {
+		wallet := &AccountTestService{open: test.open, locked: test.locked, password: "old"}
+		env := newTester(t, map[string]interface{}{"account": wallet})
+		env.prompter.passwords = []string{"prompted"}
+
+		_, err := env.console.jsre.Run(test.call)
+		if test.fails {
+			if err == nil || wallet.created != 0 {
+				t.Errorf("%s: expected the call to fail without creating an account, got %v", test.name, err)
+			}
+		} else if err != nil || wallet.created != 1 {
+			t.Errorf("%s: created %d accounts, %v", test.name, wallet.created, err)
+		}
+		if env.prompter.prompted != test.prompted {
+			t.Errorf("%s: prompted %d times for the password, want %d", test.name, env.prompter.prompted, test.prompted)
+		}
+		if wallet.password != test.password {
+			t.Errorf("%s: wallet password %q, want %q", test.name, wallet.password, test.password)
+		}
+	}
+}
+
+func TestOpenWallet(t *testing.T) {
+	wallet := new(AccountTestService)
+	env := newTester(t, map[string]interface{}{"account": wallet})
+	env.prompter.passwords = []string{"prompted"}
+
+	if _, err := env.console.jsre.Run("account.open()"); err != nil {
+		t.Fatal(err)
+	}
+	if env.prompter.prompted != 1 || wallet.password != "prompted" {
+		t.Errorf("prompted %d times, wallet opened with %q", env.prompter.prompted, wallet.password)
+	}
+	if _, err := env.console.jsre.Run(`account.open("given")`); err == nil || err.Error() != accountTypes.ErrWalletAlreadyOpen.Error() {
+		t.Errorf("expected the error of the open wallet, got %v", err)
+	}
+	if _, err := env.console.jsre.Run("account.open(1)"); err == nil {
+		t.Error("expected a non-string password to fail")
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/drepclient/component/jsre/deps"
+)
+
+var addressRegexp = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+
+// argument is the argument at the cursor within the argument list of a
+// console method, e.g. the 1 of db.getBlock(1<tab>.
+type argument struct {
+	method deps.Method
+	index  int    // index of the parameter
+	text   string // text typed so far
+}
+
+// consoleMethod returns the method called by an expression like db.getBlock
+// or drep.db.getBlock.
+func consoleMethod(expr string) (deps.Method, bool) {
+	expr = strings.TrimPrefix(expr, "drep.")
+	for _, method := range deps.Methods() {
+		if expr == method.Namespace+"."+method.Name {
+			return method, true
+		}
+	}
+	return deps.Method{}, false
+}
+
+// findArgument returns the argument the input ends in if it is passed to a
+// console method.
+func findArgument(input string) (argument, bool) {
+	type frame struct {
+		callee string // the called expression, empty for arrays and objects
+		index  int    // index of the current argument
+		start  int    // offset of the current argument
+	}
+	var (
+		stack []frame
+		quote byte
+	)
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			start := i
+			for start > 0 && isExprChar(input[start-1]) {
+				start--
+			}
+			stack = append(stack, frame{callee: input[start:i], start: i + 1})
+		case c == '[' || c == '{':
+			stack = append(stack, frame{start: i + 1})
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case c == ',' && len(stack) > 0:
+			stack[len(stack)-1].index++
+			stack[len(stack)-1].start = i + 1
+		}
+	}
+	if len(stack) == 0 {
+		return argument{}, false
+	}
+	top := stack[len(stack)-1]
+	method, ok := consoleMethod(top.callee)
+	if !ok || top.index >= len(method.Params) {
+		return argument{}, false
+	}
+	return argument{method: method, index: top.index, text: strings.TrimLeft(input[top.start:], " \t")}, true
+}
+
+func isExprChar(c byte) bool {
+	return c == '.' || c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// completeArgument completes a string argument of a console method at the
+// cursor. Addresses are taken from the wallet and the history, blocks may be
+// given by tag. The signature of the method is listed along with the values
+// if nothing is typed yet, or with the parameter if there are no values. It
+// doesn't share a prefix with them, so it is never inserted.
+func (c *Console) completeArgument(line string, pos int) (string, []string, string, bool) {
+	arg, ok := findArgument(line[:pos])
+	// expressions passed as argument are completed like any other
+	if !ok || arg.text != "" && arg.text[0] != '"' && arg.text[0] != '\'' {
+		return "", nil, "", false
+	}
+	param := arg.method.Params[arg.index]
+	quote := `"`
+	if strings.HasPrefix(arg.text, "'") {
+		quote = "'"
+	}
+	var values []string
+	switch param.Type {
+	case deps.ParamAddress:
+		values = c.knownAddresses()
+	case deps.ParamChainId:
+		values = []string{param.Default}
+	case deps.ParamBlock:
+		for tag, number := range common.BlockTags {
+			if number != common.PendingBlockNumber { // pending blocks aren't stored by nodes
+				values = append(values, tag)
+			}
+		}
+		sort.Strings(values)
+	}
+	var completions []string
+	for _, value := range values {
+		if value = quote + value + quote; strings.HasPrefix(value, arg.text) {
+			completions = append(completions, value)
+		}
+	}
+	if len(completions) > 0 {
+		if arg.text == "" {
+			completions = append(completions, arg.method.Signature())
+		}
+		return line[:pos-len(arg.text)], completions, line[pos:], true
+	}
+	hint := "<" + param.Name + ": " + param.Type
+	if param.Default != "" {
+		hint += ", default " + param.Default
+	}
+	return line[:pos], []string{arg.method.Signature(), hint + ">"}, line[pos:], true
+}
+
+// knownAddresses returns the addresses of the wallet followed by the other
+// addresses found in the history. The wallet is asked once per prompt, not on
+// every Tab. If it can't be listed, e.g. because it isn't open, only the
+// addresses of the history are offered.
+func (c *Console) knownAddresses() []string {
+	var (
+		addresses []string
+		seen      = make(map[string]bool)
+	)
+	add := func(address string) {
+		if address = strings.ToLower(address); !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	if !c.walletLoaded {
+		var wallet []string
+		if err := c.client.Call(&wallet, "account_addressList"); err != nil {
+			wallet = nil
+		}
+		c.wallet, c.walletLoaded = wallet, true
+	}
+	for _, address := range c.wallet {
+		add(address)
+	}
+	for i := len(c.history) - 1; i >= 0; i-- {
+		for _, address := range addressRegexp.FindAllString(c.history[i], -1) {
+			add(address)
+		}
+	}
+	return addresses
+}
+
+// signatures replaces the console methods among completions by their
+// signatures, e.g. db.getBlock becomes db.getBlock(height).
+func signatures(completions []string) []string {
+	result := make([]string, len(completions))
+	for i, completion := range completions {
+		result[i] = completion
+		if method, ok := consoleMethod(completion); ok {
+			result[i] = strings.TrimSuffix(completion, method.Namespace+"."+method.Name) + method.Signature()
+		}
+	}
+	return result
+}
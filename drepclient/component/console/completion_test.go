@@ -0,0 +1,138 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"reflect"
+	"testing"
+)
+
+const historyAddress = "0x0000000000000000000000000000000000000b0b"
+
+func TestFindArgument(t *testing.T) {
+	tests := []struct {
+		input string
+		found bool
+		call  string // RPC method of the argument
+		index int
+		text  string
+	}{
+		{input: "db.getBlock(", found: true, call: "db_getBlock", text: ""},
+		{input: "db.getBlock(1", found: true, call: "db_getBlock", text: "1"},
+		{input: "drep.db.getBlock(", found: true, call: "db_getBlock", text: ""},
+		{input: `db.getBalance("0x77", `, found: true, call: "db_getBalance", index: 1, text: ""},
+		{input: `db.getBalance("0x77", "0`, found: true, call: "db_getBalance", index: 1, text: `"0`},
+		{input: `db.getBalance("a,b(`, found: true, call: "db_getBalance", text: `"a,b(`},
+		{input: `db.getBalance('it\'s, `, found: true, call: "db_getBalance", text: `'it\'s, `},
+		{input: "db.getBlock(db.getMaxHeight(", found: false},
+		{input: "db.getBlock(db.getMaxHeight() - ", found: true, call: "db_getBlock", text: "db.getMaxHeight() - "},
+		{input: "db.getBlock([1, ", found: false},
+		{input: "db.getBlock(1, ", found: false}, // beyond the parameters
+		{input: "db.getBlock(1)", found: false},
+		{input: "foo(", found: false},
+		{input: "db.getBlock", found: false},
+	}
+	for _, test := range tests {
+		arg, found := findArgument(test.input)
+		if found != test.found {
+			t.Errorf("%s: found %v, want %v", test.input, found, test.found)
+			continue
+		}
+		if found && (arg.method.Call != test.call || arg.index != test.index || arg.text != test.text) {
+			t.Errorf("%s: found argument %d %q of %s, want %d %q of %s", test.input, arg.index, arg.text, arg.method.Call, test.index, test.text, test.call)
+		}
+	}
+}
+
+func TestCompleteArgument(t *testing.T) {
+	tests := []struct {
+		line        string
+		open        bool // whether the wallet is open
+		completed   bool
+		head        string
+		completions []string
+	}{
+		{
+			line: `db.getBalance(`, open: true, completed: true, head: `db.getBalance(`,
+			completions: []string{`"` + testAddress + `"`, `"` + historyAddress + `"`, "db.getBalance(address, chainId)"},
+		},
+		{
+			line: `db.getBalance("0x0`, open: true, completed: true, head: `db.getBalance(`,
+			completions: []string{`"` + historyAddress + `"`},
+		},
+		{
+			line: `db.getBalance(`, completed: true, head: `db.getBalance(`,
+			completions: []string{`"` + historyAddress + `"`, `"` + testAddress + `"`, "db.getBalance(address, chainId)"},
+		},
+		{
+			line: `db.getBalance('0x7`, open: true, completed: true, head: `db.getBalance(`,
+			completions: []string{`'` + testAddress + `'`},
+		},
+		{
+			line: `db.getBalance("0x1`, open: true, completed: true, head: `db.getBalance("0x1`,
+			completions: []string{"db.getBalance(address, chainId)", "<address: address>"},
+		},
+		{
+			line: `db.getBalance("0x77", `, completed: true, head: `db.getBalance("0x77", `,
+			completions: []string{`"0x00"`, "db.getBalance(address, chainId)"},
+		},
+		{
+			line: `db.getBlock("l`, completed: true, head: `db.getBlock(`,
+			completions: []string{`"latest"`},
+		},
+		{
+			line: `db.getBlock(`, completed: true, head: `db.getBlock(`,
+			completions: []string{`"earliest"`, `"latest"`, "db.getBlock(height)"},
+		},
+		{line: `db.getBlock(db.get`},
+		{line: `db.getBlock`},
+	}
+	for _, test := range tests {
+		wallet := &AccountTestService{open: test.open}
+		env := newTester(t, map[string]interface{}{"account": wallet})
+		env.console.history = []string{`db.getNonce("` + testAddress + `")`, `db.getNonce("` + historyAddress + `")`}
+
+		head, completions, tail, completed := env.console.completeArgument(test.line+")", len(test.line))
+		if completed != test.completed {
+			t.Errorf("%s: completed %v, want %v", test.line, completed, test.completed)
+			continue
+		}
+		if !completed {
+			continue
+		}
+		if head != test.head || tail != ")" || !reflect.DeepEqual(completions, test.completions) {
+			t.Errorf("%s: completed to %q %q %q, want %q %q %q", test.line, head, completions, tail, test.head, test.completions, ")")
+		}
+	}
+}
+
+func TestKnownAddressesCached(t *testing.T) {
+	wallet := &AccountTestService{open: true}
+	env := newTester(t, map[string]interface{}{"account": wallet})
+
+	for i := 0; i < 3; i++ {
+		env.console.AutoCompleteInput("db.getBalance(", len("db.getBalance("))
+	}
+	if wallet.listed != 1 {
+		t.Errorf("the wallet was listed %d times during a prompt, want once", wallet.listed)
+	}
+	env.console.wallet, env.console.walletLoaded = nil, false // a new prompt
+	env.console.AutoCompleteInput("db.getBalance(", len("db.getBalance("))
+	if wallet.listed != 2 {
+		t.Errorf("the wallet was listed %d times for two prompts, want twice", wallet.listed)
+	}
+}
@@ -17,16 +17,20 @@
 package console
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/mattn/go-colorable"
 	"github.com/peterh/liner"
@@ -37,14 +41,33 @@ import (
 )
 
 var (
-	passwordRegexp = regexp.MustCompile(`personal.[nus]`)
+	passwordRegexp = regexp.MustCompile(`account\.(open|unLock|createAccount)\s*\(\s*[^\s)]|account_(open|unLock)`)
 	onlyWhitespace = regexp.MustCompile(`^\s*$`)
 	exit           = regexp.MustCompile(`^\s*exit\s*;*\s*$`)
+	edit           = regexp.MustCompile(`^\s*\.edit(?:\s+(\S+))?\s*$`)
 )
 
 // HistoryFile is the file within the data directory to store input scrollback.
 const HistoryFile = "history"
 
+// DefaultHistorySize is the default number of commands kept in the history.
+const DefaultHistorySize = 1000
+
+// EditFile is the file within the data directory holding the script composed
+// with .edit.
+const EditFile = "edit.js"
+
+// formatterHook makes the drep.js methods register their output formatter with
+// the bridge before their request is sent.
+const formatterHook = `(function (Method) {
+	var toPayload = Method.prototype.toPayload;
+	Method.prototype.toPayload = function (args) {
+		var payload = toPayload.call(this, args);
+		jeth.setFormatter(payload.method, this.formatOutput.bind(this));
+		return payload;
+	};
+})(drep._extend.Method);`
+
 // DefaultPrompt is the default prompt line prefix to use for user input querying.
 const DefaultPrompt = "> "
 
@@ -58,6 +81,14 @@ type Config struct {
 	Prompter UserPrompter         // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
 	Printer  io.Writer            // Output writer to serialize any display strings to (defaults to os.Stdout)
 	Preload  []string             // Absolute paths to JavaScript files to preload
+	Format   string               // Output format of results (defaults to pretty)
+
+	Endpoints   []string // Endpoints the client is attached to, each set has its own history
+	HistorySize int      // Number of commands kept in the history (defaults to DefaultHistorySize)
+	NoHistory   bool     // Neither read nor write a history, e.g. when running a script
+
+	Timeout   time.Duration // Time limit of each statement (0 for no limit)
+	MaxOutput int           // Size in bytes after which interactive results are cut (0 for no limit)
 }
 
 // Console is a JavaScript interpreted runtime environment. It is a fully fledged
@@ -66,11 +97,19 @@ type Config struct {
 type Console struct {
 	client   *rpcConponent.Client // RPC client to execute Ethereum requests through
 	jsre     *jsre.JSRE           // JavaScript runtime environment running the interpreter
+	bridge   *bridge              // JavaScript <-> Go RPC bridge owning the console subscriptions
 	prompt   string               // Input prompt prefix string
 	prompter UserPrompter         // Input prompter to allow interactive user feedback
-	histPath string               // Absolute path to the console scrollback history
+	histPath string               // Absolute path to the console scrollback history, empty without history
+	histSize int                  // Number of commands kept in the history
 	history  []string             // Scroll history maintained by the console
+	editPath string               // Absolute path to the script composed with .edit
 	printer  io.Writer            // Output writer to serialize any display strings to
+
+	wallet       []string // Wallet addresses offered by completion during the current prompt
+	walletLoaded bool     // Whether the wallet addresses were asked for during the current prompt
+
+	maxOutput int // Size in bytes after which interactive results are cut
 }
 
 // New initializes a JavaScript interpreted runtime environment and sets defaults
@@ -86,6 +125,9 @@ func New(config Config) (*Console, error) {
 	if config.Printer == nil {
 		config.Printer = colorable.NewColorableStdout()
 	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = DefaultHistorySize
+	}
 	// Initialize the console and return
 	console := &Console{
 		client:   config.Client,
@@ -93,10 +135,26 @@ func New(config Config) (*Console, error) {
 		prompt:   config.Prompt,
 		prompter: config.Prompter,
 		printer:  config.Printer,
-		histPath: filepath.Join(config.HomeDir, HistoryFile),
+		histSize: config.HistorySize,
+		editPath: filepath.Join(config.HomeDir, EditFile),
+
+		maxOutput: config.MaxOutput,
 	}
-	if err := os.MkdirAll(config.HomeDir, 0700); err != nil {
-		return nil, err
+	if config.Format != "" {
+		if err := console.jsre.SetFormat(config.Format); err != nil {
+			return nil, err
+		}
+	}
+	console.jsre.SetTimeout(config.Timeout)
+
+	if !config.NoHistory {
+		console.histPath = HistoryPath(config.HomeDir, config.Endpoints)
+		if err := os.MkdirAll(filepath.Dir(console.histPath), 0700); err != nil {
+			return nil, err
+		}
+		if err := migrateHistory(config.HomeDir, console.histPath); err != nil {
+			return nil, err
+		}
 	}
 	if err := console.init(config.Preload); err != nil {
 		return nil, err
@@ -108,12 +166,14 @@ func New(config Config) (*Console, error) {
 // the console's JavaScript namespaces based on the exposed modules.
 func (c *Console) init(preload []string) error {
 	// Initialize the JavaScript <-> Go RPC bridge
-	bridge := newBridge(c.client, c.prompter, c.printer)
+	bridge := newBridge(c.client, c.jsre, c.prompter, c.printer)
+	c.bridge = bridge
 	c.jsre.Set("jeth", struct{}{})
 
 	jethObj, _ := c.jsre.Get("jeth")
 	jethObj.Object().Set("send", bridge.Send)
-	jethObj.Object().Set("sendAsync", bridge.Send)
+	jethObj.Object().Set("sendAsync", bridge.SendAsync)
+	jethObj.Object().Set("setFormatter", bridge.SetFormatter)
 
 	consoleObj, _ := c.jsre.Get("console")
 	consoleObj.Object().Set("log", c.consoleOutput)
@@ -132,6 +192,22 @@ func (c *Console) init(preload []string) error {
 	if _, err := c.jsre.Run("var drep = new Drep(jeth);"); err != nil {
 		return fmt.Errorf("drep provider: %v", err)
 	}
+	// The methods register their output formatter when called, batch() applies
+	// it to the results it returns.
+	if _, err := c.jsre.Run(formatterHook); err != nil {
+		return fmt.Errorf("drep formatters: %v", err)
+	}
+	// Subscriptions are offered by the console and delivered on the event loop.
+	drepObj, _ := c.jsre.Get("drep")
+	drepObj.Object().Set("subscribe", bridge.Subscribe)
+	drepObj.Object().Set("unsubscribe", bridge.Unsubscribe)
+	// Calls made within batch(function(){...}) are sent as one batch request.
+	c.jsre.Set("batch", bridge.Batch)
+	// Amounts are converted between attodrep and the other units exactly.
+	c.jsre.Set("toUnit", bridge.ToUnit)
+	c.jsre.Set("fromUnit", bridge.FromUnit)
+	// The methods are documented by the registry shared with the API commands.
+	c.jsre.Set("help", c.help)
 	// Load the supported APIs into the JavaScript runtime environment
 	apis, err := c.client.SupportedModules()
 	if err != nil {
@@ -153,36 +229,18 @@ func (c *Console) init(preload []string) error {
 	// Initialize the global name register (disabled for now)
 	//c.jsre.Run(`var GlobalRegistrar = eth.contract(` + registrar.GlobalRegistrarAbi + `);   registrar = GlobalRegistrar.at("` + registrar.GlobalRegistrarAddr + `");`)
 
-	// If the console is in interactive mode, instrument password related methods to query the user
-	if c.prompter != nil {
-		/*
-			//TODO   account manage
-			// Retrieve the account management object to instrument
-			personal, err := c.jsre.Get("personal")
-			if err != nil {
-				return err
-			}
-			// Override the openWallet, unlockAccount, newAccount and sign methods since
-			// these require user interaction. Assign these method in the Console the
-			// original drep callbacks. These will be called by the jeth.* methods after
-			// they got the password from the user and send the original drep request to
-			// the backend.
-			if obj := personal.Object(); obj != nil { // make sure the personal api is enabled over the interface
-				if _, err = c.jsre.Run(`jeth.openWallet = personal.openWallet;`); err != nil {
-					return fmt.Errorf("personal.openWallet: %v", err)
-				}
-				if _, err = c.jsre.Run(`jeth.unlockAccount = personal.unlockAccount;`); err != nil {
-					return fmt.Errorf("personal.unlockAccount: %v", err)
-				}
-				if _, err = c.jsre.Run(`jeth.newAccount = personal.newAccount;`); err != nil {
-					return fmt.Errorf("personal.newAccount: %v", err)
-				}
-				if _, err = c.jsre.Run(`jeth.sign = personal.sign;`); err != nil {
-					return fmt.Errorf("personal.sign: %v", err)
-				}
-				obj.Set("newAccount", bridge.NewAccount)
-			}
-		*/
+	// The wallet methods are offered by the console, they prompt for the password
+	// if it isn't passed. Lines passing a password are kept out of the history.
+	account, err := c.jsre.Get("account")
+	if err != nil {
+		return err
+	}
+	if obj := account.Object(); obj != nil { // make sure the account api is enabled over the interface
+		obj.Set("open", bridge.OpenWallet)
+		obj.Set("unLock", bridge.UnlockWallet)
+		obj.Set("lock", bridge.LockWallet)
+		obj.Set("close", bridge.CloseWallet)
+		obj.Set("createAccount", bridge.CreateAccount)
 	}
 	// The admin.sleep and admin.sleepBlocks are offered by the console and not by the RPC layer.
 	admin, err := c.jsre.Get("admin")
@@ -205,10 +263,13 @@ func (c *Console) init(preload []string) error {
 	}
 	// Configure the console's input prompter for scrollback and tab completion
 	if c.prompter != nil {
-		if content, err := ioutil.ReadFile(c.histPath); err != nil {
+		if c.histPath == "" {
+			c.prompter.SetHistory(nil)
+		} else if content, err := ioutil.ReadFile(c.histPath); err != nil {
 			c.prompter.SetHistory(nil)
 		} else {
 			c.history = strings.Split(string(content), "\n")
+			c.trimHistory()
 			c.prompter.SetHistory(c.history)
 		}
 		c.prompter.SetWordCompleter(c.AutoCompleteInput)
@@ -216,9 +277,49 @@ func (c *Console) init(preload []string) error {
 	return nil
 }
 
+// HistoryPath returns the history file of a console attached to the given
+// endpoints, so that the commands sent to different nodes don't mix. Without
+// endpoints, e.g. when replaying a session, it is the HistoryFile.
+func HistoryPath(homeDir string, endpoints []string) string {
+	if len(endpoints) == 0 {
+		return filepath.Join(homeDir, HistoryFile)
+	}
+	sorted := append([]string{}, endpoints...)
+	sort.Strings(sorted)
+	hash := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return filepath.Join(homeDir, "histories", hex.EncodeToString(hash[:8]))
+}
+
+// migrateHistory moves the history kept in the HistoryFile before there was a
+// history per endpoint set to the first endpoint specific history used, so the
+// earlier commands aren't lost.
+func migrateHistory(homeDir, histPath string) error {
+	legacy := filepath.Join(homeDir, HistoryFile)
+	if histPath == legacy {
+		return nil
+	}
+	if _, err := os.Stat(histPath); !os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Rename(legacy, histPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// trimHistory drops the oldest commands beyond the history size.
+func (c *Console) trimHistory() {
+	if len(c.history) > c.histSize {
+		c.history = c.history[len(c.history)-c.histSize:]
+	}
+}
+
 func (c *Console) clearHistory() {
 	c.history = nil
 	c.prompter.ClearHistory()
+	if c.histPath == "" {
+		return
+	}
 	if err := os.Remove(c.histPath); err != nil {
 		fmt.Fprintln(c.printer, "can't delete history file:", err)
 	} else {
@@ -244,6 +345,10 @@ func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, str
 	if len(line) == 0 || pos == 0 {
 		return "", nil, ""
 	}
+	// Arguments of console methods are completed from their parameters
+	if head, completions, tail, ok := c.completeArgument(line, pos); ok {
+		return head, completions, tail
+	}
 	// Chunck data to relevant part for autocompletion
 	// E.g. in case of nested lines eth.getBalance(eth.coinb<tab><tab>
 	start := pos - 1
@@ -261,7 +366,11 @@ func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, str
 		start++
 		break
 	}
-	return line[:start], c.jsre.CompleteKeywords(line[start:pos]), line[pos:]
+	completions := c.jsre.CompleteKeywords(line[start:pos])
+	if len(completions) > 1 {
+		completions = signatures(completions)
+	}
+	return line[:start], completions, line[pos:]
 }
 
 // Welcome show summary of current Drep instance and some metadata about the
@@ -308,8 +417,10 @@ func (c *Console) Interactive() {
 	// Start a goroutine to listen for prompt requests and send back inputs
 	go func() {
 		for {
-			// Read the next user input
-			line, err := c.prompter.PromptInput(<-scheduler)
+			// Read the next user input, completing it with the wallet as of this prompt
+			prompt := <-scheduler
+			c.wallet, c.walletLoaded = nil, false
+			line, err := c.prompter.PromptInput(prompt)
 			if err != nil {
 				// In case of an error, either clear the prompt or fail
 				if err == liner.ErrPromptAborted { // ctrl-C
@@ -328,6 +439,9 @@ func (c *Console) Interactive() {
 	abort := make(chan os.Signal, 1)
 	signal.Notify(abort, syscall.SIGINT, syscall.SIGTERM)
 
+	// Large results are cut so they don't flood the terminal
+	c.jsre.SetMaxOutput(c.maxOutput)
+
 	// Start sending prompts to the user and reading back inputs
 	for {
 		// Send the next prompt, triggering an input read and process the result
@@ -360,18 +474,98 @@ func (c *Console) Interactive() {
 				if len(input) > 0 && input[0] != ' ' && !passwordRegexp.MatchString(input) {
 					if command := strings.TrimSpace(input); len(c.history) == 0 || command != c.history[len(c.history)-1] {
 						c.history = append(c.history, command)
+						c.trimHistory()
 						if c.prompter != nil {
 							c.prompter.AppendHistory(command)
 						}
 					}
 				}
-				c.Evaluate(input)
+				statement := input
+				run := func() { c.Evaluate(statement) }
+				if match := edit.FindStringSubmatch(input); match != nil {
+					run = func() { c.edit(match[1]) }
+				}
 				input = ""
+				if !c.interruptible(abort, run) {
+					fmt.Fprintln(c.printer, "caught interrupt, exiting")
+					return
+				}
 			}
 		}
 	}
 }
 
+// interruptible runs fn, which evaluates JavaScript, and interrupts it when
+// the user hits Ctrl-C instead of leaving the console. It reports false if
+// the console was terminated meanwhile.
+func (c *Console) interruptible(abort chan os.Signal, fn func()) bool {
+	var (
+		done       = make(chan struct{})
+		exited     = make(chan struct{})
+		terminated = make(chan bool, 1)
+	)
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case sig := <-abort:
+				c.jsre.Interrupt(jsre.ErrInterrupted)
+				if sig != os.Interrupt {
+					terminated <- true
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	fn()
+	close(done)
+	<-exited
+
+	select {
+	case <-terminated:
+		return false
+	default:
+		return true
+	}
+}
+
+// edit opens a script in the editor set by $VISUAL or $EDITOR, vi by default,
+// and evaluates it once the editor exits. Without a path the script is kept in
+// the EditFile, so the last script can be edited and run again.
+func (c *Console) edit(path string) {
+	if path == "" {
+		path = c.editPath
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(c.printer, "%s failed: %v\n", editor, err)
+		return
+	}
+	script, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) { // the script wasn't saved
+			fmt.Fprintln(c.printer, err)
+		}
+		return
+	}
+	if onlyWhitespace.Match(script) {
+		return
+	}
+	fmt.Fprintln(c.printer, jsre.Highlight(strings.TrimRight(string(script), "\n")))
+	c.Evaluate(string(script))
+}
+
 // countIndents returns the number of identations for the given input.
 // In case of invalid input such as var a = } the result can be negative.
 func countIndents(input string) int {
@@ -420,13 +614,24 @@ func (c *Console) Execute(path string) error {
 	return c.jsre.Exec(path)
 }
 
+// Run runs the JavaScript file as a program with the given arguments and stops
+// the runtime once the script is done, see jsre.RunScript.
+func (c *Console) Run(path string, args []string) error {
+	return c.jsre.RunScript(path, args)
+}
+
 // Stop cleans up the console and terminates the runtime environment.
 func (c *Console) Stop(graceful bool) error {
-	if err := ioutil.WriteFile(c.histPath, []byte(strings.Join(c.history, "\n")), 0600); err != nil {
-		return err
+	if c.histPath != "" {
+		if err := ioutil.WriteFile(c.histPath, []byte(strings.Join(c.history, "\n")), 0600); err != nil {
+			return err
+		}
+		if err := os.Chmod(c.histPath, 0600); err != nil { // Force 0600, even if it was different previously
+			return err
+		}
 	}
-	if err := os.Chmod(c.histPath, 0600); err != nil { // Force 0600, even if it was different previously
-		return err
+	if c.bridge != nil {
+		c.bridge.closeSubscriptions()
 	}
 	c.jsre.Stop(graceful)
 	return nil
@@ -0,0 +1,337 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	rpcComponent "github.com/drep-project/drepcli/rpc/component"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+const testAddress = "0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b"
+
+// DBTestService answers the db methods used by the tests.
+type DBTestService struct {
+	heights int // number of db_getMaxHeight calls
+}
+
+func (s *DBTestService) GetNonce(addr string, chainId string) string {
+	return "0x5"
+}
+
+func (s *DBTestService) GetMaxHeight() uint64 {
+	s.heights++
+	return 42
+}
+
+func (s *DBTestService) GetBlock(height uint64) map[string]uint64 {
+	return map[string]uint64{"Height": height}
+}
+
+// hookedPrompter is a UserPrompter answering password prompts from a list.
+type hookedPrompter struct {
+	passwords []string
+	prompted  int
+}
+
+func (p *hookedPrompter) PromptInput(prompt string) (string, error) {
+	return "", nil
+}
+
+func (p *hookedPrompter) PromptPassword(prompt string) (string, error) {
+	p.prompted++
+	if len(p.passwords) == 0 {
+		return "", nil
+	}
+	password := p.passwords[0]
+	p.passwords = p.passwords[1:]
+	return password, nil
+}
+
+func (p *hookedPrompter) PromptConfirm(prompt string) (bool, error) { return true, nil }
+func (p *hookedPrompter) SetHistory(history []string)               {}
+func (p *hookedPrompter) AppendHistory(command string)              {}
+func (p *hookedPrompter) ClearHistory()                             {}
+func (p *hookedPrompter) SetWordCompleter(completer WordCompleter)  {}
+
+// tester is a console attached to an in-process server of test services.
+type tester struct {
+	console  *Console
+	prompter *hookedPrompter
+	output   *bytes.Buffer
+}
+
+// newTester starts a console on a server offering the given services by
+// namespace, the db test service unless it is replaced.
+func newTester(t *testing.T, services map[string]interface{}) *tester {
+	return newTesterConfig(t, services, Config{HomeDir: t.TempDir()})
+}
+
+// newTesterConfig is newTester starting the console with the given config.
+func newTesterConfig(t *testing.T, services map[string]interface{}, config Config) *tester {
+	server := rpcTypes.NewServer()
+	if services == nil {
+		services = make(map[string]interface{})
+	}
+	if _, ok := services["db"]; !ok {
+		services["db"] = new(DBTestService)
+	}
+	for namespace, service := range services {
+		if err := server.RegisterName(namespace, service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prompter := new(hookedPrompter)
+	output := new(bytes.Buffer)
+	config.Client = rpcComponent.DialInProc(server)
+	config.Prompter = prompter
+	config.Printer = output
+	console, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		console.Stop(false)
+		server.Stop()
+	})
+	return &tester{console: console, prompter: prompter, output: output}
+}
+
+// run runs the JavaScript and returns its result as JSON.
+func (env *tester) run(t *testing.T, script string) string {
+	t.Helper()
+	value, err := env.console.jsre.Run("JSON.stringify(" + script + ")")
+	if err != nil {
+		t.Fatalf("%s: %v", script, err)
+	}
+	return value.String()
+}
+
+func TestBatchFormatsResults(t *testing.T) {
+	env := newTester(t, nil)
+
+	single := env.run(t, `db.getNonce("`+testAddress+`", "0x00")`)
+	if single != "5" {
+		t.Fatalf("single call returned %s, want 5", single)
+	}
+	got := env.run(t, `batch(function(){ db.getNonce("`+testAddress+`", "0x00"); db.getMaxHeight() })`)
+	want := `[{"method":"db_getNonce","result":5},{"method":"db_getMaxHeight","result":42}]`
+	if got != want {
+		t.Errorf("batch returned\n%s\nwant\n%s", got, want)
+	}
+
+	env.run(t, `batch(function(){ db.getNonce("`+testAddress+`", "0x00", function(err, nonce){ batchNonce = nonce }) })`)
+	if got := env.run(t, "batchNonce"); got != single {
+		t.Errorf("callback within batch got %s, want %s", got, single)
+	}
+}
+
+func TestBatchResolvesLatestOnce(t *testing.T) {
+	db := new(DBTestService)
+	env := newTester(t, map[string]interface{}{"db": db})
+
+	got := env.run(t, `batch(function(){ db.getBlock("latest"); db.getBlock("earliest"); db.getBlock("latest") }).map(function(r){ return r.result.Height })`)
+	if got != "[42,0,42]" {
+		t.Errorf("batch returned heights %s, want [42,0,42]", got)
+	}
+	if db.heights != 1 {
+		t.Errorf("the latest height was asked for %d times within the batch, want once", db.heights)
+	}
+	env.run(t, `db.getBlock("latest")`)
+	if db.heights != 2 {
+		t.Errorf("the latest height of the batch was reused by a later call")
+	}
+}
+
+func TestBatchInterrupted(t *testing.T) {
+	env := newTester(t, nil)
+	env.console.jsre.SetTimeout(50 * time.Millisecond)
+
+	if err := env.console.Evaluate(`batch(function(){ db.getMaxHeight(); while(true){} })`); err == nil {
+		t.Fatal("expected the batch to time out")
+	}
+	env.console.jsre.SetTimeout(0)
+	if got := env.run(t, "db.getMaxHeight()"); got != "42" {
+		t.Errorf("call after the interrupted batch returned %s, want 42", got)
+	}
+	if got := env.run(t, "batch(function(){ db.getMaxHeight() })"); got != `[{"method":"db_getMaxHeight","result":42}]` {
+		t.Errorf("batch after the interrupted one returned %s", got)
+	}
+}
+
+func TestPasswordRegexp(t *testing.T) {
+	tests := []struct {
+		input  string
+		secret bool
+	}{
+		{`account.open("secret")`, true},
+		{`account.unLock( 'secret' )`, true},
+		{`account.createAccount("secret")`, true},
+		{`account.createAccount(password)`, true},
+		{`var a = 1; account.open("secret")`, true},
+		{`jeth.send({method: "account_open", params: ["secret"]})`, true},
+		{`jeth.send({method: "account_unLock", params: ["secret"]})`, true},
+		{`account.open()`, false},
+		{`account.unLock( )`, false},
+		{`account.createAccount()`, false},
+		{`account.lock()`, false},
+		{`account.addressList()`, false},
+		{`db.getBlock(3)`, false},
+	}
+	for _, test := range tests {
+		if secret := passwordRegexp.MatchString(test.input); secret != test.secret {
+			t.Errorf("%s: kept out of the history %v, want %v", test.input, secret, test.secret)
+		}
+	}
+}
+
+func TestHistoryPath(t *testing.T) {
+	home := t.TempDir()
+	if path := HistoryPath(home, nil); path != filepath.Join(home, HistoryFile) {
+		t.Errorf("history without endpoints is %s, want the %s file", path, HistoryFile)
+	}
+	path := HistoryPath(home, []string{"http://a:15645", "ws://b:15646"})
+	if filepath.Dir(path) != filepath.Join(home, "histories") {
+		t.Errorf("history %s isn't kept below histories", path)
+	}
+	if other := HistoryPath(home, []string{"ws://b:15646", "http://a:15645"}); other != path {
+		t.Errorf("the order of the endpoints changed the history from %s to %s", path, other)
+	}
+	if other := HistoryPath(home, []string{"http://a:15645"}); other == path {
+		t.Errorf("different endpoints share the history %s", path)
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	tests := []struct {
+		history, want []string
+	}{
+		{nil, nil},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"c", "d", "e"}},
+	}
+	for _, test := range tests {
+		c := &Console{history: test.history, histSize: 3}
+		c.trimHistory()
+		if !reflect.DeepEqual(c.history, test.want) {
+			t.Errorf("trimming %v kept %v, want %v", test.history, c.history, test.want)
+		}
+	}
+}
+
+func TestHistoryMigration(t *testing.T) {
+	home := t.TempDir()
+	legacy := filepath.Join(home, HistoryFile)
+	if err := ioutil.WriteFile(legacy, []byte("a\nb\nc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := newTesterConfig(t, nil, Config{HomeDir: home, Endpoints: []string{"http://a:15645"}, HistorySize: 2})
+	if want := []string{"b", "c"}; !reflect.DeepEqual(env.console.history, want) {
+		t.Errorf("migrated history is %v, want %v", env.console.history, want)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("the %s file is kept after the migration: %v", HistoryFile, err)
+	}
+
+	// Only the first history used takes the former commands over.
+	if err := ioutil.WriteFile(legacy, []byte("d"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env = newTesterConfig(t, nil, Config{HomeDir: home, Endpoints: []string{"http://a:15645"}})
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(env.console.history, want) {
+		t.Errorf("history is %v after another migration, want %v", env.console.history, want)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("the %s file was migrated into an existing history: %v", HistoryFile, err)
+	}
+}
+
+func TestNoHistory(t *testing.T) {
+	home := t.TempDir()
+	legacy := filepath.Join(home, HistoryFile)
+	if err := ioutil.WriteFile(legacy, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := newTesterConfig(t, nil, Config{HomeDir: home, Endpoints: []string{"http://a:15645"}, NoHistory: true})
+	if len(env.console.history) != 0 {
+		t.Errorf("loaded the history %v", env.console.history)
+	}
+	env.console.history = []string{"b"}
+	if err := env.console.Stop(false); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(legacy); err != nil || string(content) != "a" {
+		t.Errorf("the %s file changed to %q, %v", HistoryFile, content, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "histories")); !os.IsNotExist(err) {
+		t.Errorf("histories were created: %v", err)
+	}
+}
+
+// writeEditor writes an editor replacing the edited file with the script.
+func writeEditor(t *testing.T, script string) string {
+	editor := filepath.Join(t.TempDir(), "editor")
+	content := "#!/bin/sh\nprintf '%s' '" + script + "' > \"$1\"\n"
+	if err := ioutil.WriteFile(editor, []byte(content), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return editor
+}
+
+func TestEdit(t *testing.T) {
+	env := newTester(t, nil)
+
+	t.Setenv("VISUAL", writeEditor(t, "edited = 42"))
+	env.console.edit("")
+	if got := env.run(t, "edited"); got != "42" {
+		t.Errorf("edited script set %s, want 42", got)
+	}
+	if script, err := ioutil.ReadFile(env.console.editPath); err != nil || string(script) != "edited = 42" {
+		t.Errorf("the script wasn't kept in %s: %q, %v", EditFile, script, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "own.js")
+	t.Setenv("VISUAL", writeEditor(t, "own = 7"))
+	env.console.edit(path)
+	if got := env.run(t, "own"); got != "7" {
+		t.Errorf("edited file set %s, want 7", got)
+	}
+
+	// A script that isn't saved, or holds nothing, isn't run.
+	env.output.Reset()
+	t.Setenv("VISUAL", "true")
+	env.console.edit(filepath.Join(t.TempDir(), "unsaved.js"))
+	t.Setenv("VISUAL", writeEditor(t, " \n"))
+	env.console.edit("")
+	if env.output.Len() != 0 {
+		t.Errorf("unsaved and empty scripts printed %q", env.output.String())
+	}
+
+	t.Setenv("VISUAL", "false")
+	env.console.edit("")
+	if !strings.Contains(env.output.String(), "false failed") {
+		t.Errorf("failing editor printed %q", env.output.String())
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/robertkrimen/otto"
+
+	"github.com/drep-project/drepcli/drepclient/component/jsre/deps"
+)
+
+// help implements help([topic]). Without topic it lists the namespaces, a
+// namespace like db lists its methods and a method like db.getBalance prints
+// its documentation. Topics may also be given by name, e.g. help("db").
+func (c *Console) help(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) == 0 {
+		c.printNamespaces()
+		return otto.Value{}
+	}
+	topic := call.Argument(0)
+	for _, namespace := range deps.Namespaces {
+		if topic.IsString() && topic.String() == namespace.Name || isGlobal(call.Otto, topic, namespace.Name) {
+			c.printNamespace(namespace)
+			return otto.Value{}
+		}
+	}
+	for _, method := range deps.ConsoleMethods() {
+		if topic.IsString() && strings.TrimPrefix(topic.String(), "drep.") == method.Namespace+"."+method.Name ||
+			isGlobal(call.Otto, topic, method.Namespace, method.Name) {
+			c.printMethod(method)
+			return otto.Value{}
+		}
+	}
+	return throwJSException("help: no documentation for " + topic.String() + ", see help()")
+}
+
+// isGlobal reports whether value is the object at the path of properties of
+// the global object, e.g. db.getBalance. Objects are compared by identity.
+func isGlobal(vm *otto.Otto, value otto.Value, path ...string) bool {
+	if !value.IsObject() {
+		return false
+	}
+	obj, err := vm.Get(path[0])
+	for _, name := range path[1:] {
+		if err != nil || !obj.IsObject() {
+			return false
+		}
+		obj, err = obj.Object().Get(name)
+	}
+	return err == nil && obj == value
+}
+
+// printNamespaces lists the namespaces of the console.
+func (c *Console) printNamespaces() {
+	w := tabwriter.NewWriter(c.printer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Namespaces:")
+	for _, namespace := range deps.Namespaces {
+		fmt.Fprintf(w, "  %s\t%s\n", namespace.Name, namespace.Description)
+	}
+	w.Flush()
+	fmt.Fprintln(c.printer, "\nUse help(namespace) to list its methods, e.g. help(db), and help(method) to")
+	fmt.Fprintln(c.printer, "describe a method, e.g. help(db.getBalance).")
+}
+
+// printNamespace lists the methods of the namespace.
+func (c *Console) printNamespace(namespace deps.Namespace) {
+	fmt.Fprintf(c.printer, "%s: %s\n\n", namespace.Name, namespace.Description)
+	w := tabwriter.NewWriter(c.printer, 0, 8, 2, ' ', 0)
+	for _, method := range deps.ConsoleMethods() {
+		if method.Namespace == namespace.Name {
+			fmt.Fprintf(w, "  %s\t%s\n", method.Signature(), method.Description)
+		}
+	}
+	w.Flush()
+}
+
+// printMethod prints the documentation of the method. Every method has a
+// description, result and example, the tests of deps make sure of it.
+func (c *Console) printMethod(method deps.Method) {
+	fmt.Fprintf(c.printer, "%s\n\n%s\n", method.Signature(), method.Description)
+	if len(method.Params) > 0 {
+		fmt.Fprintln(c.printer, "\nParameters:")
+		printParams(c.printer, method.Params)
+	}
+	fmt.Fprintf(c.printer, "\nReturns: %s\n", method.Returns)
+	fmt.Fprintf(c.printer, "Example: %s\n", method.Example)
+}
+
+func printParams(out io.Writer, params []deps.Param) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, param := range params {
+		description := deps.TypeDescription(param.Type)
+		if param.Default != "" {
+			description += ", default " + param.Default
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", param.Name, param.Type, description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto"
+)
+
+func TestHelp(t *testing.T) {
+	env := newTester(t, nil)
+	tests := []struct {
+		call     string
+		contains []string
+	}{
+		{"help()", []string{"Namespaces:", "account", "chain", "db", "help(db.getBalance)"}},
+		{"help(db)", []string{"db: Query blocks", "db.getBlock(height)", "db.getLogs(txHash, chainId)"}},
+		{`help("db")`, []string{"db: Query blocks", "db.getBlock(height)"}},
+		{"help(db.getBalance)", []string{"db.getBalance(address, chainId)", "Parameters:", "default 0x00", "Returns: Number", "Example: db.getBalance("}},
+		{"help(drep.db.getBalance)", []string{"db.getBalance(address, chainId)"}},
+		{`help("drep.db.getBalance")`, []string{"db.getBalance(address, chainId)"}},
+		{`help("chain.travel")`, []string{"chain.travel()", "Get all the blocks of the chain", "Returns: Array of blocks"}},
+		{`help("account.createAccount")`, []string{"account.createAccount(password)", "opening or unlocking the wallet", "default prompted for"}},
+	}
+	for _, test := range tests {
+		env.output.Reset()
+		if _, err := env.console.jsre.Run(test.call); err != nil {
+			t.Errorf("%s: %v", test.call, err)
+			continue
+		}
+		for _, text := range test.contains {
+			if !strings.Contains(env.output.String(), text) {
+				t.Errorf("%s printed\n%s\nwithout %q", test.call, env.output.String(), text)
+			}
+		}
+	}
+
+	for _, call := range []string{"help(1)", `help("nothing")`, "help({})", "help(db.nothing)"} {
+		if _, err := env.console.jsre.Run(call); err == nil || !strings.Contains(err.Error(), "no documentation") {
+			t.Errorf("%s: expected no documentation, got %v", call, err)
+		}
+	}
+}
+
+func TestIsGlobal(t *testing.T) {
+	env := newTester(t, nil)
+	tests := []struct {
+		expr   string
+		path   []string
+		global bool
+	}{
+		{"db", []string{"db"}, true},
+		{"drep.db", []string{"db"}, true},
+		{"db.getBalance", []string{"db", "getBalance"}, true},
+		{"db.getBalance", []string{"db", "getNonce"}, false},
+		{"db.getBalance", []string{"db"}, false},
+		{"({})", []string{"db"}, false},
+		{`"db"`, []string{"db"}, false},
+		{"db", []string{"nothing", "db"}, false},
+		{"db.getBalance", []string{"db", "getBalance", "call"}, false},
+	}
+	env.console.jsre.Do(func(vm *otto.Otto) {
+		for _, test := range tests {
+			value, err := vm.Run(test.expr)
+			if err != nil {
+				t.Errorf("%s: %v", test.expr, err)
+				continue
+			}
+			if global := isGlobal(vm, value, test.path...); global != test.global {
+				t.Errorf("%s is %s: %v, want %v", test.expr, strings.Join(test.path, "."), global, test.global)
+			}
+		}
+	})
+}
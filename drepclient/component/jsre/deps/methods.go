@@ -0,0 +1,298 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package deps
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Parameter types of the console methods.
+const (
+	ParamAddress  = "address" // 0x prefixed account address
+	ParamChainId  = "chainId" // chain id, "0x00" for the root chain
+	ParamUint     = "uint"    // unsigned integer, block heights and counts
+	ParamBlock    = "block"   // block height or a tag of common.BlockTags, resolved by the client
+	ParamAmount   = "amount"  // amount with optional unit suffix, sent as attodrep string
+	ParamHex      = "hex"     // hex encoded data, sent as string
+	ParamBool     = "bool"
+	ParamAny      = "any"      // JSON value, or a string if it isn't valid JSON
+	ParamPassword = "password" // wallet password, only taken by console methods
+)
+
+// paramTypes describes the parameter types for the help of the console.
+var paramTypes = map[string]string{
+	ParamAddress:  "0x prefixed account address",
+	ParamChainId:  `chain id, "0x00" for the root chain`,
+	ParamUint:     "unsigned integer",
+	ParamBlock:    `block height, or the tag "latest" or "earliest"`,
+	ParamAmount:   `amount in attodrep, or with a unit like "1.5 drep"`,
+	ParamHex:      "hex encoded data",
+	ParamBool:     "true or false",
+	ParamAny:      "any JSON value",
+	ParamPassword: "wallet password",
+}
+
+// TypeDescription returns a short description of the parameter type.
+func TypeDescription(typ string) string {
+	return paramTypes[typ]
+}
+
+// Param describes a parameter of a console method.
+type Param struct {
+	Name    string
+	Type    string
+	Default string // used if the parameter is not given, empty if it is required
+}
+
+// Method is a console method defined by drep.js, such as db.getBlock.
+type Method struct {
+	Namespace string // object of the method in the console, e.g. db
+	Name      string // e.g. getBlock
+	Call      string // RPC method, e.g. db_getBlock
+	Params    []Param
+
+	Description string // what the method does
+	Returns     string // type of the result and its meaning
+	Example     string // a call in the console
+}
+
+// Namespace is a console object holding methods, such as db.
+type Namespace struct {
+	Name        string
+	Description string
+}
+
+// Namespaces are the console objects holding the methods of drep.js. The API
+// commands offer a command for each of them.
+var Namespaces = []Namespace{
+	{"account", "Manage the wallet and the local accounts of a drep node"},
+	{"chain", "Query accounts and send transactions and contracts to the chain"},
+	{"db", "Query blocks and accounts stored by a drep node"},
+}
+
+// methodDoc documents a method of drep.js.
+type methodDoc struct {
+	description, returns, example string
+}
+
+// methodDocs documents the methods of drep.js by RPC name. It is the source of
+// the help of the console and the usage of the API commands.
+var methodDocs = map[string]methodDoc{
+	"account_addressList":   {"List the addresses of the local accounts", "Array of addresses", "account.addressList()"},
+	"account_createAccount": {"Create a local account", "String, the address of the account", "account.createAccount()"},
+	"account_dumpPrikey":    {"Export the private key of a local account", "String, the hex encoded private key", `account.dumpPrikey("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b")`},
+
+	"chain_call":         {"Invoke a contract, or only read its state if readOnly is true", "String, the hex encoded result", `chain.call("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b", "0x00", "0x60fe47b1", "0", true)`},
+	"chain_check":        {"Get the state of an account on a chain", "Object with the balance, nonce and code of the account", `chain.check("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b", "0x00")`},
+	"chain_checkBalance": {"Get the balance of an account on the root chain", "Number, the balance in attodrep", `chain.checkBalance("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b")`},
+	"chain_checkNonce":   {"Get the nonce of an account on the root chain", "Number", `chain.checkNonce("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b")`},
+	"chain_create":       {"Deploy a contract", "String, the address of the contract", `chain.create("0x6080604052348015600f57600080fd5b50")`},
+	"chain_h":            {"Get the height of the chain", "Number", "chain.h()"},
+	"chain_me":           {"Get the account of the node", "Object with the address, balance, chain id and nonce", "chain.me()"},
+	"chain_miner":        {"Tell whether an address is the account the node seals blocks with", "Boolean", `chain.miner("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b", "0x00")`},
+	"chain_n":            {"Get the nonce of the account of the node", "Number", "chain.n()"},
+	"chain_send":         {"Transfer an amount to an address", "String, the hash of the transaction", `chain.send("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b", "0x00", "1.5 drep")`},
+	"chain_travel":       {"Get all the blocks of the chain, oldest first", "Array of blocks", "chain.travel()"},
+
+	"db_getAllBlocks":        {"Get all the stored blocks", "Array of blocks", "db.getAllBlocks()"},
+	"db_getBalance":          {"Get the balance of an account on a chain", "Number, the balance in attodrep", `db.getBalance("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b", "0x00")`},
+	"db_getBlock":            {"Get the block at a height", "Object, the block", `db.getBlock("latest")`},
+	"db_getBlocksFrom":       {"Get size blocks from the start height on", "Array of blocks", "db.getBlocksFrom(10, 2)"},
+	"db_getByteCode":         {"Get the bytecode of a contract", "String, the hex encoded bytecode", `db.getByteCode("0x511bede065df9c06e98ca75df9f0584f48a0a4f3", "0x00")`},
+	"db_getCodeHash":         {"Get the hash of the bytecode of a contract", "String, the hex encoded hash", `db.getCodeHash("0x511bede065df9c06e98ca75df9f0584f48a0a4f3", "0x00")`},
+	"db_getHighestBlock":     {"Get the latest block", "Object, the block", "db.getHighestBlock()"},
+	"db_getLogs":             {"Get the logs of a sealed transaction, it fails for unknown transactions", "Array of logs", `db.getLogs("0x9c22ff5f21f0b81b113e63f7db6da94fedef11b2119b4088b89664fb9a3cb658", "0x00")`},
+	"db_getMaxHeight":        {"Get the height of the latest block", "Number", "db.getMaxHeight()"},
+	"db_getMostRecentBlocks": {"Get the given number of most recent blocks, oldest first", "Array of blocks", "db.getMostRecentBlocks(2)"},
+	"db_getNonce":            {"Get the nonce of an account on a chain", "Number", `db.getNonce("0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b", "0x00")`},
+}
+
+// consoleMethods are implemented by the console instead of drep.js, as they
+// prompt for the wallet password if it isn't passed.
+var consoleMethods = []Method{
+	{
+		Namespace: "account", Name: "createAccount", Call: "account_createAccount",
+		Params:      []Param{{Name: "password", Type: ParamPassword, Default: "prompted for"}},
+		Description: "Create a local account, opening or unlocking the wallet first if needed",
+		Returns:     "String, the address of the account",
+		Example:     "account.createAccount()",
+	},
+	{
+		Namespace: "account", Name: "open", Call: "account_open",
+		Params:      []Param{{Name: "password", Type: ParamPassword, Default: "prompted for"}},
+		Description: "Open the wallet",
+		Returns:     "Nothing",
+		Example:     "account.open()",
+	},
+	{
+		Namespace: "account", Name: "close", Call: "account_close",
+		Description: "Close the wallet",
+		Returns:     "Nothing",
+		Example:     "account.close()",
+	},
+	{
+		Namespace: "account", Name: "lock", Call: "account_lock",
+		Description: "Lock the wallet",
+		Returns:     "Nothing",
+		Example:     "account.lock()",
+	},
+	{
+		Namespace: "account", Name: "unLock", Call: "account_unLock",
+		Params:      []Param{{Name: "password", Type: ParamPassword, Default: "prompted for"}},
+		Description: "Unlock the wallet",
+		Returns:     "Nothing",
+		Example:     "account.unLock()",
+	},
+}
+
+// methodParams names and types the parameters of the methods in drep.js, which
+// only counts them. Methods missing here get parameters of type any named
+// arg1, arg2, ...
+var methodParams = map[string][]Param{
+	"account_dumpPrikey": {{Name: "address", Type: ParamAddress}},
+
+	"chain_call": {
+		{Name: "address", Type: ParamAddress},
+		{Name: "chainId", Type: ParamChainId, Default: "0x00"},
+		{Name: "input", Type: ParamHex},
+		{Name: "amount", Type: ParamAmount, Default: "0"},
+		{Name: "readOnly", Type: ParamBool, Default: "false"},
+	},
+	"chain_check":        {{Name: "address", Type: ParamAddress}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+	"chain_checkBalance": {{Name: "address", Type: ParamAddress}},
+	"chain_checkNonce":   {{Name: "address", Type: ParamAddress}},
+	"chain_create":       {{Name: "code", Type: ParamHex}},
+	"chain_miner":        {{Name: "address", Type: ParamAddress}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+	"chain_send": {
+		{Name: "to", Type: ParamAddress},
+		{Name: "chainId", Type: ParamChainId, Default: "0x00"},
+		{Name: "amount", Type: ParamAmount},
+	},
+
+	"db_getBalance":          {{Name: "address", Type: ParamAddress}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+	"db_getBlock":            {{Name: "height", Type: ParamBlock}},
+	"db_getBlocksFrom":       {{Name: "start", Type: ParamBlock}, {Name: "size", Type: ParamUint}},
+	"db_getByteCode":         {{Name: "address", Type: ParamAddress}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+	"db_getCodeHash":         {{Name: "address", Type: ParamAddress}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+	"db_getLogs":             {{Name: "txHash", Type: ParamHex}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+	"db_getMostRecentBlocks": {{Name: "count", Type: ParamUint}},
+	"db_getNonce":            {{Name: "address", Type: ParamAddress}, {Name: "chainId", Type: ParamChainId, Default: "0x00"}},
+}
+
+var (
+	methodRegexp = regexp.MustCompile(`new Method\(\{([^}]*)\}\)`)
+	nameRegexp   = regexp.MustCompile(`name:\s*'([^']*)'`)
+	callRegexp   = regexp.MustCompile(`call:\s*'([^']*)'`)
+	paramsRegexp = regexp.MustCompile(`params:\s*(\d+)`)
+
+	methodsOnce sync.Once
+	methods     []Method
+)
+
+// Methods returns the methods defined by the Method tables of drep.js, in the
+// order of the file.
+func Methods() []Method {
+	methodsOnce.Do(func() {
+		var err error
+		if methods, err = parseMethods(string(MustAsset("drep.js"))); err != nil {
+			panic(err)
+		}
+	})
+	return methods
+}
+
+// ConsoleMethods returns the methods of the console, which are the methods of
+// drep.js with those the console implements itself replaced, followed by the
+// other methods of the console. The API commands only offer those of drep.js.
+func ConsoleMethods() []Method {
+	var result []Method
+	own := make(map[string]bool)
+	for _, method := range Methods() {
+		for _, replacement := range consoleMethods {
+			if replacement.Call == method.Call {
+				method, own[method.Call] = replacement, true
+			}
+		}
+		result = append(result, method)
+	}
+	for _, method := range consoleMethods {
+		if !own[method.Call] {
+			result = append(result, method)
+		}
+	}
+	return result
+}
+
+// Signature returns the call syntax of the method in the console, e.g.
+// db.getBlock(height).
+func (method Method) Signature() string {
+	names := make([]string, len(method.Params))
+	for i, param := range method.Params {
+		names[i] = param.Name
+	}
+	return fmt.Sprintf("%s.%s(%s)", method.Namespace, method.Name, strings.Join(names, ", "))
+}
+
+// Lookup returns the method with the given RPC name, e.g. chain_send.
+func Lookup(call string) (Method, bool) {
+	for _, method := range Methods() {
+		if method.Call == call {
+			return method, true
+		}
+	}
+	return Method{}, false
+}
+
+// parseMethods extracts the new Method({...}) definitions of a script
+func parseMethods(script string) ([]Method, error) {
+	var result []Method
+	for _, match := range methodRegexp.FindAllStringSubmatch(script, -1) {
+		name, call, params := nameRegexp.FindStringSubmatch(match[1]), callRegexp.FindStringSubmatch(match[1]), paramsRegexp.FindStringSubmatch(match[1])
+		if name == nil || call == nil || params == nil {
+			return nil, fmt.Errorf("incomplete method definition %q", match[0])
+		}
+		count, _ := strconv.Atoi(params[1])
+		namespace := strings.SplitN(call[1], "_", 2)[0]
+		doc := methodDocs[call[1]]
+		result = append(result, Method{
+			Namespace:   namespace,
+			Name:        name[1],
+			Call:        call[1],
+			Params:      describeParams(call[1], count),
+			Description: doc.description,
+			Returns:     doc.returns,
+			Example:     doc.example,
+		})
+	}
+	return result, nil
+}
+
+// describeParams returns the count parameters of the RPC method
+func describeParams(call string, count int) []Param {
+	if params, ok := methodParams[call]; ok && len(params) == count {
+		return params
+	}
+	params := make([]Param, count)
+	for i := range params {
+		params[i] = Param{Name: fmt.Sprintf("arg%d", i+1), Type: ParamAny}
+	}
+	return params
+}
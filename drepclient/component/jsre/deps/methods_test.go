@@ -0,0 +1,86 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package deps
+
+import "testing"
+
+func TestMethods(t *testing.T) {
+	byCall := make(map[string]Method)
+	for _, method := range Methods() {
+		byCall[method.Call] = method
+	}
+	if len(byCall) != 25 {
+		t.Errorf("found %d methods in drep.js, want 25", len(byCall))
+	}
+	block := byCall["db_getBlock"]
+	if block.Namespace != "db" || block.Name != "getBlock" || len(block.Params) != 1 || block.Params[0].Type != ParamBlock {
+		t.Errorf("wrong db_getBlock method %+v", block)
+	}
+	if signature := block.Signature(); signature != "db.getBlock(height)" {
+		t.Errorf("got signature %q", signature)
+	}
+	if signature := byCall["db_getLogs"].Signature(); signature != "db.getLogs(txHash, chainId)" {
+		t.Errorf("got signature %q", signature)
+	}
+	undescribed, err := parseMethods(`new Method({ name: 'peek', call: 'db_peek', params: 2 })`)
+	if err != nil || len(undescribed) != 1 {
+		t.Fatalf("parsed %+v, %v", undescribed, err)
+	}
+	if params := undescribed[0].Params; len(params) != 2 || params[1].Name != "arg2" || params[1].Type != ParamAny {
+		t.Errorf("undescribed parameters of db_peek not generated: %+v", params)
+	}
+	// every described method must match the parameter count of drep.js
+	for call, params := range methodParams {
+		if method, ok := byCall[call]; !ok || len(method.Params) != len(params) || method.Params[0] != params[0] {
+			t.Errorf("parameters of %s don't match drep.js", call)
+		}
+	}
+}
+
+func TestMethodDocs(t *testing.T) {
+	namespaces := make(map[string]bool)
+	for _, namespace := range Namespaces {
+		namespaces[namespace.Name] = true
+	}
+	for call := range methodDocs {
+		if _, ok := Lookup(call); !ok {
+			t.Errorf("documented method %s isn't defined by drep.js", call)
+		}
+	}
+	for _, method := range ConsoleMethods() {
+		if !namespaces[method.Namespace] {
+			t.Errorf("namespace of %s isn't registered", method.Call)
+		}
+		if method.Description == "" || method.Returns == "" || method.Example == "" {
+			t.Errorf("%s isn't documented", method.Call)
+		}
+		for _, param := range method.Params {
+			if TypeDescription(param.Type) == "" {
+				t.Errorf("type %s of %s isn't described", param.Type, method.Call)
+			}
+		}
+	}
+	var createAccount []Method
+	for _, method := range ConsoleMethods() {
+		if method.Call == "account_createAccount" {
+			createAccount = append(createAccount, method)
+		}
+	}
+	if len(createAccount) != 1 || len(createAccount[0].Params) != 1 {
+		t.Errorf("account.createAccount of the console doesn't replace the one of drep.js: %+v", createAccount)
+	}
+}
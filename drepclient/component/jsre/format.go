@@ -0,0 +1,369 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robertkrimen/otto"
+
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/drepclient/component/output"
+)
+
+// Output formats of evaluated values, the formats of the output package.
+const (
+	FormatPretty = string(output.Pretty) // JavaScript object syntax, values decoded for humans
+	FormatJSON   = string(output.JSON)   // JSON, byte fields as hex and big numbers exact
+	FormatTable  = string(output.Table)  // text table, values decoded like pretty
+	FormatYAML   = string(output.YAML)   // YAML, values decoded like JSON
+)
+
+// ParseFormat checks the name of an output format.
+func ParseFormat(name string) (string, error) {
+	format, err := output.ParseFormat(name)
+	return string(format), err
+}
+
+// WriteResult writes a JSON encoded RPC result in the given format, decoding
+// its fields and keeping all digits of large integers like the console does.
+func WriteResult(w io.Writer, format string, result json.RawMessage) error {
+	if len(bytes.TrimSpace(result)) == 0 {
+		result = json.RawMessage("null")
+	}
+	vm := otto.New()
+	if _, err := vm.Run(BigNumber_JS); err != nil {
+		return fmt.Errorf("bignumber.js: %v", err)
+	}
+	value, err := ParseJSON(vm, result)
+	if err != nil {
+		return err
+	}
+	return writeFormatted(vm, value, format, w)
+}
+
+// bigNumberMarker prefixes the digits of integers too large for a float64,
+// ParseJSON replaces them by BigNumber values. It is JSON encoded, the string
+// starts with a NUL character.
+const bigNumberMarker = `\u0000BigNumber:`
+
+// bigNumberReviver is the JSON.parse reviver creating the BigNumber values.
+const bigNumberReviver = `(function(key, value) {
+	var marker = "\u0000BigNumber:";
+	if (typeof value === "string" && value.indexOf(marker) === 0) {
+		return new BigNumber(value.slice(marker.length));
+	}
+	return value;
+})`
+
+// ParseJSON parses JSON with JSON.parse, except for integers of more than 15
+// digits which become BigNumber values so they keep all their digits. The
+// BigNumber constructor of bignumber.js must be defined in vm.
+func ParseJSON(vm *otto.Otto, data []byte) (otto.Value, error) {
+	marked, ok := markBigIntegers(data)
+	if !ok {
+		return vm.Call("JSON.parse", nil, string(data))
+	}
+	reviver, err := vm.Run(bigNumberReviver)
+	if err != nil {
+		return otto.Value{}, err
+	}
+	return vm.Call("JSON.parse", nil, string(marked), reviver)
+}
+
+// markBigIntegers replaces the integers of more than 15 digits in JSON by
+// strings holding bigNumberMarker and the digits. It returns false if there
+// are none.
+func markBigIntegers(data []byte) ([]byte, bool) {
+	var (
+		out    []byte
+		marked bool
+	)
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			// skip strings, they may hold digits
+			j := i + 1
+			for ; j < len(data) && data[j] != '"'; j++ {
+				if data[j] == '\\' {
+					j++
+				}
+			}
+			if j < len(data) {
+				j++
+			}
+			out = append(out, data[i:j]...)
+			i = j
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(data) && strings.IndexByte("0123456789.eE+-", data[j]) >= 0 {
+				j++
+			}
+			number := data[i:j]
+			digits := strings.TrimPrefix(string(number), "-")
+			if len(digits) > 15 && strings.Trim(digits, "0123456789") == "" {
+				out = append(out, '"')
+				out = append(out, bigNumberMarker...)
+				out = append(out, number...)
+				out = append(out, '"')
+				marked = true
+			} else {
+				out = append(out, number...)
+			}
+			i = j
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out, marked
+}
+
+// fieldDecoder renders the value of a field of a RPC result. It returns false
+// if it doesn't apply to the value. Values which are decoded for humans only
+// are left as they are if human is false.
+type fieldDecoder func(ctx ppctx, v otto.Value, human bool) (decoded interface{}, ok bool)
+
+// fieldDecoders are keyed by the lower case field name. Nodes send byte slices
+// as base64 and big integers as numbers or hex strings.
+var fieldDecoders = map[string]fieldDecoder{
+	"previoushash": decodeBytes,
+	"merkleroot":   decodeBytes,
+	"stateroot":    decodeBytes,
+	"txroot":       decodeBytes,
+	"txhashes":     decodeBytes,
+	"txhash":       decodeBytes,
+	"codehash":     decodeBytes,
+	"topics":       decodeBytes,
+	"leaderpubkey": decodeBytes,
+	"minorpubkeys": decodeBytes,
+	"bitmap":       decodeBytes,
+	"sig":          decodeBytes,
+	"bytecode":     decodeBytes,
+
+	"gaslimit": decodeInteger,
+	"gasused":  decodeInteger,
+	"gasprice": decodeInteger,
+
+	"balance": decodeAmount,
+	"amount":  decodeAmount,
+
+	"timestamp": decodeTimestamp,
+}
+
+// decoderFor returns the decoder of a field, nil if there is none.
+func decoderFor(key string) fieldDecoder {
+	return fieldDecoders[strings.ToLower(key)]
+}
+
+// decodeBytes renders base64 encoded bytes as 0x prefixed hex.
+func decodeBytes(ctx ppctx, v otto.Value, human bool) (interface{}, bool) {
+	s, ok := exportString(v)
+	if !ok || s == "" || strings.HasPrefix(s, "0x") {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return "0x" + hex.EncodeToString(b), true
+}
+
+// decodeInteger renders a big integer sent as base64, hex or number exactly.
+func decodeInteger(ctx ppctx, v otto.Value, human bool) (interface{}, bool) {
+	n, ok := ctx.bigInt(v)
+	if !ok {
+		return nil, false
+	}
+	return numberLiteral(n.String()), true
+}
+
+// decodeAmount renders an amount in drep for humans and in attodrep otherwise.
+func decodeAmount(ctx ppctx, v otto.Value, human bool) (interface{}, bool) {
+	n, ok := ctx.bigInt(v)
+	if !ok {
+		return nil, false
+	}
+	if !human {
+		return numberLiteral(n.String()), true
+	}
+	return common.FormatAmount(n, common.Drep) + " " + common.Drep.Name, true
+}
+
+// decodeTimestamp renders unix seconds as an ISO 8601 date for humans.
+func decodeTimestamp(ctx ppctx, v otto.Value, human bool) (interface{}, bool) {
+	if !human || !v.IsNumber() {
+		return nil, false
+	}
+	seconds, err := v.ToInteger()
+	if err != nil || seconds <= 0 {
+		return nil, false
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339), true
+}
+
+// numberLiteral is a decoded number, printed without quotes.
+type numberLiteral string
+
+// bigInt converts a number, BigNumber, 0x prefixed hex or base64 big endian
+// string to an integer.
+func (ctx ppctx) bigInt(v otto.Value) (*big.Int, bool) {
+	switch {
+	case v.IsNumber():
+		f, err := v.ToFloat()
+		if err != nil || f != float64(int64(f)) {
+			return nil, false
+		}
+		return big.NewInt(int64(f)), true
+	case v.IsObject() && ctx.isBigNumber(v.Object()):
+		return new(big.Int).SetString(bigNumberString(v.Object()), 10)
+	}
+	s, ok := exportString(v)
+	if !ok || s == "" {
+		return nil, false
+	}
+	if strings.HasPrefix(s, "0x") {
+		return new(big.Int).SetString(s[2:], 16)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(b), true
+}
+
+func exportString(v otto.Value) (string, bool) {
+	if !v.IsString() {
+		return "", false
+	}
+	s, err := v.ToString()
+	return s, err == nil
+}
+
+// bigNumberString returns the digits of a bignumber.js value, without the
+// exponential notation toString uses for large numbers.
+func bigNumberString(obj *otto.Object) string {
+	s, _ := obj.Call("toFixed")
+	return s.String()
+}
+
+// writeFormatted writes value in the given format. Undefined and functions
+// are always pretty printed.
+func writeFormatted(vm *otto.Otto, value otto.Value, format string, w io.Writer) error {
+	ctx := ppctx{vm: vm, w: w}
+	switch {
+	case value.IsUndefined() || value.IsFunction():
+	case format == FormatJSON || format == FormatTable || format == FormatYAML:
+		var buf bytes.Buffer
+		ctx.writeJSON(&buf, value, "", format == FormatTable)
+		if format != FormatJSON {
+			return output.Write(w, output.Format(format), json.RawMessage(buf.Bytes()))
+		}
+		var out bytes.Buffer
+		if err := output.Write(&out, output.JSON, json.RawMessage(buf.Bytes())); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, Highlight(out.String()))
+		return err
+	}
+	ctx.printValue(value, 0, false)
+	fmt.Fprintln(w)
+	return nil
+}
+
+// writeJSON encodes a value as JSON, applying the decoder of the field it was
+// found in. Functions are dropped.
+func (ctx ppctx) writeJSON(buf *bytes.Buffer, v otto.Value, key string, human bool) {
+	if decoder := decoderFor(key); decoder != nil {
+		if decoded, ok := decoder(ctx, v, human); ok {
+			writeDecoded(buf, decoded)
+			return
+		}
+	}
+	switch {
+	case v.IsObject():
+		obj := v.Object()
+		switch {
+		case ctx.isBigNumber(obj):
+			buf.WriteString(bigNumberString(obj))
+		case obj.Class() == "Array" || obj.Class() == "GoArray":
+			lv, _ := obj.Get("length")
+			length, _ := lv.ToInteger()
+			buf.WriteByte('[')
+			for i := int64(0); i < length; i++ {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				el, _ := obj.Get(strconv.FormatInt(i, 10))
+				// elements are decoded like their array, e.g. TxHashes
+				ctx.writeJSON(buf, el, key, human)
+			}
+			buf.WriteByte(']')
+		default:
+			buf.WriteByte('{')
+			first := true
+			for _, k := range ctx.fields(obj) {
+				field, _ := obj.Get(k)
+				if field.IsFunction() {
+					continue
+				}
+				if !first {
+					buf.WriteByte(',')
+				}
+				first = false
+				writeDecoded(buf, k)
+				buf.WriteByte(':')
+				ctx.writeJSON(buf, field, k, human)
+			}
+			buf.WriteByte('}')
+		}
+	case v.IsNumber() && !v.IsNaN():
+		f, _ := v.ToFloat()
+		writeDecoded(buf, f)
+	case v.IsString():
+		s, _ := v.ToString()
+		writeDecoded(buf, s)
+	case v.IsBoolean():
+		b, _ := v.ToBoolean()
+		writeDecoded(buf, b)
+	default:
+		buf.WriteString("null")
+	}
+}
+
+func writeDecoded(buf *bytes.Buffer, decoded interface{}) {
+	if n, ok := decoded.(numberLiteral); ok {
+		buf.WriteString(string(n))
+		return
+	}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		buf.WriteString("null")
+		return
+	}
+	buf.Write(encoded)
+}
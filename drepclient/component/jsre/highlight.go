@@ -0,0 +1,102 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	KeywordColor = color.New(color.FgCyan).SprintfFunc()
+	CommentColor = color.New(color.FgHiBlack).SprintfFunc()
+)
+
+var keywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "const": true, "continue": true, "default": true,
+	"delete": true, "do": true, "else": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "in": true, "instanceof": true, "let": true, "new": true,
+	"null": true, "return": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "undefined": true, "var": true, "void": true, "while": true,
+}
+
+// Highlight colors the keywords, strings, numbers and comments of JavaScript
+// or JSON source with ANSI escapes. The source is returned as it is if colors
+// are disabled, e.g. because the output isn't a terminal.
+func Highlight(src string) string {
+	if color.NoColor {
+		return src
+	}
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		c := src[i]
+		j := i + 1
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			if j = strings.IndexByte(src[i:], '\n'); j < 0 {
+				j = len(src)
+			} else {
+				j += i
+			}
+			b.WriteString(CommentColor("%s", src[i:j]))
+		case strings.HasPrefix(src[i:], "/*"):
+			if j = strings.Index(src[i+2:], "*/"); j < 0 {
+				j = len(src)
+			} else {
+				j += i + 4
+			}
+			b.WriteString(CommentColor("%s", src[i:j]))
+		case c == '"' || c == '\'':
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(src) && src[j] == c {
+				j++
+			}
+			if j > len(src) {
+				j = len(src)
+			}
+			b.WriteString(StringColor("%s", src[i:j]))
+		case c >= '0' && c <= '9':
+			for j < len(src) && (isIdentChar(src[j]) || src[j] == '.') {
+				j++
+			}
+			b.WriteString(NumberColor("%s", src[i:j]))
+		case isIdentChar(c):
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			if word := src[i:j]; keywords[word] {
+				b.WriteString(KeywordColor("%s", word))
+			} else {
+				b.WriteString(word)
+			}
+		default:
+			b.WriteByte(c)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
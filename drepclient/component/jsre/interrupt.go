@@ -0,0 +1,163 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/robertkrimen/otto"
+)
+
+// ErrInterrupted is the error of JavaScript interrupted by the user, e.g. with
+// Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// interruption is the panic ending the JavaScript ended by Interrupt. Like an
+// ExitError it unwinds the script even through try blocks.
+type interruption struct {
+	err error
+}
+
+// errOutputLimit is the panic of a limitWriter stopping the printer.
+var errOutputLimit = errors.New("output limit reached")
+
+// SetTimeout limits the time a statement run by Evaluate may take, it is
+// interrupted once the time is up. Zero lifts the limit.
+func (re *JSRE) SetTimeout(timeout time.Duration) {
+	re.Do(func(*otto.Otto) { re.timeout = timeout })
+}
+
+// SetMaxOutput cuts the results printed by Evaluate after max bytes. Zero lifts
+// the limit.
+func (re *JSRE) SetMaxOutput(max int) {
+	re.Do(func(*otto.Otto) { re.maxOutput = max })
+}
+
+// Interrupt ends the JavaScript running on the event loop with err, such as
+// ErrInterrupted, at its next statement. It reports whether JavaScript was
+// running. Calls into Go, e.g. RPC calls, aren't cut short, the JavaScript is
+// ended once they return.
+func (re *JSRE) Interrupt(err error) bool {
+	return re.interruptRun(0, err)
+}
+
+// interruptRun interrupts the run with the given id, or any run if id is 0.
+func (re *JSRE) interruptRun(id uint64, err error) bool {
+	re.runMu.Lock()
+	defer re.runMu.Unlock()
+
+	if re.running == 0 || id != 0 && id != re.running {
+		return false
+	}
+	select {
+	case re.interrupt <- func() { panic(&interruption{err}) }:
+	default: // interrupted already
+	}
+	return true
+}
+
+// interruptible runs fn, which runs JavaScript on the event loop, and returns
+// the error it was interrupted with, if any. The JavaScript is interrupted
+// after timeout unless it is zero. Runs within a run are part of the outer
+// one.
+func (re *JSRE) interruptible(timeout time.Duration, fn func()) (err error) {
+	re.runMu.Lock()
+	if re.running != 0 {
+		re.runMu.Unlock()
+		fn()
+		return nil
+	}
+	re.runs++
+	id := re.runs
+	re.running = id
+	re.runMu.Unlock()
+
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			re.interruptRun(id, fmt.Errorf("timed out after %v", timeout))
+		})
+		defer timer.Stop()
+	}
+	defer func() {
+		re.runMu.Lock()
+		re.running = 0
+		select {
+		case <-re.interrupt: // drop an interruption which came too late
+		default:
+		}
+		re.runMu.Unlock()
+
+		if caught := recover(); caught != nil {
+			interrupted, ok := caught.(*interruption)
+			if !ok {
+				panic(caught)
+			}
+			err = interrupted.err
+		}
+	}()
+	fn()
+	return nil
+}
+
+// writeResult writes the result of Evaluate in the selected format, cut after
+// maxOutput bytes if it is set.
+func (re *JSRE) writeResult(vm *otto.Otto, value otto.Value, w io.Writer) (err error) {
+	if re.maxOutput <= 0 {
+		return writeFormatted(vm, value, re.format, w)
+	}
+	defer func() {
+		if caught := recover(); caught != nil {
+			if caught != errOutputLimit {
+				panic(caught)
+			}
+			fmt.Fprintf(w, "\n... output cut after %d bytes\n", re.maxOutput)
+			err = nil
+		}
+	}()
+	return writeFormatted(vm, value, re.format, &limitWriter{w: w, remaining: re.maxOutput})
+}
+
+// limitWriter passes the first bytes written on and stops the printer with an
+// errOutputLimit panic once more are written, so large values aren't walked
+// in vain.
+type limitWriter struct {
+	w         io.Writer
+	remaining int
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if len(p) <= lw.remaining {
+		lw.remaining -= len(p)
+		return lw.w.Write(p)
+	}
+	cut := p[:lw.remaining]
+	// don't leave half an escape sequence or a color switched on
+	if i := bytes.LastIndexByte(cut, 0x1b); i >= 0 && bytes.IndexByte(cut[i:], 'm') < 0 {
+		cut = cut[:i]
+	}
+	lw.w.Write(cut)
+	if !color.NoColor {
+		io.WriteString(lw.w, "\x1b[0m")
+	}
+	lw.remaining = 0
+	panic(errOutputLimit)
+}
@@ -24,7 +24,11 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/drep-project/drepcli/drepclient/component/jsre/deps"
@@ -50,6 +54,33 @@ type JSRE struct {
 	evalQueue     chan *evalReq
 	stopEventLoop chan bool
 	closed        chan struct{}
+
+	pending int32         // work held by Hold, accessed atomically
+	wake    chan struct{} // signals the event loop that pending work was released
+
+	format string // output format of Evaluate, only accessed by the event loop
+
+	timeout   time.Duration // time limit of statements run by Evaluate, 0 for none
+	maxOutput int           // size limit of results printed by Evaluate, 0 for none
+
+	interrupt chan func() // Interrupt channel of the vm
+	runMu     sync.Mutex  // protects running and runs
+	running   uint64      // id of the interruptible run on the event loop, 0 if none
+	runs      uint64      // number of interruptible runs so far
+
+	// Set when running a script with RunScript. Uncaught exceptions of timer
+	// callbacks end the script, the error is read once the event loop closed.
+	script    bool
+	scriptErr error
+}
+
+// ExitError is returned by RunScript if the script called exit(code).
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 // jsTimer is a single timer instance with a callback function
@@ -74,10 +105,14 @@ func New(assetPath string, output io.Writer) *JSRE {
 		closed:        make(chan struct{}),
 		evalQueue:     make(chan *evalReq),
 		stopEventLoop: make(chan bool),
+		wake:          make(chan struct{}, 1),
+		format:        FormatPretty,
+		interrupt:     make(chan func(), 1),
 	}
 	go re.runEventLoop()
 	re.Set("loadScript", re.loadScript)
 	re.Set("inspect", re.prettyPrintJS)
+	re.Set("format", re.formatJS)
 	return re
 }
 
@@ -106,6 +141,7 @@ func (re *JSRE) runEventLoop() {
 	defer close(re.closed)
 
 	vm := otto.New()
+	vm.Interrupt = re.interrupt
 	r := randomSource()
 	vm.SetRandomSource(r.Float64)
 
@@ -169,8 +205,13 @@ func (re *JSRE) runEventLoop() {
 	}`)
 	vm.Set("clearTimeout", clearTimeout)
 	vm.Set("clearInterval", clearTimeout)
+	vm.Run(promiseJS)
 
 	var waitForCallbacks bool
+	// idle reports whether no timers are scheduled and no work is held
+	idle := func() bool {
+		return len(registry) == 0 && atomic.LoadInt32(&re.pending) == 0
+	}
 
 loop:
 	for {
@@ -188,8 +229,21 @@ loop:
 				arguments = make([]interface{}, 1)
 			}
 			arguments[0] = timer.call.ArgumentList[0]
-			_, err := vm.Call(`Function.call.call`, nil, arguments...)
+			var err, interrupted error
+			if re.guard(func() {
+				interrupted = re.interruptible(0, func() { _, err = vm.Call(`Function.call.call`, nil, arguments...) })
+			}) {
+				break loop
+			}
+			if interrupted != nil { // an interrupted interval isn't run again
+				err = interrupted
+				delete(registry, timer)
+			}
 			if err != nil {
+				if re.script {
+					re.scriptErr = err
+					break loop
+				}
 				fmt.Println("js error:", err, arguments)
 			}
 
@@ -198,19 +252,23 @@ loop:
 				timer.timer.Reset(timer.duration)
 			} else {
 				delete(registry, timer)
-				if waitForCallbacks && (len(registry) == 0) {
+				if waitForCallbacks && idle() {
 					break loop
 				}
 			}
 		case req := <-re.evalQueue:
 			// run the code, send the result back
-			req.fn(vm)
+			exited := re.guard(func() { req.fn(vm) })
 			close(req.done)
-			if waitForCallbacks && (len(registry) == 0) {
+			if exited || (re.script && re.scriptErr != nil) || waitForCallbacks && idle() {
+				break loop
+			}
+		case <-re.wake:
+			if waitForCallbacks && idle() {
 				break loop
 			}
 		case waitForCallbacks = <-re.stopEventLoop:
-			if !waitForCallbacks || (len(registry) == 0) {
+			if !waitForCallbacks || idle() {
 				break loop
 			}
 		}
@@ -222,12 +280,68 @@ loop:
 	}
 }
 
-// Do executes the given function on the JS event loop.
+// guard runs fn on the event loop and reports whether the script called exit,
+// which ends the event loop.
+func (re *JSRE) guard(fn func()) (exited bool) {
+	defer func() {
+		if caught := recover(); caught != nil {
+			exit, ok := caught.(*ExitError)
+			if !ok {
+				panic(caught)
+			}
+			re.scriptErr = exit
+			exited = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// Do executes the given function on the JS event loop. The function is dropped
+// if the event loop has already been stopped.
 func (re *JSRE) Do(fn func(*otto.Otto)) {
 	done := make(chan bool)
 	req := &evalReq{fn, done}
-	re.evalQueue <- req
-	<-done
+	select {
+	case re.evalQueue <- req:
+		<-done
+	case <-re.closed:
+	}
+}
+
+// Hold keeps Stop(true) and RunScript waiting until release is called. It is
+// used for work that ends with a callback on the event loop, such as an
+// asynchronous RPC call. Release must be called once, after the callback has
+// been scheduled with Do.
+func (re *JSRE) Hold() (release func()) {
+	atomic.AddInt32(&re.pending, 1)
+	return func() {
+		atomic.AddInt32(&re.pending, -1)
+		select {
+		case re.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// CallFunction invokes a JavaScript callback, it must be called on the event
+// loop. Exceptions thrown by the callback are printed to the output, or end
+// a script run by RunScript like uncaught exceptions of timers.
+func (re *JSRE) CallFunction(vm *otto.Otto, fn otto.Value, args ...interface{}) {
+	var err error
+	if interrupted := re.interruptible(0, func() { _, err = fn.Call(otto.NullValue(), args...) }); interrupted != nil {
+		err = interrupted
+	}
+	if err != nil {
+		if re.script {
+			if re.scriptErr == nil {
+				re.scriptErr = err
+			}
+			return
+		}
+		prettyError(vm, err, re.output)
+		fmt.Fprintln(re.output)
+	}
 }
 
 // stops the event loop before exit, optionally waits for all timers to expire
@@ -257,6 +371,49 @@ func (re *JSRE) Exec(file string) error {
 	return err
 }
 
+// RunScript runs a file as a program. The arguments are exposed as process.argv
+// after the interpreter and the file, the environment as process.env, the
+// script ends itself with exit(code) or process.exit(code). RunScript waits
+// for all timers to fire and stops the runtime. It returns an *ExitError if
+// the script called exit and the first uncaught exception otherwise.
+func (re *JSRE) RunScript(file string, args []string) error {
+	path := AbsolutePath(re.assetPath, file)
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	var err error
+	re.Do(func(vm *otto.Otto) {
+		re.script = true
+		var process *otto.Object
+		if process, err = vm.Object(`({})`); err != nil {
+			return
+		}
+		process.Set("argv", append([]string{os.Args[0], path}, args...))
+		process.Set("env", env)
+		process.Set("exit", re.exit)
+		vm.Set("process", process)
+		vm.Set("exit", re.exit)
+	})
+	if err == nil {
+		err = re.Exec(path)
+	}
+	re.Stop(err == nil)
+	if err != nil {
+		return err
+	}
+	return re.scriptErr
+}
+
+// exit implements exit([code]), it unwinds the running script up to the event
+// loop, even through try blocks.
+func (re *JSRE) exit(call otto.FunctionCall) otto.Value {
+	code, _ := call.Argument(0).ToInteger()
+	panic(&ExitError{Code: int(code)})
+}
+
 // Bind assigns value v to a variable in the JS environment
 // This method is deprecated, use Set.
 func (re *JSRE) Bind(name string, v interface{}) error {
@@ -305,23 +462,52 @@ func (re *JSRE) loadScript(call otto.FunctionCall) otto.Value {
 	return otto.TrueValue()
 }
 
-// Evaluate executes code and pretty prints the result to the specified output
-// stream.
+// Evaluate executes code and prints the result to the specified output stream
+// in the format set with SetFormat. The code can be ended with Interrupt and
+// is interrupted once the timeout set with SetTimeout is up.
 func (re *JSRE) Evaluate(code string, w io.Writer) error {
 	var fail error
 
 	re.Do(func(vm *otto.Otto) {
-		val, err := vm.Run(code)
-		if err != nil {
-			prettyError(vm, err, w)
-		} else {
-			prettyPrint(vm, val, w)
+		var val otto.Value
+		if err := re.interruptible(re.timeout, func() { val, fail = vm.Run(code) }); err != nil {
+			fail = err
+		} else if fail == nil {
+			fail = re.writeResult(vm, val, w)
+		}
+		if fail != nil {
+			prettyError(vm, fail, w)
+			fmt.Fprintln(w)
 		}
-		fmt.Fprintln(w)
 	})
 	return fail
 }
 
+// SetFormat sets the output format of Evaluate, one of pretty, json, table and
+// yaml.
+func (re *JSRE) SetFormat(name string) error {
+	format, err := ParseFormat(name)
+	if err != nil {
+		return err
+	}
+	re.Do(func(*otto.Otto) { re.format = format })
+	return nil
+}
+
+// formatJS returns the output format of the console and sets it if a format
+// is passed, e.g. format("table").
+func (re *JSRE) formatJS(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) > 0 {
+		format, err := ParseFormat(call.Argument(0).String())
+		if err != nil {
+			panic(call.Otto.MakeCustomError("Error", err.Error()))
+		}
+		re.format = format
+	}
+	value, _ := call.Otto.ToValue(re.format)
+	return value
+}
+
 // Compile compiles and then runs a piece of JS code.
 func (re *JSRE) Compile(filename string, src interface{}) (err error) {
 	re.Do(func(vm *otto.Otto) { _, err = compileAndRun(vm, filename, src) })
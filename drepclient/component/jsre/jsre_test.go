@@ -17,12 +17,15 @@
 package jsre
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/robertkrimen/otto"
 )
 
@@ -135,3 +138,255 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+func TestRunScript(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `
+		if (process.argv[2] !== "a" || process.argv.length !== 4 || !process.env.PATH) { exit(9); }
+		setTimeout(function() {
+			try { exit(Number(process.argv[3])); } catch (e) {}
+			exit(8);
+		}, 10);`)
+	defer os.RemoveAll(dir)
+
+	err := jsre.RunScript("test.js", []string{"a", "3"})
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 3 {
+		t.Errorf("expected exit status 3, got %v", err)
+	}
+}
+
+func TestRunScriptUncaughtException(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `setTimeout(function() { throw new Error("boom"); }, 10); setTimeout(function() { exit(2); }, 50);`)
+	defer os.RemoveAll(dir)
+
+	err := jsre.RunScript("test.js", nil)
+	if ottoErr, ok := err.(*otto.Error); !ok || !strings.Contains(ottoErr.String(), "boom") {
+		t.Errorf("expected the uncaught exception, got %v", err)
+	}
+}
+
+func TestPromise(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `
+		var double = promisify(function(n, callback) { setTimeout(function() { callback(null, 2 * n); }, 5); });
+		var fail = promisify(function(callback) { callback("failed"); });
+		Promise.all([double(1), double(2), 3]).then(function(values) {
+			return fail().then(function() { exit(9); }, function(err) { return values.concat([err]); });
+		}).catch(function() { exit(8); }).then(function(values) {
+			exit(values.join(",") === "2,4,3,failed" ? 3 : 7);
+		});`)
+	defer os.RemoveAll(dir)
+
+	err := jsre.RunScript("test.js", nil)
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 3 {
+		t.Errorf("expected exit status 3, got %v", err)
+	}
+}
+
+func TestUnhandledRejection(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `Promise.reject(new Error("lost")); new Promise(function(resolve, reject) { reject(1); }).catch(function() {});`)
+	defer os.RemoveAll(dir)
+
+	err := jsre.RunScript("test.js", nil)
+	if ottoErr, ok := err.(*otto.Error); !ok || !strings.Contains(ottoErr.String(), "lost") {
+		t.Errorf("expected the unhandled rejection, got %v", err)
+	}
+}
+
+func TestHold(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `done = false; hold();`)
+	defer os.RemoveAll(dir)
+
+	jsre.Set("hold", func(call otto.FunctionCall) otto.Value {
+		release := jsre.Hold()
+		go func() {
+			defer release()
+			time.Sleep(20 * time.Millisecond)
+			jsre.Do(func(vm *otto.Otto) { vm.Run(`exit(done ? 1 : 4)`) })
+		}()
+		return otto.UndefinedValue()
+	})
+	err := jsre.RunScript("test.js", nil)
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 4 {
+		t.Errorf("expected the held callback to exit with 4, got %v", err)
+	}
+}
+
+func TestEvaluateFormat(t *testing.T) {
+	jsre := New("", os.Stdout)
+	defer jsre.Stop(false)
+	if err := jsre.Compile("bignumber.js", BigNumber_JS); err != nil {
+		t.Fatal(err)
+	}
+	block := `({Height: 3, GasLimit: "RWORgkT0AAA=", Timestamp: 1547280876, Balance: "0x3635c9adc5dea00001",
+		MinorPubKeys: ["AQI=", "0x0a"], R: new BigNumber("37142117789744075123456789012345678901234567890123456789012345678901234567890")})`
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, `{
+  "Balance": 1000000000000000000001,
+  "GasLimit": 5000000000000000000,
+  "Height": 3,
+  "MinorPubKeys": [
+    "0x0102",
+    "0x0a"
+  ],
+  "R": 37142117789744075123456789012345678901234567890123456789012345678901234567890,
+  "Timestamp": 1547280876
+}
+`},
+		{FormatPretty, `{
+  Balance: "1000.000000000000000001 drep",
+  GasLimit: 5000000000000000000,
+  Height: 3,
+  MinorPubKeys: ["0x0102", "0x0a"],
+  R: 37142117789744075123456789012345678901234567890123456789012345678901234567890,
+  Timestamp: "2019-01-12T08:14:36Z"
+}
+`},
+		{FormatTable, `Balance       1000.000000000000000001 drep
+GasLimit      5000000000000000000
+Height        3
+MinorPubKeys  ["0x0102","0x0a"]
+R             37142117789744075123456789012345678901234567890123456789012345678901234567890
+Timestamp     2019-01-12T08:14:36Z
+`},
+		{FormatYAML, `Balance: "1000000000000000000001"
+GasLimit: 5000000000000000000
+Height: 3
+MinorPubKeys:
+- "0x0102"
+- "0x0a"
+R: "37142117789744075123456789012345678901234567890123456789012345678901234567890"
+Timestamp: 1547280876
+`},
+	}
+	for _, test := range tests {
+		if err := jsre.SetFormat(test.format); err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		if err := jsre.Evaluate(block, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", test.format, out.String(), test.want)
+		}
+	}
+	if err := jsre.SetFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	result := json.RawMessage(`{"Height":3,"Nonce":9007199254740993,"Balance":"0x3635c9adc5dea00001","Sig":"AQI=","R":37142117789744075123456789012345678901234567890123456789012345678901234567890}`)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatPretty, "{\n  Balance: \"1000.000000000000000001 drep\",\n  Height: 3,\n  Nonce: 9007199254740993,\n  R: 37142117789744075123456789012345678901234567890123456789012345678901234567890,\n  Sig: \"0x0102\"\n}\n"},
+		{FormatJSON, "{\n  \"Balance\": 1000000000000000000001,\n  \"Height\": 3,\n  \"Nonce\": 9007199254740993,\n  \"R\": 37142117789744075123456789012345678901234567890123456789012345678901234567890,\n  \"Sig\": \"0x0102\"\n}\n"},
+		{FormatTable, "Balance  1000.000000000000000001 drep\nHeight   3\nNonce    9007199254740993\nR        37142117789744075123456789012345678901234567890123456789012345678901234567890\nSig      0x0102\n"},
+		{FormatYAML, "Balance: \"1000000000000000000001\"\nHeight: 3\nNonce: 9007199254740993\nR: \"37142117789744075123456789012345678901234567890123456789012345678901234567890\"\nSig: \"0x0102\"\n"},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		if err := WriteResult(&out, test.format, result); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", test.format, out.String(), test.want)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	vm := otto.New()
+	if _, err := vm.Run(BigNumber_JS); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input, want string
+	}{
+		{`{"n":1,"s":"12345678901234567890"}`, `{"n":1,"s":"12345678901234567890"}`},
+		{`[9007199254740993,-9007199254740993,1.5e300]`, `["9007199254740993","-9007199254740993",1.5e+300]`},
+		{`{"k\"9007199254740993":123456789012345678}`, `{"k\"9007199254740993":"123456789012345678"}`},
+	}
+	for _, test := range tests {
+		value, err := ParseJSON(vm, []byte(test.input))
+		if err != nil {
+			t.Fatalf("%s: %v", test.input, err)
+		}
+		got, err := vm.Call("JSON.stringify", nil, value)
+		if err != nil || got.String() != test.want {
+			t.Errorf("%s: parsed to %s, %v, want %s", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestEvaluateInterrupt(t *testing.T) {
+	jsre := New("", os.Stdout)
+	defer jsre.Stop(false)
+
+	// the timeout ends loops, even within try blocks
+	jsre.SetTimeout(50 * time.Millisecond)
+	var out strings.Builder
+	err := jsre.Evaluate(`try { while (true) {} } catch (e) {}`, &out)
+	if err == nil || !strings.Contains(out.String(), "timed out after 50ms") {
+		t.Fatalf("expected the loop to time out, got %v: %q", err, out.String())
+	}
+	jsre.SetTimeout(0)
+
+	go func() {
+		for !jsre.Interrupt(ErrInterrupted) {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if err := jsre.Evaluate(`while (true) {}`, &out); err != ErrInterrupted {
+		t.Fatalf("expected the loop to be interrupted, got %v", err)
+	}
+	// the runtime is still usable and late interruptions are dropped
+	jsre.Interrupt(ErrInterrupted)
+	out.Reset()
+	if err := jsre.Evaluate(`var sum = 0; for (var i = 0; i < 1000; i++) { sum += i }; sum`, &out); err != nil || out.String() != "499500\n" {
+		t.Errorf("got %v: %q", err, out.String())
+	}
+}
+
+func TestEvaluateMaxOutput(t *testing.T) {
+	jsre := New("", os.Stdout)
+	defer jsre.Stop(false)
+
+	jsre.SetMaxOutput(10)
+	var out strings.Builder
+	if err := jsre.Evaluate(`[1, 2, 3, 4, 5, 6, 7, 8, 9]`, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[1, 2, 3, \n... output cut after 10 bytes\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	if err := jsre.Evaluate(`"short"`, &out); err != nil || out.String() != "\"short\"\n" {
+		t.Errorf("got %v: %q", err, out.String())
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = false
+
+	src := `var s = "a\"b" + 'c'; // done
+if (x > 10.5) { return null } /* end */`
+	got := Highlight(src)
+	for _, want := range []string{
+		KeywordColor("var"), StringColor(`"a\"b"`), StringColor("'c'"), CommentColor("// done"),
+		KeywordColor("if"), NumberColor("10.5"), KeywordColor("return"), KeywordColor("null"), CommentColor("/* end */"),
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q doesn't contain %q", got, want)
+		}
+	}
+	color.NoColor = true
+	if got := Highlight(src); got != src {
+		t.Errorf("got %q without colors", got)
+	}
+}
@@ -110,8 +110,8 @@ func (ctx ppctx) printObject(obj *otto.Object, level int, inArray bool) {
 	switch obj.Class() {
 	case "Array", "GoArray":
 		lv, _ := obj.Get("length")
-		len, _ := lv.ToInteger()
-		if len == 0 {
+		length, _ := lv.ToInteger()
+		if length == 0 {
 			fmt.Fprintf(ctx.w, "[]")
 			return
 		}
@@ -120,12 +120,12 @@ func (ctx ppctx) printObject(obj *otto.Object, level int, inArray bool) {
 			return
 		}
 		fmt.Fprint(ctx.w, "[")
-		for i := int64(0); i < len; i++ {
+		for i := int64(0); i < length; i++ {
 			el, err := obj.Get(strconv.FormatInt(i, 10))
 			if err == nil {
 				ctx.printValue(el, level+1, true)
 			}
-			if i < len-1 {
+			if i < length-1 {
 				fmt.Fprintf(ctx.w, ", ")
 			}
 		}
@@ -134,7 +134,7 @@ func (ctx ppctx) printObject(obj *otto.Object, level int, inArray bool) {
 	case "Object":
 		// Print values from bignumber.js as regular numbers.
 		if ctx.isBigNumber(obj) {
-			fmt.Fprint(ctx.w, NumberColor("%s", toString(obj)))
+			fmt.Fprint(ctx.w, NumberColor("%s", bigNumberString(obj)))
 			return
 		}
 		// Otherwise, print all fields indented, but stop if we're too deep.
@@ -151,7 +151,9 @@ func (ctx ppctx) printObject(obj *otto.Object, level int, inArray bool) {
 		for i, k := range keys {
 			v, _ := obj.Get(k)
 			fmt.Fprintf(ctx.w, "%s%s: ", ctx.indent(level+1), k)
-			ctx.printValue(v, level+1, false)
+			if decoder := decoderFor(k); decoder == nil || !ctx.printDecoded(decoder, v, level+1) {
+				ctx.printValue(v, level+1, false)
+			}
 			if i < len(keys)-1 {
 				fmt.Fprintf(ctx.w, ",")
 			}
@@ -185,6 +187,45 @@ func (ctx ppctx) printObject(obj *otto.Object, level int, inArray bool) {
 	}
 }
 
+// printDecoded prints a field value with the decoder of the field, arrays
+// element by element. It returns false if the decoder doesn't apply.
+func (ctx ppctx) printDecoded(decoder fieldDecoder, v otto.Value, level int) bool {
+	if !v.IsObject() || v.Class() != "Array" {
+		decoded, ok := decoder(ctx, v, true)
+		if ok {
+			ctx.printDecodedValue(decoded)
+		}
+		return ok
+	}
+	lv, _ := v.Object().Get("length")
+	length, _ := lv.ToInteger()
+	if length == 0 || level > maxPrettyPrintLevel {
+		return false
+	}
+	fmt.Fprint(ctx.w, "[")
+	for i := int64(0); i < length; i++ {
+		el, _ := v.Object().Get(strconv.FormatInt(i, 10))
+		if decoded, ok := decoder(ctx, el, true); ok {
+			ctx.printDecodedValue(decoded)
+		} else {
+			ctx.printValue(el, level+1, true)
+		}
+		if i < length-1 {
+			fmt.Fprint(ctx.w, ", ")
+		}
+	}
+	fmt.Fprint(ctx.w, "]")
+	return true
+}
+
+func (ctx ppctx) printDecodedValue(decoded interface{}) {
+	if n, ok := decoded.(numberLiteral); ok {
+		fmt.Fprint(ctx.w, NumberColor("%s", n))
+	} else {
+		fmt.Fprint(ctx.w, StringColor("%q", decoded))
+	}
+}
+
 func (ctx ppctx) fields(obj *otto.Object) []string {
 	var (
 		vals, methods []string
@@ -0,0 +1,189 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package jsre
+
+// promiseJS is a minimal Promise implementation for the ES5 interpreter, along
+// with promisify to turn functions taking a callback(err, result), such as the
+// RPC methods of the console, into functions returning a promise. Reactions run
+// from timers, a rejection nobody handled is thrown as uncaught exception.
+const promiseJS = `
+var Promise = (function() {
+	var PENDING = 0, FULFILLED = 1, REJECTED = 2;
+
+	function Promise(executor) {
+		if (typeof executor !== 'function') {
+			throw new TypeError('Promise resolver ' + executor + ' is not a function');
+		}
+		this._state = PENDING;
+		this._value = undefined;
+		this._reactions = [];
+		this._handled = false;
+
+		var self = this, done = false;
+		try {
+			executor(function(value) {
+				if (!done) { done = true; resolve(self, value); }
+			}, function(reason) {
+				if (!done) { done = true; settle(self, REJECTED, reason); }
+			});
+		} catch (e) {
+			if (!done) { done = true; settle(self, REJECTED, e); }
+		}
+	}
+
+	// resolve adopts the state of thenables and fulfills with other values
+	function resolve(promise, value) {
+		if (value === promise) {
+			return settle(promise, REJECTED, new TypeError('A promise cannot be resolved with itself'));
+		}
+		if (value !== null && (typeof value === 'object' || typeof value === 'function')) {
+			var then;
+			try {
+				then = value.then;
+			} catch (e) {
+				return settle(promise, REJECTED, e);
+			}
+			if (typeof then === 'function') {
+				var done = false;
+				try {
+					then.apply(value, [function(v) {
+						if (!done) { done = true; resolve(promise, v); }
+					}, function(r) {
+						if (!done) { done = true; settle(promise, REJECTED, r); }
+					}]);
+				} catch (e) {
+					if (!done) { done = true; settle(promise, REJECTED, e); }
+				}
+				return;
+			}
+		}
+		settle(promise, FULFILLED, value);
+	}
+
+	function settle(promise, state, value) {
+		if (promise._state !== PENDING) {
+			return;
+		}
+		promise._state = state;
+		promise._value = value;
+		var reactions = promise._reactions;
+		promise._reactions = null;
+		for (var i = 0; i < reactions.length; i++) {
+			react(promise, reactions[i]);
+		}
+		if (state === REJECTED) {
+			setTimeout(function() {
+				if (!promise._handled) {
+					throw value instanceof Error ? value : new Error('Uncaught (in promise) ' + value);
+				}
+			}, 0);
+		}
+	}
+
+	function react(promise, reaction) {
+		setTimeout(function() {
+			var handler = promise._state === FULFILLED ? reaction.onFulfilled : reaction.onRejected;
+			if (typeof handler !== 'function') {
+				if (promise._state === FULFILLED) {
+					resolve(reaction.promise, promise._value);
+				} else {
+					settle(reaction.promise, REJECTED, promise._value);
+				}
+				return;
+			}
+			var result;
+			try {
+				result = handler(promise._value);
+			} catch (e) {
+				return settle(reaction.promise, REJECTED, e);
+			}
+			resolve(reaction.promise, result);
+		}, 0);
+	}
+
+	Promise.prototype.then = function(onFulfilled, onRejected) {
+		var reaction = {onFulfilled: onFulfilled, onRejected: onRejected, promise: new Promise(function() {})};
+		this._handled = true;
+		if (this._state === PENDING) {
+			this._reactions.push(reaction);
+		} else {
+			react(this, reaction);
+		}
+		return reaction.promise;
+	};
+
+	Promise.prototype['catch'] = function(onRejected) {
+		return this.then(undefined, onRejected);
+	};
+
+	Promise.resolve = function(value) {
+		if (value instanceof Promise) {
+			return value;
+		}
+		return new Promise(function(resolve) { resolve(value); });
+	};
+
+	Promise.reject = function(reason) {
+		return new Promise(function(resolve, reject) { reject(reason); });
+	};
+
+	Promise.all = function(values) {
+		return new Promise(function(resolve, reject) {
+			var results = [], remaining = values.length;
+			if (remaining === 0) {
+				return resolve(results);
+			}
+			for (var i = 0; i < values.length; i++) {
+				(function(i) {
+					Promise.resolve(values[i]).then(function(value) {
+						results[i] = value;
+						if (--remaining === 0) {
+							resolve(results);
+						}
+					}, reject);
+				})(i);
+			}
+		});
+	};
+
+	Promise.race = function(values) {
+		return new Promise(function(resolve, reject) {
+			for (var i = 0; i < values.length; i++) {
+				Promise.resolve(values[i]).then(resolve, reject);
+			}
+		});
+	};
+
+	return Promise;
+})();
+
+var promisify = function(fn) {
+	return function() {
+		var self = this, args = Array.prototype.slice.apply(arguments);
+		return new Promise(function(resolve, reject) {
+			args.push(function(err, result) {
+				if (err) {
+					reject(err);
+				} else {
+					resolve(result);
+				}
+			});
+			fn.apply(self, args);
+		});
+	};
+};
+`
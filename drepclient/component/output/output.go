@@ -0,0 +1,278 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package output renders JSON-RPC results as JSON, YAML or text tables. The
+// pretty format is rendered by the printer of the console, see jsre.WriteResult.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Format is an output format of RPC results
+type Format string
+
+const (
+	Pretty Format = "pretty"
+	JSON   Format = "json"
+	Table  Format = "table"
+	YAML   Format = "yaml"
+)
+
+// Formats lists the formats of the console and the API commands
+var Formats = []Format{Pretty, JSON, Table, YAML}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Write renders the JSON encoded value to w in any format but Pretty. Object
+// keys keep the order of the encoding, numbers are written as they are encoded.
+func Write(w io.Writer, format Format, value json.RawMessage) error {
+	if len(bytes.TrimSpace(value)) == 0 {
+		value = json.RawMessage("null")
+	}
+	switch format {
+	case JSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, value, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := buf.WriteTo(w)
+		return err
+	case YAML:
+		decoded, err := decode(value)
+		if err != nil {
+			return err
+		}
+		out, err := yaml.Marshal(toYAML(decoded))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case Table:
+		decoded, err := decode(value)
+		if err != nil {
+			return err
+		}
+		return writeTable(w, decoded)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// field is a member of a decoded JSON object
+type field struct {
+	key   string
+	value interface{}
+}
+
+// object is a decoded JSON object, its fields in the order of the encoding
+type object []field
+
+// decode decodes JSON into objects, []interface{}, json.Number, string, bool
+// and nil values
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{key.(string), value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	}
+	return token, nil
+}
+
+// toYAML converts a decoded value to values the YAML encoder keeps in order
+func toYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case object:
+		slice := make(yaml.MapSlice, len(v))
+		for i, f := range v {
+			slice[i] = yaml.MapItem{Key: f.key, Value: toYAML(f.value)}
+		}
+		return slice
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = toYAML(item)
+		}
+		return list
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return n
+		}
+		// keep numbers float64 can't hold exactly as written
+		return string(v)
+	}
+	return value
+}
+
+// writeTable writes objects as key/value rows, lists of objects as one row per
+// object with a column per key and other values one per line. Nested values
+// are written as compact JSON.
+func writeTable(w io.Writer, value interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	switch v := value.(type) {
+	case object:
+		for _, f := range v {
+			fmt.Fprintf(tw, "%s\t%s\n", f.key, cell(f.value))
+		}
+	case []interface{}:
+		columns := listColumns(v)
+		if columns == nil {
+			for _, item := range v {
+				fmt.Fprintln(tw, cell(item))
+			}
+			break
+		}
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, item := range v {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				for _, f := range item.(object) {
+					if f.key == column {
+						row[i] = cell(f.value)
+					}
+				}
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	default:
+		fmt.Fprintln(tw, cell(v))
+	}
+	return tw.Flush()
+}
+
+// listColumns returns the keys of a list of objects in order of appearance,
+// or nil if the list holds other values
+func listColumns(list []interface{}) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, item := range list {
+		obj, ok := item.(object)
+		if !ok {
+			return nil
+		}
+		for _, f := range obj {
+			if !seen[f.key] {
+				seen[f.key] = true
+				columns = append(columns, f.key)
+			}
+		}
+	}
+	return columns
+}
+
+// cell renders a value as a single line
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	var buf bytes.Buffer
+	writeCompact(&buf, value)
+	return buf.String()
+}
+
+// writeCompact writes a decoded value as compact JSON
+func writeCompact(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case object:
+		buf.WriteByte('{')
+		for i, f := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(f.key)
+			buf.Write(key)
+			buf.WriteByte(':')
+			writeCompact(buf, f.value)
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCompact(buf, item)
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(string(v))
+	default:
+		encoded, _ := json.Marshal(v)
+		buf.Write(encoded)
+	}
+}
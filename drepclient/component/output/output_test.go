@@ -0,0 +1,61 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format Format
+		value  string
+		want   string
+	}{
+		{JSON, `{"b":1,"a":[true]}`, "{\n  \"b\": 1,\n  \"a\": [\n    true\n  ]\n}\n"},
+		{YAML, `{"b":1,"a":"x","big":123456789012345678901234567890}`, "b: 1\na: x\nbig: \"123456789012345678901234567890\"\n"},
+		{YAML, `null`, "null\n"},
+		{Table, `{"Nonce":2,"Balance":"0xa","Data":{"x":[1]}}`, "Nonce    2\nBalance  0xa\nData     {\"x\":[1]}\n"},
+		{Table, `[{"to":"0x1","n":1},{"to":"0x22","extra":null}]`, "TO    N  EXTRA\n0x1   1  \n0x22     \n"},
+		{Table, `["0x1","0x2"]`, "0x1\n0x2\n"},
+		{Table, `"0xa"`, "0xa\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, test.format, json.RawMessage(test.value)); err != nil {
+			t.Errorf("%s %s: %v", test.format, test.value, err)
+			continue
+		}
+		if buf.String() != test.want {
+			t.Errorf("%s %s:\ngot  %q\nwant %q", test.format, test.value, buf.String(), test.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("YAML"); err != nil || format != YAML {
+		t.Errorf("got %q, %v", format, err)
+	}
+	if format, err := ParseFormat("pretty"); err != nil || format != Pretty {
+		t.Errorf("got %q, %v", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/drepclient/component/jsre"
+	"github.com/drep-project/drepcli/drepclient/component/jsre/deps"
+	cliTypes "github.com/drep-project/drepcli/drepclient/types"
+	rpcComponent "github.com/drep-project/drepcli/rpc/component"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// Exit codes of the API and run commands.
+const (
+	ExitFailure     = 1 // other failures, e.g. an uncaught exception of a script
+	ExitInvalidArgs = 2 // the arguments don't match the method parameters
+	ExitUnreachable = 3 // the node couldn't be reached or answered garbage
+	ExitRPCError    = 4 // the node returned an error for the call
+)
+
+// apiAliases are additional command names of some methods
+var apiAliases = map[string][]string{
+	"account_addressList": {"list"},
+}
+
+// apiCommands returns a command per namespace with a subcommand for every
+// method drep.js defines in it, e.g. "db get-block 10".
+func apiCommands() []cli.Command {
+	var commands []cli.Command
+	for _, namespace := range deps.Namespaces {
+		command := cli.Command{Name: namespace.Name, Usage: namespace.Description}
+		for _, method := range deps.Methods() {
+			if method.Namespace == namespace.Name {
+				command.Subcommands = append(command.Subcommands, apiCommand(method))
+			}
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// apiCommand returns the command calling the method. Parameters are taken
+// from their flags or, in order, from the arguments.
+func apiCommand(method deps.Method) cli.Command {
+	flags := []cli.Flag{cliTypes.EndpointFlag, cliTypes.OutputFlag}
+	var argsUsage []string
+	for _, param := range method.Params {
+		flags = append(flags, paramFlag(param))
+		if param.Default == "" {
+			argsUsage = append(argsUsage, "<"+param.Name+">")
+		} else {
+			argsUsage = append(argsUsage, "["+param.Name+"]")
+		}
+	}
+	return cli.Command{
+		Name:        kebabCase(method.Name),
+		Aliases:     apiAliases[method.Call],
+		Usage:       method.Description,
+		Description: fmt.Sprintf("%s. Calls %s.\n\nReturns: %s\nConsole example: %s", method.Description, method.Call, method.Returns, method.Example),
+		ArgsUsage:   strings.Join(argsUsage, " "),
+		Flags:       flags,
+		Action: func(ctx *cli.Context) error {
+			return callMethod(ctx, method)
+		},
+	}
+}
+
+// paramFlag returns the flag setting the parameter
+func paramFlag(param deps.Param) cli.Flag {
+	name := kebabCase(param.Name)
+	usage := fmt.Sprintf("%s (%s)", param.Name, param.Type)
+	if param.Default != "" {
+		usage += ", default " + param.Default
+	}
+	switch param.Type {
+	case deps.ParamUint:
+		return cli.Uint64Flag{Name: name, Usage: usage}
+	case deps.ParamBool:
+		return cli.BoolFlag{Name: name, Usage: usage}
+	}
+	return cli.StringFlag{Name: name, Usage: usage}
+}
+
+// callMethod calls the method with the parameters given on the command line
+// and writes the result in the selected format
+func callMethod(ctx *cli.Context, method deps.Method) error {
+	format, err := jsre.ParseFormat(commandOutput(ctx))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ExitInvalidArgs)
+	}
+	params, err := methodArgs(ctx, method)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("%s: %v", method.Call, err), ExitInvalidArgs)
+	}
+
+	client, err := rpcComponent.Dial(commandEndpoint(ctx))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to attach to remote drep: %v", err), ExitUnreachable)
+	}
+	defer client.Close()
+
+	if err := resolveBlockTags(client, method, params); err != nil {
+		return callError("db_getMaxHeight", err)
+	}
+	var result json.RawMessage
+	if err := client.CallContext(context.Background(), &result, method.Call, params...); err != nil {
+		return callError(method.Call, err)
+	}
+	return jsre.WriteResult(ctx.App.Writer, format, result)
+}
+
+// callError returns the exit error of a failed call
+func callError(call string, err error) error {
+	if rpcErr, ok := err.(rpcTypes.Error); ok {
+		return cli.NewExitError(fmt.Sprintf("%s failed: %v (code %d)", call, err, rpcErr.ErrorCode()), ExitRPCError)
+	}
+	return cli.NewExitError(fmt.Sprintf("%s failed: %v", call, err), ExitUnreachable)
+}
+
+// commandEndpoint returns the endpoint flag, by default the local HTTP endpoint
+func commandEndpoint(ctx *cli.Context) string {
+	if endpoint := ctx.String(cliTypes.EndpointFlag.Name); endpoint != "" {
+		return endpoint
+	}
+	return "http://" + rpcTypes.DefaultHTTPEndpoint()
+}
+
+// commandOutput returns the output format set on the command or globally,
+// json by default
+func commandOutput(ctx *cli.Context) string {
+	if format := ctx.String(cliTypes.OutputFlag.Name); format != "" {
+		return format
+	}
+	if format := ctx.GlobalString(cliTypes.OutputFlag.Name); format != "" {
+		return format
+	}
+	return jsre.FormatJSON
+}
+
+// methodArgs converts the flags and arguments to the parameters of the method
+func methodArgs(ctx *cli.Context, method deps.Method) ([]interface{}, error) {
+	args := ctx.Args()
+	params := make([]interface{}, len(method.Params))
+	for i, param := range method.Params {
+		var raw string
+		switch name := kebabCase(param.Name); {
+		case ctx.IsSet(name) && param.Type == deps.ParamUint:
+			raw = strconv.FormatUint(ctx.Uint64(name), 10)
+		case ctx.IsSet(name) && param.Type == deps.ParamBool:
+			raw = strconv.FormatBool(ctx.Bool(name))
+		case ctx.IsSet(name):
+			raw = ctx.String(name)
+		case len(args) > 0:
+			raw, args = args[0], args[1:]
+		case param.Default != "":
+			raw = param.Default
+		default:
+			return nil, fmt.Errorf("missing %s, pass it as argument or with --%s", param.Name, name)
+		}
+		value, err := paramValue(param, raw)
+		if err != nil {
+			return nil, err
+		}
+		params[i] = value
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("too many arguments %q", []string(args))
+	}
+	return params, nil
+}
+
+// resolveBlockTags replaces the block tags among the parameters by heights.
+func resolveBlockTags(client *rpcComponent.Client, method deps.Method, params []interface{}) error {
+	for i, param := range method.Params {
+		tag, ok := params[i].(string)
+		if param.Type != deps.ParamBlock || !ok {
+			continue
+		}
+		switch common.BlockTags[tag] {
+		case common.LatestBlockNumber:
+			var height uint64
+			if err := client.Call(&height, "db_getMaxHeight"); err != nil {
+				return err
+			}
+			params[i] = height
+		case common.EarliestBlockNumber:
+			params[i] = uint64(0)
+		}
+	}
+	return nil
+}
+
+// paramValue converts the text of a parameter to the value sent to the node
+func paramValue(param deps.Param, raw string) (interface{}, error) {
+	switch param.Type {
+	case deps.ParamAddress:
+		address, err := parseAddress(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", param.Name, err)
+		}
+		return address, nil
+	case deps.ParamUint:
+		n, err := strconv.ParseUint(raw, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid number %q", param.Name, raw)
+		}
+		return n, nil
+	case deps.ParamBlock:
+		// pending blocks aren't stored by nodes
+		if number, ok := common.BlockTags[raw]; ok && number != common.PendingBlockNumber {
+			return raw, nil // resolved once the node is attached
+		}
+		n, err := strconv.ParseUint(raw, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid block %q, expected a height, latest or earliest", param.Name, raw)
+		}
+		return n, nil
+	case deps.ParamAmount:
+		amount, err := common.ParseAmount(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", param.Name, err)
+		}
+		return amount.String(), nil
+	case deps.ParamHex:
+		if _, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(raw, "0x"), "0X")); err != nil {
+			return nil, fmt.Errorf("%s: invalid hex data %q", param.Name, raw)
+		}
+		return raw, nil
+	case deps.ParamBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid boolean %q", param.Name, raw)
+		}
+		return b, nil
+	case deps.ParamAny:
+		if json.Valid([]byte(raw)) {
+			return json.RawMessage(raw), nil
+		}
+	}
+	return raw, nil
+}
+
+// kebabCase converts a method or parameter name to a command or flag name,
+// getBlocksFrom becomes get-blocks-from
+func kebabCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"github.com/drep-project/drepcli/app"
 	"github.com/drep-project/drepcli/drepclient/component/console"
@@ -19,8 +20,9 @@ var (
 
 // CliService provides an interactive command line window
 type CliService struct {
-	config *cliTypes.Config
-	Log *log.LogService `service:"log"`
+	config   *cliTypes.Config
+	recorder *rpcComponent.Recorder // set when the session is recorded
+	Log      *log.LogService        `service:"log"`
 }
 
 // Name name
@@ -35,29 +37,80 @@ func (cliService *CliService) Api() []app.API {
 
 // Flags flags  enable load js and execute before run
 func (cliService *CliService) Flags() []cli.Flag {
-	return []cli.Flag{cliTypes.JSpathFlag, cliTypes.ExecFlag, cliTypes.PreloadJSFlag}
+	return []cli.Flag{cliTypes.JSpathFlag, cliTypes.ExecFlag, cliTypes.OutputFlag, cliTypes.HistorySizeFlag, cliTypes.TimeoutFlag, cliTypes.MaxOutputFlag, cliTypes.PreloadJSFlag, cliTypes.EndpointStrategyFlag, cliTypes.RPCCacheFlag, cliTypes.RecordFlag, cliTypes.ReplayFlag, cliTypes.ReplayRealtimeFlag}
 }
 
-// Init  set console config
+// Init  set console config, several endpoints may be given as separate or comma separated arguments
 func (cliService *CliService) Init(executeContext *app.ExecuteContext) error {
-	endpoint := executeContext.CliContext.Args().First()
-	if len(endpoint) == 0 {
+	path := executeContext.CommonConfig.HomeDir
+	if replay := executeContext.CliContext.GlobalString(cliTypes.ReplayFlag.Name); replay != "" {
+		// a replayed session is served in process, no node is attached
+		client, err := rpcComponent.DialReplay(replay, executeContext.CliContext.GlobalBool(cliTypes.ReplayRealtimeFlag.Name))
+		if err != nil {
+			return fmt.Errorf("Unable to replay session: %v", err)
+		}
+		cliService.setConfig(executeContext, path, nil, client)
+		return nil
+	}
+
+	endpoints := rpcComponent.SplitEndpoints(executeContext.CliContext.Args()...)
+	if len(endpoints) == 0 {
 		return fmt.Errorf("You have to specify an address")
 	}
-	client, err := rpcComponent.Dial(endpoint)
+	client, err := cliService.dial(executeContext, endpoints)
 	if err != nil {
 		return fmt.Errorf("Unable to attach to remote drep: %v", err)
 	}
+	if record := executeContext.CliContext.GlobalString(cliTypes.RecordFlag.Name); record != "" {
+		cliService.recorder, err = rpcComponent.CreateRecorder(record)
+		if err != nil {
+			return fmt.Errorf("Unable to record session: %v", err)
+		}
+		client.SetRecorder(cliService.recorder)
+	}
 
-	path := executeContext.CommonConfig.HomeDir
+	if executeContext.CliContext.GlobalBool(cliTypes.RPCCacheFlag.Name) {
+		config := rpcComponent.DefaultCacheConfig
+		config.Dir = rpcComponent.CacheDir(path, endpoints)
+		cache, err := rpcComponent.NewCache(config)
+		if err != nil {
+			return err
+		}
+		client.SetCache(cache)
+	}
+	cliService.setConfig(executeContext, path, endpoints, client)
+	return nil
+}
+
+func (cliService *CliService) setConfig(executeContext *app.ExecuteContext, path string, endpoints []string, client *rpcComponent.Client) {
 	cliService.config = &cliTypes.Config{}
 	cliService.config.Config = console.Config{
 		HomeDir: path,
 		DocRoot: executeContext.CliContext.GlobalString(cliTypes.JSpathFlag.Name),
 		Client:  client,
 		Preload: cliTypes.MakeConsolePreloads(executeContext.CliContext),
+		Format:  executeContext.CliContext.GlobalString(cliTypes.OutputFlag.Name),
+
+		Endpoints:   endpoints,
+		HistorySize: executeContext.CliContext.GlobalInt(cliTypes.HistorySizeFlag.Name),
+
+		Timeout:   executeContext.CliContext.GlobalDuration(cliTypes.TimeoutFlag.Name),
+		MaxOutput: executeContext.CliContext.GlobalInt(cliTypes.MaxOutputFlag.Name),
 	}
-	return nil
+}
+
+// dial attaches to a single endpoint or to a pool of endpoints using the selected strategy
+func (cliService *CliService) dial(executeContext *app.ExecuteContext, endpoints []string) (*rpcComponent.Client, error) {
+	if len(endpoints) == 1 {
+		return rpcComponent.Dial(endpoints[0])
+	}
+	strategy, err := rpcComponent.ParseStrategy(executeContext.CliContext.GlobalString(cliTypes.EndpointStrategyFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	config := rpcComponent.DefaultPoolConfig
+	config.Strategy = strategy
+	return rpcComponent.DialEndpoints(context.Background(), endpoints, config)
 }
 
 func (cliService *CliService) Start(executeContext *app.ExecuteContext) error {
@@ -66,6 +119,9 @@ func (cliService *CliService) Start(executeContext *app.ExecuteContext) error {
 
 func (cliService *CliService) Stop(executeContext *app.ExecuteContext) error {
 	console.Stdin.Close()
+	if cliService.recorder != nil {
+		return cliService.recorder.Close()
+	}
 	return nil
 }
 
@@ -79,7 +135,10 @@ func (cliService *CliService) remoteConsole(executeContext *app.ExecuteContext)
 	defer console.Stop(false)
 
 	if script := executeContext.CliContext.GlobalString(cliTypes.ExecFlag.Name); script != "" {
-		console.Evaluate(script)
+		if err := console.Evaluate(script); err != nil {
+			// the error has been printed by the console
+			return cli.NewExitError("", 1)
+		}
 		return nil
 	}
 
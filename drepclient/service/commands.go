@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/robertkrimen/otto"
+
+	"github.com/drep-project/drepcli/app"
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/crypto"
+	"github.com/drep-project/drepcli/drepclient/component/console"
+	"github.com/drep-project/drepcli/drepclient/component/jsre"
+	cliTypes "github.com/drep-project/drepcli/drepclient/types"
+	rpcComponent "github.com/drep-project/drepcli/rpc/component"
+)
+
+// Commands returns the commands offered by the cli service
+func (cliService *CliService) Commands(executeContext *app.ExecuteContext) []cli.Command {
+	return append(apiCommands(), []cli.Command{
+		{
+			Name:      "run",
+			Usage:     "Run a JavaScript file against a drep node",
+			ArgsUsage: "<file.js> [-- args...]",
+			Description: `Runs the file in the console environment. The arguments following the file
+are passed in process.argv, the environment in process.env. The command exits
+once the script and its timers are done, with the code passed to exit(code) or
+1 if an exception wasn't caught.`,
+			Flags: []cli.Flag{cliTypes.EndpointFlag},
+			Action: func(ctx *cli.Context) error {
+				return runScript(executeContext, ctx)
+			},
+		},
+		{
+			Name:  "watch",
+			Usage: "Stream chain events of a drep node as JSON lines",
+			Subcommands: []cli.Command{
+				{
+					Name:   "blocks",
+					Usage:  "Stream new blocks",
+					Flags:  []cli.Flag{cliTypes.EndpointFlag},
+					Action: watchBlocks,
+				},
+				{
+					Name:      "address",
+					Usage:     "Stream state changes of an account",
+					ArgsUsage: "<address>",
+					Flags:     []cli.Flag{cliTypes.EndpointFlag},
+					Action:    watchAddress,
+				},
+			},
+		},
+	}...)
+}
+
+// runScript runs the script given as first argument in a console attached to
+// the endpoint and turns its outcome into the exit code of the command
+func runScript(executeContext *app.ExecuteContext, ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) == 0 {
+		return cli.NewExitError("You have to specify a script file", ExitInvalidArgs)
+	}
+	file, args := args[0], args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	client, err := rpcComponent.Dial(commandEndpoint(ctx))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to attach to remote drep: %v", err), ExitUnreachable)
+	}
+	defer client.Close()
+	console, err := console.New(console.Config{
+		HomeDir: executeContext.CommonConfig.HomeDir,
+		DocRoot: ctx.GlobalString(cliTypes.JSpathFlag.Name),
+		Client:  client,
+		Preload: cliTypes.MakeConsolePreloads(ctx),
+
+		NoHistory: true,
+	})
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Failed to start the JavaScript console: %v", err), ExitUnreachable)
+	}
+	defer console.Stop(false)
+
+	switch err := console.Run(file, args).(type) {
+	case nil:
+		return nil
+	case *jsre.ExitError:
+		if err.Code == 0 {
+			return nil
+		}
+		return cli.NewExitError("", err.Code)
+	case *otto.Error:
+		// the stack trace of the uncaught exception
+		return cli.NewExitError(err.String(), ExitFailure)
+	default:
+		return cli.NewExitError(err.Error(), ExitFailure)
+	}
+}
+
+func watchBlocks(ctx *cli.Context) error {
+	client, err := dialEndpoint(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	blocks := make(chan *chainTypes.Block)
+	sub, err := client.SubscribeNewBlocks(context.Background(), blocks)
+	if err != nil {
+		return err
+	}
+	return streamEvents(sub, blocks)
+}
+
+func watchAddress(ctx *cli.Context) error {
+	address, err := parseAddress(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	client, err := dialEndpoint(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	events := make(chan *chainTypes.AccountEvent)
+	sub, err := client.SubscribeAccount(context.Background(), address, events)
+	if err != nil {
+		return err
+	}
+	return streamEvents(sub, events)
+}
+
+// dialEndpoint connects to the endpoint given by the endpoint flag. Watchers run
+// for a long time, the client reconnects in the background and resubscribes.
+func dialEndpoint(ctx *cli.Context) (*rpcComponent.Client, error) {
+	endpoint := ctx.String(cliTypes.EndpointFlag.Name)
+	if endpoint == "" {
+		return nil, fmt.Errorf("You have to specify an endpoint with --%s", cliTypes.EndpointFlag.Name)
+	}
+	client, err := rpcComponent.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to attach to remote drep: %v", err)
+	}
+	client.SetReconnectPolicy(&rpcComponent.DefaultReconnectPolicy)
+	return client, nil
+}
+
+// parseAddress parses a hex encoded account address, with or without 0x prefix.
+func parseAddress(s string) (crypto.CommonAddress, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != crypto.AddressLength {
+		return crypto.CommonAddress{}, fmt.Errorf("invalid address %q", s)
+	}
+	return crypto.Bytes2Address(b), nil
+}
+
+// streamEvents writes every event received on channel as a JSON line to stdout
+// until the subscription fails or the process is interrupted.
+func streamEvents(sub *rpcComponent.ClientSubscription, channel interface{}) error {
+	defer sub.Unsubscribe()
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	encoder := json.NewEncoder(os.Stdout)
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Err())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Gap())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sigc)},
+	}
+	for {
+		chosen, recv, _ := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			if err := encoder.Encode(recv.Interface()); err != nil {
+				return err
+			}
+		case 1:
+			if err, _ := recv.Interface().(error); err != nil {
+				return err
+			}
+			return nil
+		case 2:
+			fmt.Fprintln(os.Stderr, "connection restored, events may have been missed")
+		default:
+			return nil
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/drepclient/component/console"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -68,10 +69,53 @@ var (
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
 	}
+	HistorySizeFlag = cli.IntFlag{
+		Name:  "historysize",
+		Usage: "Number of console commands kept in the history of each endpoint",
+		Value: console.DefaultHistorySize,
+	}
+	TimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit of each console statement, e.g. 30s (0 for no limit)",
+	}
+	MaxOutputFlag = cli.IntFlag{
+		Name:  "maxoutput",
+		Usage: "Size in bytes after which results printed by the interactive console are cut (0 for no limit)",
+		Value: 1 << 20,
+	}
 	PreloadJSFlag = cli.StringFlag{
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
 	}
+	EndpointStrategyFlag = cli.StringFlag{
+		Name:  "strategy",
+		Usage: "Endpoint selection when attaching to several nodes (failover, round-robin, lowest-latency)",
+		Value: "failover",
+	}
+	RPCCacheFlag = cli.BoolFlag{
+		Name:  "rpccache",
+		Usage: "Cache the results of immutable chain queries in memory and below the data directory",
+	}
+	RecordFlag = cli.StringFlag{
+		Name:  "record",
+		Usage: "Record every RPC call, response and subscription notification of the session to a file",
+	}
+	ReplayFlag = cli.StringFlag{
+		Name:  "replay",
+		Usage: "Serve the RPC responses recorded in a file instead of attaching to a node",
+	}
+	ReplayRealtimeFlag = cli.BoolFlag{
+		Name:  "replaytiming",
+		Usage: "Delay replayed responses and notifications as they were when recorded",
+	}
+	EndpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "RPC endpoint (http, ws or ipc) of the drep node",
+	}
+	OutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Output format of results: pretty, json, table or yaml (default pretty in the console, json for the API commands)",
+	}
 )
 
 // MigrateFlags sets the global flag from a local flag when it's set.
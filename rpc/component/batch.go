@@ -0,0 +1,126 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+)
+
+// Batch collects calls which are sent to the server as a single batch request.
+// Every call decodes its result into the value given when it was added.
+//
+//	batch := client.NewBatch()
+//	var height uint64
+//	var balance common.Big
+//	batch.MaxHeight(&height)
+//	batch.Balance(address, chainId, &balance)
+//	if err := batch.Send(ctx); err != nil {
+//		// some or all calls failed
+//	}
+type Batch struct {
+	client *Client
+	elems  []BatchElem
+}
+
+// NewBatch creates an empty batch sent through the client.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// Call adds a call of method, its result is decoded into result. It returns the
+// position of the call in the batch.
+func (b *Batch) Call(result interface{}, method string, args ...interface{}) int {
+	b.elems = append(b.elems, BatchElem{Method: method, Args: args, Result: result})
+	return len(b.elems) - 1
+}
+
+// Len returns the number of calls in the batch.
+func (b *Batch) Len() int {
+	return len(b.elems)
+}
+
+// Send sends all calls as one batch request and waits for their results. The
+// error is a *BatchError if the request went through but some of the calls
+// failed, the results of the other calls are valid then.
+func (b *Batch) Send(ctx context.Context) error {
+	if len(b.elems) == 0 {
+		return nil
+	}
+	if err := b.client.BatchCallContext(ctx, b.elems); err != nil {
+		return err
+	}
+	failed := &BatchError{Methods: make([]string, len(b.elems)), Errors: make([]error, len(b.elems))}
+	for i, elem := range b.elems {
+		failed.Methods[i] = elem.Method
+		if elem.Error != nil {
+			failed.Errors[i] = elem.Error
+			failed.Failed++
+		}
+	}
+	if failed.Failed > 0 {
+		return failed
+	}
+	return nil
+}
+
+// Err returns the error of the call at position i after the batch was sent.
+func (b *Batch) Err(i int) error {
+	return b.elems[i].Error
+}
+
+// BatchError is returned by Batch.Send when some of the calls failed.
+type BatchError struct {
+	Methods []string // methods of all calls, in batch order
+	Errors  []error  // errors of all calls, nil for the successful ones
+	Failed  int      // number of failed calls
+}
+
+func (e *BatchError) Error() string {
+	var msgs []string
+	for i, err := range e.Errors {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("#%d %s: %v", i, e.Methods[i], err))
+		}
+	}
+	return fmt.Sprintf("%d of %d batch calls failed: %s", e.Failed, len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// MaxHeight adds a db_getMaxHeight call, retrieving the height of the chain head.
+func (b *Batch) MaxHeight(height *uint64) int {
+	return b.Call(height, "db_getMaxHeight")
+}
+
+// Block adds a db_getBlock call, retrieving the block at the given height.
+func (b *Batch) Block(height uint64, block *chainTypes.Block) int {
+	return b.Call(block, "db_getBlock", height)
+}
+
+// Balance adds a db_getBalance call, retrieving the balance of the account.
+func (b *Batch) Balance(address crypto.CommonAddress, chainId common.ChainIdType, balance *common.Big) int {
+	return b.Call(balance, "db_getBalance", address, chainId)
+}
+
+// Nonce adds a db_getNonce call, retrieving the nonce of the account.
+func (b *Batch) Nonce(address crypto.CommonAddress, chainId common.ChainIdType, nonce *uint64) int {
+	return b.Call(nonce, "db_getNonce", address, chainId)
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/crypto"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// DBTestService answers the db queries offered by Batch.
+type DBTestService struct{}
+
+func (s *DBTestService) GetMaxHeight() uint64 {
+	return 42
+}
+
+func (s *DBTestService) GetBlock(height uint64) (*chainTypes.Block, error) {
+	if height > 42 {
+		return nil, errors.New("block not found")
+	}
+	return &chainTypes.Block{Header: &chainTypes.BlockHeader{Height: height}}, nil
+}
+
+func (s *DBTestService) GetBalance(address crypto.CommonAddress, chainId common.ChainIdType) *common.Big {
+	return (*common.Big)(big.NewInt(1000))
+}
+
+func (s *DBTestService) GetNonce(address crypto.CommonAddress, chainId common.ChainIdType) uint64 {
+	return 7
+}
+
+func TestBatch(t *testing.T) {
+	server := newTestServer("db", new(DBTestService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var (
+		batch   = client.NewBatch()
+		height  uint64
+		block   chainTypes.Block
+		balance common.Big
+		nonce   uint64
+	)
+	batch.MaxHeight(&height)
+	batch.Block(3, &block)
+	batch.Balance(crypto.CommonAddress{}, common.ChainIdType{}, &balance)
+	batch.Nonce(crypto.CommonAddress{}, common.ChainIdType{}, &nonce)
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if height != 42 || block.Header.Height != 3 || (*big.Int)(&balance).Int64() != 1000 || nonce != 7 {
+		t.Errorf("wrong results: height %d, block %d, balance %v, nonce %d", height, block.Header.Height, (*big.Int)(&balance), nonce)
+	}
+}
+
+func TestBatchPartialFailure(t *testing.T) {
+	server := newTestServer("db", new(DBTestService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var (
+		batch  = client.NewBatch()
+		height uint64
+		block  chainTypes.Block
+		result int
+	)
+	batch.MaxHeight(&height)
+	missing := batch.Block(100, &block)
+	unknown := batch.Call(&result, "db_noSuchMethod")
+
+	err := batch.Send(context.Background())
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("expected *BatchError, got %v", err)
+	}
+	if batchErr.Failed != 2 || batch.Err(0) != nil {
+		t.Errorf("wrong failures: %v", batchErr)
+	}
+	if height != 42 {
+		t.Errorf("result of the successful call is %d, want 42", height)
+	}
+	if batch.Err(missing) == nil || batch.Err(missing).Error() != "block not found" {
+		t.Errorf("wrong error of failed call: %v", batch.Err(missing))
+	}
+	if err, ok := batch.Err(unknown).(rpcTypes.Error); !ok || err.ErrorCode() != -32601 {
+		t.Errorf("expected method not found error, got %v", batch.Err(unknown))
+	}
+	want := []string{"db_getMaxHeight", "db_getBlock", "db_noSuchMethod"}
+	if !reflect.DeepEqual(batchErr.Methods, want) {
+		t.Errorf("wrong methods: %v", batchErr.Methods)
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drep-project/drepcli/common"
+	"github.com/drep-project/drepcli/log"
+)
+
+// CachePolicy declares whether and how long the result of a method may be cached.
+type CachePolicy struct {
+	// BlockParam is the index of the block number parameter. Results for fixed
+	// heights never change, results for "latest" are cached until the chain
+	// height advances and results for "pending" are never cached. A negative
+	// index declares the result immutable for any params.
+	BlockParam int
+	// Range declares that the parameter after BlockParam is the number of
+	// blocks queried from that height on, all of which must be final.
+	Range bool
+}
+
+// DefaultCachePolicies lists the chain queries whose results are immutable once
+// the queried height is final. Account state queries have no block parameter,
+// they always return the state at the head and are never cached.
+var DefaultCachePolicies = map[string]CachePolicy{
+	"db_getBlock":      {BlockParam: 0},
+	"db_getBlocksFrom": {BlockParam: 0, Range: true},
+}
+
+// CacheConfig configures a response cache.
+type CacheConfig struct {
+	MaxEntries    int           // results kept in memory, least recently used ones are evicted
+	MaxEntryBytes int           // results larger than this are never cached, zero means no limit
+	Dir           string        // directory of the on-disk cache, empty disables it
+	MaxDiskBytes  int64         // size bound of the on-disk cache, zero means no limit
+	LatestTTL     time.Duration // lifetime of results for "latest" queries
+	Confirmations uint64        // heights are final once this many blocks are on top of them
+	Policies      map[string]CachePolicy
+}
+
+// DefaultCacheConfig keeps a few thousand results in memory only.
+var DefaultCacheConfig = CacheConfig{
+	MaxEntries:    4096,
+	MaxEntryBytes: 1 << 20,
+	MaxDiskBytes:  256 << 20,
+	LatestTTL:     5 * time.Second,
+	Confirmations: 6,
+	Policies:      DefaultCachePolicies,
+}
+
+type cacheEntry struct {
+	key     string
+	result  json.RawMessage
+	latest  bool      // result of a "latest" query
+	height  uint64    // chain height when a latest result was fetched
+	fetched time.Time // fetch time of a latest result
+}
+
+type diskEntry struct {
+	name string
+	size int64
+}
+
+// Cache is an LRU cache of call results keyed by method and params, optionally
+// backed by a directory. A cache must only be shared by clients of the same chain.
+type Cache struct {
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key => *cacheEntry element of lru
+	lru     *list.List               // front is most recently used
+	head    uint64                   // highest chain height observed
+	seen    time.Time                // time the height was last observed
+	hits    uint64
+	misses  uint64
+
+	diskFiles map[string]*list.Element // file name => *diskEntry element of diskLRU
+	diskLRU   *list.List               // front is most recently written
+	diskSize  int64
+}
+
+// NewCache creates a response cache. If a directory is configured, it is
+// created if necessary and its current content is reused.
+func NewCache(config CacheConfig) (*Cache, error) {
+	if config.Policies == nil {
+		config.Policies = DefaultCachePolicies
+	}
+	cache := &Cache{
+		config:    config,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+		diskFiles: make(map[string]*list.Element),
+		diskLRU:   list.New(),
+	}
+	if config.Dir != "" {
+		if err := cache.openDir(); err != nil {
+			return nil, err
+		}
+	}
+	return cache, nil
+}
+
+// openDir indexes the files of an existing cache directory, oldest last.
+func (cache *Cache) openDir() error {
+	if err := os.MkdirAll(cache.config.Dir, 0700); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(cache.config.Dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().After(files[j].ModTime()) })
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		entry := &diskEntry{name: file.Name(), size: file.Size()}
+		cache.diskFiles[entry.name] = cache.diskLRU.PushBack(entry)
+		cache.diskSize += entry.size
+	}
+	cache.shrinkDisk()
+	return nil
+}
+
+// SetCache makes the client answer calls of cacheable methods from cache,
+// nil disables caching.
+func (c *Client) SetCache(cache *Cache) {
+	c.cacheMu.Lock()
+	c.cache = cache
+	c.cacheMu.Unlock()
+}
+
+func (c *Client) responseCache() *Cache {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	return c.cache
+}
+
+// Stats returns the number of cache hits and misses of cacheable calls.
+func (cache *Cache) Stats() (hits, misses uint64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.hits, cache.misses
+}
+
+// Len returns the number of results held in memory.
+func (cache *Cache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.lru.Len()
+}
+
+// Purge drops all results, including the on-disk ones.
+func (cache *Cache) Purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.entries = make(map[string]*list.Element)
+	cache.lru.Init()
+	for name := range cache.diskFiles {
+		os.Remove(filepath.Join(cache.config.Dir, name))
+	}
+	cache.diskFiles = make(map[string]*list.Element)
+	cache.diskLRU.Init()
+	cache.diskSize = 0
+}
+
+// ObserveHeight tells the cache about the current chain height. Results of
+// "latest" queries fetched at a lower height are dropped. Clients observe the
+// height automatically from db_getMaxHeight results.
+func (cache *Cache) ObserveHeight(height uint64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.seen = time.Now()
+	if height <= cache.head {
+		return
+	}
+	cache.head = height
+	for key, elem := range cache.entries {
+		if entry := elem.Value.(*cacheEntry); entry.latest && entry.height < height {
+			cache.lru.Remove(elem)
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// classify returns whether a call may be cached and whether its result refers
+// to the latest block. Results at fixed heights may only be cached once the
+// returned height is final.
+func (cache *Cache) classify(method string, params json.RawMessage) (cacheable, latest bool, height uint64) {
+	policy, ok := cache.config.Policies[method]
+	if !ok {
+		return false, false, 0
+	}
+	if policy.BlockParam < 0 {
+		return true, false, 0
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || policy.BlockParam >= len(args) {
+		return false, false, 0
+	}
+	number, err := parseBlockNumber(args[policy.BlockParam])
+	if err != nil {
+		return false, false, 0
+	}
+	switch number {
+	case common.PendingBlockNumber:
+		return false, false, 0
+	case common.LatestBlockNumber:
+		return true, true, 0
+	}
+	height = uint64(number)
+	if policy.Range {
+		var size uint64
+		if policy.BlockParam+1 >= len(args) || json.Unmarshal(args[policy.BlockParam+1], &size) != nil {
+			return false, false, 0
+		}
+		if size > 0 {
+			height += size - 1
+		}
+	}
+	return true, false, height
+}
+
+// final reports whether the given height has enough confirmations. The chain
+// height is fetched if the known one is too low and older than LatestTTL.
+func (cache *Cache) final(height uint64, fetch func(method string, params json.RawMessage) (json.RawMessage, error)) bool {
+	cache.mu.Lock()
+	head, seen := cache.head, cache.seen
+	cache.mu.Unlock()
+
+	if height+cache.config.Confirmations <= head {
+		return true
+	}
+	if !seen.IsZero() && time.Since(seen) < cache.config.LatestTTL {
+		return false
+	}
+	result, err := fetch(HealthCheckMethod, json.RawMessage("[]"))
+	if err != nil {
+		return false
+	}
+	head, err = parseHeight(result)
+	if err != nil {
+		return false
+	}
+	cache.ObserveHeight(head)
+	return height+cache.config.Confirmations <= head
+}
+
+// parseBlockNumber accepts plain numbers besides the encodings of common.BlockNumber.
+func parseBlockNumber(raw json.RawMessage) (common.BlockNumber, error) {
+	var height uint64
+	if err := json.Unmarshal(raw, &height); err == nil && height <= math.MaxInt64 {
+		return common.BlockNumber(height), nil
+	}
+	var number common.BlockNumber
+	err := json.Unmarshal(raw, &number)
+	return number, err
+}
+
+// call answers the call from cache or performs it through fetch, which is
+// also used to fetch the chain height.
+func (cache *Cache) call(method string, params json.RawMessage, fetch func(method string, params json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	cacheable, latest, height := cache.classify(method, params)
+	if cacheable && !latest && cache.config.Policies[method].BlockParam >= 0 {
+		cacheable = cache.final(height, fetch)
+	}
+	if !cacheable {
+		result, err := fetch(method, params)
+		if err == nil && method == HealthCheckMethod {
+			if height, err := parseHeight(result); err == nil {
+				cache.ObserveHeight(height)
+			}
+		}
+		return result, err
+	}
+	key := method + string(params)
+	if result, ok := cache.get(key, latest); ok {
+		return result, nil
+	}
+	result, err := fetch(method, params)
+	if err != nil {
+		return nil, err
+	}
+	cache.put(key, result, latest)
+	return result, nil
+}
+
+func (cache *Cache) get(key string, latest bool) (json.RawMessage, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if !entry.latest || time.Since(entry.fetched) < cache.config.LatestTTL {
+			cache.lru.MoveToFront(elem)
+			cache.hits++
+			return entry.result, true
+		}
+		cache.lru.Remove(elem)
+		delete(cache.entries, key)
+	}
+	if !latest && cache.config.Dir != "" {
+		if result, ok := cache.readDisk(key); ok {
+			cache.insert(&cacheEntry{key: key, result: result})
+			cache.hits++
+			return result, true
+		}
+	}
+	cache.misses++
+	return nil, false
+}
+
+func (cache *Cache) put(key string, result json.RawMessage, latest bool) {
+	// null usually means the queried object doesn't exist (yet)
+	if bytes.Equal(bytes.TrimSpace(result), []byte("null")) {
+		return
+	}
+	if cache.config.MaxEntryBytes > 0 && len(result) > cache.config.MaxEntryBytes {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry := &cacheEntry{key: key, result: result, latest: latest}
+	if latest {
+		entry.height, entry.fetched = cache.head, time.Now()
+	} else if cache.config.Dir != "" {
+		cache.writeDisk(key, result)
+	}
+	cache.insert(entry)
+}
+
+// insert adds the entry to the memory cache, evicting the least recently used ones.
+func (cache *Cache) insert(entry *cacheEntry) {
+	if elem, ok := cache.entries[entry.key]; ok {
+		cache.lru.Remove(elem)
+	}
+	cache.entries[entry.key] = cache.lru.PushFront(entry)
+	for cache.config.MaxEntries > 0 && cache.lru.Len() > cache.config.MaxEntries {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// CacheDir returns the directory below root caching the results of the given
+// endpoints, so that caches of different chains don't mix.
+func CacheDir(root string, endpoints []string) string {
+	sorted := append([]string{}, endpoints...)
+	sort.Strings(sorted)
+	hash := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return filepath.Join(root, "rpccache", hex.EncodeToString(hash[:8]))
+}
+
+func diskName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func (cache *Cache) readDisk(key string) (json.RawMessage, bool) {
+	name := diskName(key)
+	if _, ok := cache.diskFiles[name]; !ok {
+		return nil, false
+	}
+	content, err := ioutil.ReadFile(filepath.Join(cache.config.Dir, name))
+	if err != nil {
+		cache.removeDisk(name)
+		return nil, false
+	}
+	// the key is stored in front of the result to rule out hash collisions
+	if !bytes.HasPrefix(content, []byte(key+"\n")) {
+		return nil, false
+	}
+	return json.RawMessage(content[len(key)+1:]), true
+}
+
+func (cache *Cache) writeDisk(key string, result json.RawMessage) {
+	name := diskName(key)
+	content := append([]byte(key+"\n"), result...)
+	if err := ioutil.WriteFile(filepath.Join(cache.config.Dir, name), content, 0600); err != nil {
+		log.Debug("can't write cache file", "err", err)
+		return
+	}
+	cache.removeDiskIndex(name)
+	entry := &diskEntry{name: name, size: int64(len(content))}
+	cache.diskFiles[name] = cache.diskLRU.PushFront(entry)
+	cache.diskSize += entry.size
+	cache.shrinkDisk()
+}
+
+// shrinkDisk removes the oldest files until the directory fits into its bound.
+func (cache *Cache) shrinkDisk() {
+	for cache.config.MaxDiskBytes > 0 && cache.diskSize > cache.config.MaxDiskBytes {
+		oldest := cache.diskLRU.Back()
+		if oldest == nil {
+			return
+		}
+		cache.removeDisk(oldest.Value.(*diskEntry).name)
+	}
+}
+
+func (cache *Cache) removeDisk(name string) {
+	os.Remove(filepath.Join(cache.config.Dir, name))
+	cache.removeDiskIndex(name)
+}
+
+func (cache *Cache) removeDiskIndex(name string) {
+	if elem, ok := cache.diskFiles[name]; ok {
+		cache.diskSize -= elem.Value.(*diskEntry).size
+		cache.diskLRU.Remove(elem)
+		delete(cache.diskFiles, name)
+	}
+}
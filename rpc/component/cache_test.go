@@ -0,0 +1,227 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// CacheTestService counts the calls which reach the server.
+type CacheTestService struct {
+	calls  int32
+	height uint64
+}
+
+func (s *CacheTestService) GetBlock(number interface{}) map[string]interface{} {
+	return map[string]interface{}{"number": number, "call": atomic.AddInt32(&s.calls, 1)}
+}
+
+func (s *CacheTestService) GetBlocksFrom(start, size uint64) map[string]interface{} {
+	return map[string]interface{}{"start": start, "call": atomic.AddInt32(&s.calls, 1)}
+}
+
+func (s *CacheTestService) GetBalance(addr string) int32 {
+	return atomic.AddInt32(&s.calls, 1)
+}
+
+func (s *CacheTestService) GetMaxHeight() uint64 {
+	return atomic.LoadUint64(&s.height)
+}
+
+func (s *CacheTestService) GetPeers() int32 {
+	return atomic.AddInt32(&s.calls, 1)
+}
+
+func newCacheTestClient(t *testing.T, config CacheConfig) (*Client, *CacheTestService, *Cache) {
+	service := &CacheTestService{height: 10}
+	server := rpcTypes.NewServer()
+	if err := server.RegisterName("db", service); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewCache(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	client.SetCache(cache)
+	return client, service, cache
+}
+
+func getBlockCall(t *testing.T, client *Client, number interface{}) float64 {
+	var block map[string]interface{}
+	if err := client.Call(&block, "db_getBlock", number); err != nil {
+		t.Fatal(err)
+	}
+	return block["call"].(float64)
+}
+
+func TestCacheFixedHeight(t *testing.T) {
+	client, service, cache := newCacheTestClient(t, DefaultCacheConfig)
+	defer client.Close()
+
+	first := getBlockCall(t, client, 3)
+	if again := getBlockCall(t, client, 3); again != first {
+		t.Errorf("block 3 fetched twice")
+	}
+	getBlockCall(t, client, "0x4")
+	if getBlockCall(t, client, "pending") == getBlockCall(t, client, "pending") {
+		t.Errorf("pending block served from cache")
+	}
+	var peers int32
+	client.Call(&peers, "db_getPeers")
+	client.Call(&peers, "db_getPeers")
+	if calls := atomic.LoadInt32(&service.calls); calls != 6 {
+		t.Errorf("server got %d calls, want 6", calls)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("got %d hits and %d misses, want 1 and 2", hits, misses)
+	}
+}
+
+func TestCacheConfirmations(t *testing.T) {
+	client, service, _ := newCacheTestClient(t, DefaultCacheConfig)
+	defer client.Close()
+
+	// the head is 10, so heights above 4 have less than 6 confirmations
+	if getBlockCall(t, client, 5) == getBlockCall(t, client, 5) {
+		t.Errorf("block without enough confirmations served from cache")
+	}
+	getBlocksFrom := func(start, size uint64) float64 {
+		var blocks map[string]interface{}
+		if err := client.Call(&blocks, "db_getBlocksFrom", start, size); err != nil {
+			t.Fatal(err)
+		}
+		return blocks["call"].(float64)
+	}
+	if getBlocksFrom(0, 5) != getBlocksFrom(0, 5) {
+		t.Errorf("final range fetched twice")
+	}
+	if getBlocksFrom(0, 11) == getBlocksFrom(0, 11) {
+		t.Errorf("range reaching the head served from cache")
+	}
+
+	atomic.StoreUint64(&service.height, 11)
+	var height uint64
+	if err := client.Call(&height, "db_getMaxHeight"); err != nil {
+		t.Fatal(err)
+	}
+	if getBlockCall(t, client, 5) != getBlockCall(t, client, 5) {
+		t.Errorf("block 5 not cached once final")
+	}
+}
+
+func TestCacheStateQuery(t *testing.T) {
+	client, _, cache := newCacheTestClient(t, DefaultCacheConfig)
+	defer client.Close()
+
+	var first, second int32
+	client.Call(&first, "db_getBalance", "0x00")
+	client.Call(&second, "db_getBalance", "0x00")
+	if first == second {
+		t.Errorf("balance served from cache")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("cache holds %d results, want none", cache.Len())
+	}
+}
+
+func TestCacheLatestInvalidation(t *testing.T) {
+	client, service, _ := newCacheTestClient(t, DefaultCacheConfig)
+	defer client.Close()
+
+	first := getBlockCall(t, client, "latest")
+	if getBlockCall(t, client, "latest") != first {
+		t.Fatalf("latest block not cached")
+	}
+	atomic.StoreUint64(&service.height, 11)
+	var height uint64
+	if err := client.Call(&height, "db_getMaxHeight"); err != nil {
+		t.Fatal(err)
+	}
+	if getBlockCall(t, client, "latest") == first {
+		t.Errorf("latest block still cached after the height advanced")
+	}
+}
+
+func TestCacheLatestTTL(t *testing.T) {
+	config := DefaultCacheConfig
+	config.LatestTTL = 20 * time.Millisecond
+	client, _, _ := newCacheTestClient(t, config)
+	defer client.Close()
+
+	first := getBlockCall(t, client, "latest")
+	time.Sleep(2 * config.LatestTTL)
+	if getBlockCall(t, client, "latest") == first {
+		t.Errorf("latest block served after its TTL")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	config := DefaultCacheConfig
+	config.MaxEntries = 2
+	client, _, cache := newCacheTestClient(t, config)
+	defer client.Close()
+
+	first := getBlockCall(t, client, 1)
+	getBlockCall(t, client, 2)
+	getBlockCall(t, client, 3)
+	if cache.Len() != 2 {
+		t.Errorf("cache holds %d results, want 2", cache.Len())
+	}
+	if getBlockCall(t, client, 1) == first {
+		t.Errorf("least recently used result not evicted")
+	}
+}
+
+func TestCacheDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drep-rpccache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := DefaultCacheConfig
+	config.Dir = dir
+	client, _, _ := newCacheTestClient(t, config)
+	first := getBlockCall(t, client, 4)
+	getBlockCall(t, client, "latest")
+	client.Close()
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("got %d cache files, want 1", len(files))
+	}
+	client, _, _ = newCacheTestClient(t, config)
+	defer client.Close()
+	if getBlockCall(t, client, 4) != first {
+		t.Errorf("block 4 not served from disk")
+	}
+
+	config.MaxDiskBytes = 1
+	if _, err := NewCache(config); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("disk cache not shrunk to its bound, %d files left", len(files))
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/drep-project/drepcli/log"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// cborSchemeSuffix selects the CBOR codec when appended to a transport URL scheme,
+// e.g. "ws+cbor://127.0.0.1:15645" or "ipc+cbor:///home/drep/drep.ipc".
+const cborSchemeSuffix = "+cbor"
+
+var errCBORDeclined = errors.New("server declined the CBOR codec")
+
+// DialCBOR creates a new client for the given URL which talks CBOR to the server.
+// The URL scheme is one of "http", "https", "ws", "wss", "tcp" or "ipc", optionally
+// with the "+cbor" suffix accepted by Dial. The client falls back to JSON if the
+// server doesn't support CBOR.
+func DialCBOR(ctx context.Context, rawurl string) (*Client, error) {
+	idx := strings.Index(rawurl, "://")
+	if idx < 0 {
+		return DialIPCCBOR(ctx, rawurl)
+	}
+	scheme := strings.TrimSuffix(rawurl[:idx], cborSchemeSuffix)
+	endpoint := scheme + rawurl[idx:]
+	switch scheme {
+	case "http", "https":
+		return DialHTTPCBOR(endpoint)
+	case "ws", "wss":
+		return DialWebsocketCBOR(ctx, endpoint, "")
+	case "tcp":
+		return DialTCPCBOR(ctx, rawurl[idx+len("://"):])
+	case "ipc":
+		return DialIPCCBOR(ctx, rawurl[idx+len("://"):])
+	default:
+		return nil, fmt.Errorf("no known transport for URL scheme %q", scheme)
+	}
+}
+
+// isCBORURL reports whether rawurl asks for the CBOR codec.
+func isCBORURL(rawurl string) bool {
+	idx := strings.Index(rawurl, "://")
+	return idx >= 0 && strings.HasSuffix(rawurl[:idx], cborSchemeSuffix)
+}
+
+// DialIPCCBOR connects to the IPC endpoint like DialIPC, using the CBOR codec.
+func DialIPCCBOR(ctx context.Context, endpoint string) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		return dialCBORStream(ctx, func(ctx context.Context) (net.Conn, error) {
+			return newIPCConnection(ctx, endpoint)
+		})
+	})
+}
+
+// DialWebsocketCBOR connects to the websocket endpoint like DialWebsocket, using the
+// CBOR codec.
+func DialWebsocketCBOR(ctx context.Context, endpoint, origin string) (*Client, error) {
+	config, err := wsGetConfig(endpoint, origin)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		return wsDialCBOR(ctx, *config)
+	})
+}
+
+// dialCBORStream opens a stream connection and asks the server for the CBOR codec.
+// Servers without CBOR support are spoken to in JSON on a fresh connection.
+func dialCBORStream(ctx context.Context, dial func(context.Context) (net.Conn, error)) (net.Conn, error) {
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := requestCBORStream(ctx, conn); err != nil {
+		conn.Close()
+		log.Debug("CBOR codec negotiation failed, falling back to JSON", "err", err)
+		return dial(ctx)
+	}
+	return &cborConn{
+		Conn: conn,
+		readFrame: func() ([]byte, error) {
+			return rpcTypes.ReadCBORFrame(conn, rpcTypes.MaxCBORFrameSize)
+		},
+		writeFrame: func(payload []byte) error {
+			return rpcTypes.WriteCBORFrame(conn, payload)
+		},
+	}, nil
+}
+
+// requestCBORStream sends the CBOR preamble and waits for the server to echo it.
+func requestCBORStream(ctx context.Context, conn net.Conn) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultDialTimeout)
+	}
+	conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write(rpcTypes.CBORPreamble); err != nil {
+		return err
+	}
+	ack := make([]byte, len(rpcTypes.CBORPreamble))
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		return err
+	}
+	if !bytes.Equal(ack, rpcTypes.CBORPreamble) {
+		return errCBORDeclined
+	}
+	return nil
+}
+
+// wsDialCBOR offers the codec subprotocols to the server. Servers which don't know
+// them either ignore the offer or fail the handshake, both lead to a JSON connection.
+func wsDialCBOR(ctx context.Context, config websocket.Config) (net.Conn, error) {
+	plain := config
+	config.Protocol = []string{rpcTypes.CBORSubprotocol, rpcTypes.JSONSubprotocol}
+	ws, err := wsDialContext(ctx, &config)
+	if err != nil {
+		log.Debug("CBOR codec negotiation failed, falling back to JSON", "err", err)
+		return wsDialContext(ctx, &plain)
+	}
+	if protocols := ws.Config().Protocol; len(protocols) != 1 || protocols[0] != rpcTypes.CBORSubprotocol {
+		return ws, nil
+	}
+	ws.MaxPayloadBytes = rpcTypes.MaxCBORFrameSize
+	return &cborConn{
+		Conn: ws,
+		readFrame: func() ([]byte, error) {
+			var msg []byte
+			err := websocket.Message.Receive(ws, &msg)
+			return msg, err
+		},
+		writeFrame: func(payload []byte) error {
+			return websocket.Message.Send(ws, payload)
+		},
+	}, nil
+}
+
+// cborConn is a connection carrying CBOR messages. The client writes and reads
+// its messages through writeMessage and readMessages instead of Write and Read.
+type cborConn struct {
+	net.Conn
+	readFrame  func() ([]byte, error)
+	writeFrame func(payload []byte) error
+}
+
+// readMessages decodes the next frame, a message or a batch of them.
+func (c *cborConn) readMessages() ([]*rpcTypes.JsonrpcMessage, error) {
+	frame, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	return decodeCBORMessages(frame)
+}
+
+func (c *cborConn) writeMessage(msg interface{}) error {
+	payload, err := rpcTypes.MarshalCBOR(msg)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(payload)
+}
+
+// decodeCBORMessages decodes a CBOR encoded message or batch of messages.
+func decodeCBORMessages(data []byte) (msgs []*rpcTypes.JsonrpcMessage, err error) {
+	if rpcTypes.IsCBORBatch(data) {
+		err = rpcTypes.UnmarshalCBOR(data, &msgs)
+	} else {
+		msgs = make([]*rpcTypes.JsonrpcMessage, 1)
+		err = rpcTypes.UnmarshalCBOR(data, &msgs[0])
+	}
+	return msgs, err
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+func TestClientCBOR(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	for _, transport := range []string{"ipc", "tcp", "ws", "http"} {
+		url, stop := startCBORTestServer(t, server, transport, false)
+		defer stop()
+
+		client, err := Dial(url)
+		if err != nil {
+			t.Fatalf("%s: can't dial: %v", transport, err)
+		}
+		defer client.Close()
+
+		var resp Result
+		if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if !reflect.DeepEqual(resp, Result{"hello", 10, &Args{"world"}}) {
+			t.Errorf("%s: incorrect result %#v", transport, resp)
+		}
+		if !usesCBOR(client) {
+			t.Errorf("%s: client didn't negotiate CBOR", transport)
+		}
+	}
+}
+
+func TestClientCBORFallback(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	for _, transport := range []string{"ipc", "ws", "http"} {
+		url, stop := startCBORTestServer(t, server, transport, true)
+		defer stop()
+
+		client, err := Dial(url)
+		if err != nil {
+			t.Fatalf("%s: can't dial: %v", transport, err)
+		}
+		defer client.Close()
+
+		var resp Result
+		if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if !reflect.DeepEqual(resp, Result{"hello", 10, &Args{"world"}}) {
+			t.Errorf("%s: incorrect result %#v", transport, resp)
+		}
+		if usesCBOR(client) {
+			t.Errorf("%s: client uses CBOR with a JSON-only server", transport)
+		}
+	}
+}
+
+func TestClientSubscribeCBOR(t *testing.T) {
+	server := newTestServer("eth", new(NotificationTestService))
+	defer server.Stop()
+	url, stop := startCBORTestServer(t, server, "ws", false)
+	defer stop()
+
+	client, err := Dial(url)
+	if err != nil {
+		t.Fatal("can't dial:", err)
+	}
+	defer client.Close()
+
+	nc := make(chan int)
+	count := 10
+	sub, err := client.Subscribe(context.Background(), "eth", nc, "someSubscription", count, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	for i := 0; i < count; i++ {
+		select {
+		case val := <-nc:
+			if val != i {
+				t.Fatalf("value mismatch: got %d, want %d", val, i)
+			}
+		case err := <-sub.Err():
+			t.Fatal("subscription error:", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for notification", i)
+		}
+	}
+}
+
+// startCBORTestServer serves srv on the given transport and returns the CBOR URL
+// of the endpoint. Legacy servers only speak JSON like servers predating the
+// CBOR codec.
+func startCBORTestServer(t *testing.T, srv *rpcTypes.Server, transport string, legacy bool) (string, func()) {
+	switch transport {
+	case "ipc":
+		endpoint := fmt.Sprintf("%s/drep-test-cbor-%d-%d.ipc", os.TempDir(), os.Getpid(), rand.Int63())
+		l, err := IpcListen(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if legacy {
+			go func() {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					go srv.ServeCodec(rpcTypes.NewJSONCodec(conn), rpcTypes.OptionMethodInvocation)
+				}
+			}()
+		} else {
+			go srv.ServeListener(l)
+		}
+		return "ipc+cbor://" + endpoint, func() { l.Close() }
+
+	case "tcp":
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.ServeListener(l)
+		return "tcp+cbor://" + l.Addr().String(), func() { l.Close() }
+
+	case "ws":
+		handler := srv.WebsocketHandler([]string{"*"})
+		if legacy {
+			handler = websocket.Server{Handler: func(conn *websocket.Conn) {
+				srv.ServeCodec(rpcTypes.NewJSONCodec(conn), rpcTypes.OptionMethodInvocation|rpcTypes.OptionSubscriptions)
+			}}
+		}
+		hs := httptest.NewServer(handler)
+		return "ws+cbor://" + strings.TrimPrefix(hs.URL, "http://"), hs.Close
+
+	case "http":
+		var handler http.Handler = srv
+		if legacy {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if mt, _, _ := mime.ParseMediaType(r.Header.Get("content-type")); mt != rpcTypes.ContentType {
+					http.Error(w, "invalid content type", http.StatusUnsupportedMediaType)
+					return
+				}
+				srv.ServeHTTP(w, r)
+			})
+		}
+		hs := httptest.NewServer(handler)
+		return "http+cbor://" + strings.TrimPrefix(hs.URL, "http://"), hs.Close
+	}
+	panic("unknown transport: " + transport)
+}
+
+// usesCBOR reports whether the client's current connection uses the CBOR codec.
+func usesCBOR(c *Client) bool {
+	if hc, ok := c.writeConn.(*httpConn); ok {
+		return atomic.LoadInt32(&hc.cbor) == 1
+	}
+	_, ok := c.writeConn.(*cborConn)
+	return ok
+}
@@ -47,7 +47,7 @@ const (
 	tcpKeepAliveInterval = 30 * time.Second
 	defaultDialTimeout   = 10 * time.Second // used when dialing if the context has no deadline
 	defaultWriteTimeout  = 10 * time.Second // used for calls if the context has no deadline
-	subscribeTimeout     = 5 * time.Second  // overall timeout eth_subscribe, rpc_modules calls
+	subscribeTimeout     = 5 * time.Second  // overall timeout subscribe, rpc_modules calls
 )
 
 const (
@@ -82,6 +82,13 @@ type Client struct {
 	idCounter   uint32
 	connectFunc func(ctx context.Context) (net.Conn, error)
 	isHTTP      bool
+	pool        *endpointPool // set for clients spanning multiple endpoints
+
+	cacheMu sync.Mutex
+	cache   *Cache // answers calls of cacheable methods, nil if disabled
+
+	recorderMu sync.Mutex
+	recorder   *Recorder // records the session, nil if disabled
 
 	// writeConn is only safe to access outside dispatch, with the
 	// write lock held. The write lock is taken by sending on
@@ -99,13 +106,23 @@ type Client struct {
 	sendDone    chan error                      // signals write completion, releases write lock
 	respWait    map[string]*requestOp           // active requests
 	subs        map[string]*ClientSubscription  // active subscriptions
+
+	// for automatic reconnects
+	policyMu      sync.Mutex
+	policy        *ReconnectPolicy         // nil if reconnects are only attempted lazily
+	stopKeepAlive chan struct{}            // closed to stop the keepalive loop
+	redialDone    chan error               // signals the end of a background redial
+	suspend       chan *ClientSubscription // subscriptions waiting for the next connection
+	suspended     []*ClientSubscription    // subscriptions of a lost connection, owned by dispatch
 }
 
 type requestOp struct {
 	ids  []json.RawMessage
 	err  error
 	resp chan *rpcTypes.JsonrpcMessage // receives up to len(ids) responses
-	sub  *ClientSubscription           // only set for EthSubscribe requests
+	sub  *ClientSubscription           // only set for Subscribe requests
+
+	resubscribe bool // set if sub is replayed on a new connection
 }
 
 func (op *requestOp) wait(ctx context.Context) (*rpcTypes.JsonrpcMessage, error) {
@@ -119,14 +136,21 @@ func (op *requestOp) wait(ctx context.Context) (*rpcTypes.JsonrpcMessage, error)
 
 // Dial creates a new client for the given URL.
 //
-// The currently supported URL schemes are "http", "https", "ws" and "wss". If rawurl is a
-// file name with no URL scheme, a local socket connection is established using UNIX
-// domain sockets on supported platforms and named pipes on Windows. If you want to
-// configure transport options, use DialHTTP, DialWebsocket or DialIPC instead.
+// The currently supported URL schemes are "http", "https", "ws", "wss" and "tcp". If
+// rawurl is a file name with no URL scheme, a local socket connection is established
+// using UNIX domain sockets on supported platforms and named pipes on Windows. If you
+// want to configure transport options, use DialHTTP, DialWebsocket or DialIPC instead.
 //
 // For websocket connections, the origin is set to the local host name.
 //
-// The client reconnects automatically if the connection is lost.
+// Appending "+cbor" to the URL scheme selects the CBOR codec, see DialCBOR. Local
+// sockets use the "ipc+cbor" scheme then.
+//
+// rawurl may also be a comma separated list of endpoints, see DialEndpoints. Such
+// clients fail over between the endpoints using DefaultPoolConfig.
+//
+// The client reconnects lazily on the next call if the connection is lost. Use
+// SetReconnectPolicy to reconnect in the background and keep subscriptions alive.
 func Dial(rawurl string) (*Client, error) {
 	return DialContext(context.Background(), rawurl)
 }
@@ -136,6 +160,12 @@ func Dial(rawurl string) (*Client, error) {
 // The context is used to cancel or time out the initial connection establishment. It does
 // not affect subsequent interactions with the client.
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	if endpoints := SplitEndpoints(rawurl); len(endpoints) > 1 {
+		return DialEndpoints(ctx, endpoints, DefaultPoolConfig)
+	}
+	if isCBORURL(rawurl) {
+		return DialCBOR(ctx, rawurl)
+	}
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
@@ -145,6 +175,8 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 		return DialHTTP(rawurl)
 	case "ws", "wss":
 		return DialWebsocket(ctx, rawurl, "")
+	case "tcp":
+		return DialTCP(ctx, u.Host)
 	case "stdio":
 		return DialStdIO(ctx)
 	case "":
@@ -154,6 +186,19 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	}
 }
 
+// SplitEndpoints splits comma separated endpoint lists, dropping empty entries.
+func SplitEndpoints(endpoints ...string) []string {
+	var urls []string
+	for _, list := range endpoints {
+		for _, url := range strings.Split(list, ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
+
 func newClient(initctx context.Context, connectFunc func(context.Context) (net.Conn, error)) (*Client, error) {
 	conn, err := connectFunc(initctx)
 	if err != nil {
@@ -174,6 +219,8 @@ func newClient(initctx context.Context, connectFunc func(context.Context) (net.C
 		sendDone:    make(chan error, 1),
 		respWait:    make(map[string]*requestOp),
 		subs:        make(map[string]*ClientSubscription),
+		redialDone:  make(chan error),
+		suspend:     make(chan *ClientSubscription),
 	}
 	if !isHTTP {
 		go c.dispatch(conn)
@@ -198,6 +245,10 @@ func (c *Client) SupportedModules() (map[string]string, error) {
 
 // Close closes the client, aborting any in-flight requests.
 func (c *Client) Close() {
+	if c.pool != nil {
+		c.pool.close()
+		return
+	}
 	if c.isHTTP {
 		return
 	}
@@ -224,31 +275,89 @@ func (c *Client) Call(result interface{}, method string, args ...interface{}) er
 // The result must be a pointer so that package json can unmarshal into it. You
 // can also pass nil, in which case the result is ignored.
 func (c *Client) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if c.responseCache() != nil || c.sessionRecorder() != nil {
+		params := json.RawMessage("[]")
+		if len(args) > 0 {
+			var err error
+			if params, err = json.Marshal(args); err != nil {
+				return err
+			}
+		}
+		raw, err := c.CallRawContext(ctx, method, params)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(raw, &result)
+	}
+	if c.pool != nil {
+		return c.pool.callContext(ctx, result, method, args...)
+	}
 	msg, err := c.newMessage(method, args...)
 	if err != nil {
 		return err
 	}
+	raw, err := c.call(ctx, msg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, &result)
+}
+
+// CallRawContext performs a JSON-RPC call with already encoded params, which
+// may be a JSON array or object, and returns the undecoded result.
+func (c *Client) CallRawContext(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	if len(params) == 0 {
+		params = json.RawMessage("[]")
+	}
+	if rec := c.sessionRecorder(); rec != nil {
+		start := time.Now()
+		result, err := c.cachedCall(ctx, method, params)
+		rec.recordCall(method, params, result, err, start)
+		return result, err
+	}
+	return c.cachedCall(ctx, method, params)
+}
+
+func (c *Client) cachedCall(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	if cache := c.responseCache(); cache != nil {
+		return cache.call(method, params, func(method string, params json.RawMessage) (json.RawMessage, error) {
+			return c.callRaw(ctx, method, params)
+		})
+	}
+	return c.callRaw(ctx, method, params)
+}
+
+func (c *Client) callRaw(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	if c.pool != nil {
+		return c.pool.callRawContext(ctx, method, params)
+	}
+	return c.call(ctx, &rpcTypes.JsonrpcMessage{Version: "2.0", ID: c.nextID(), Method: method, Params: params})
+}
+
+// call sends msg and waits for its result.
+func (c *Client) call(ctx context.Context, msg *rpcTypes.JsonrpcMessage) (json.RawMessage, error) {
 	op := &requestOp{ids: []json.RawMessage{msg.ID}, resp: make(chan *rpcTypes.JsonrpcMessage, 1)}
 
+	var err error
 	if c.isHTTP {
 		err = c.sendHTTP(ctx, op, msg)
 	} else {
 		err = c.send(ctx, op, msg)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// dispatch has accepted the request and will close the channel when it quits.
 	switch resp, err := op.wait(ctx); {
 	case err != nil:
-		return err
+		return nil, err
 	case resp.Error != nil:
-		return resp.Error
+		return nil, resp.Error
 	case len(resp.Result) == 0:
-		return ErrNoResult
+		return nil, ErrNoResult
 	default:
-		return json.Unmarshal(resp.Result, &result)
+		return resp.Result, nil
 	}
 }
 
@@ -274,6 +383,16 @@ func (c *Client) BatchCall(b []BatchElem) error {
 //
 // Note that batch calls may not be executed atomically on the server side.
 func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
+	if rec := c.sessionRecorder(); rec != nil {
+		return rec.batchCall(ctx, b, c.batchCall)
+	}
+	return c.batchCall(ctx, b)
+}
+
+func (c *Client) batchCall(ctx context.Context, b []BatchElem) error {
+	if c.pool != nil {
+		return c.pool.batchCallContext(ctx, b)
+	}
 	msgs := make([]*rpcTypes.JsonrpcMessage, len(b))
 	op := &requestOp{
 		ids:  make([]json.RawMessage, len(b)),
@@ -325,16 +444,6 @@ func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
 	return err
 }
 
-// EthSubscribe registers a subscripion under the "eth" namespace.
-func (c *Client) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
-	return c.Subscribe(ctx, "eth", channel, args...)
-}
-
-// ShhSubscribe registers a subscripion under the "shh" namespace.
-func (c *Client) ShhSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
-	return c.Subscribe(ctx, "shh", channel, args...)
-}
-
 // Subscribe calls the "<namespace>_subscribe" method with the given arguments,
 // registering a subscription. Server notifications for the subscription are
 // sent to the given channel. The element type of the channel must match the
@@ -356,6 +465,26 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	if chanVal.IsNil() {
 		panic("channel given to Subscribe must not be nil")
 	}
+	rec := c.sessionRecorder()
+	if rec == nil {
+		return c.subscribe(ctx, namespace, chanVal, nil, args...)
+	}
+	start := time.Now()
+	sub, err := c.subscribe(ctx, namespace, chanVal, rec, args...)
+	var subid string
+	if err == nil {
+		subid = sub.ID()
+	}
+	rec.recordSubscribe(namespace, args, subid, err, start)
+	return sub, err
+}
+
+// subscribe creates the subscription, its notifications are recorded with rec
+// unless it is nil.
+func (c *Client) subscribe(ctx context.Context, namespace string, chanVal reflect.Value, rec *Recorder, args ...interface{}) (*ClientSubscription, error) {
+	if c.pool != nil {
+		return c.pool.subscribe(ctx, namespace, chanVal, rec, args...)
+	}
 	if c.isHTTP {
 		return nil, rpcTypes.ErrNotificationsUnsupported
 	}
@@ -367,8 +496,9 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	op := &requestOp{
 		ids:  []json.RawMessage{msg.ID},
 		resp: make(chan *rpcTypes.JsonrpcMessage),
-		sub:  newClientSubscription(c, namespace, chanVal),
+		sub:  newClientSubscription(c, namespace, chanVal, args),
 	}
+	op.sub.recorder = rec
 
 	// Send the subscription request.
 	// The arrival and validity of the response is signaled on sub.quit.
@@ -423,7 +553,12 @@ func (c *Client) write(ctx context.Context, msg interface{}) error {
 		}
 	}
 	c.writeConn.SetWriteDeadline(deadline)
-	err := json.NewEncoder(c.writeConn).Encode(msg)
+	var err error
+	if cc, ok := c.writeConn.(*cborConn); ok {
+		err = cc.writeMessage(msg)
+	} else {
+		err = json.NewEncoder(c.writeConn).Encode(msg)
+	}
 	c.writeConn.SetWriteDeadline(time.Time{})
 	if err != nil {
 		c.writeConn = nil
@@ -458,11 +593,16 @@ func (c *Client) dispatch(conn net.Conn) {
 		lastOp        *requestOp    // tracks last send operation
 		requestOpLock = c.requestOp // nil while the send lock is held
 		reading       = true        // if true, a read loop is running
+		reconnecting  = false       // if true, a background redial is running
 	)
 	defer close(c.didClose)
 	defer func() {
 		close(c.closing)
 		c.closeRequestOps(ErrClientQuit)
+		for _, sub := range c.suspended {
+			sub.quitWithError(ErrClientQuit, false)
+		}
+		c.suspended = nil
 		conn.Close()
 		if reading {
 			// Empty read channels until read is dead.
@@ -505,9 +645,17 @@ func (c *Client) dispatch(conn net.Conn) {
 
 		case err := <-c.readErr:
 			log.Debug("<-readErr", "err", err)
+			policy := c.reconnectPolicy()
+			if policy != nil {
+				c.suspendSubscriptions()
+			}
 			c.closeRequestOps(err)
 			conn.Close()
 			reading = false
+			if policy != nil && !reconnecting {
+				reconnecting = true
+				go c.redial(conn, policy)
+			}
 
 		case newconn := <-c.reconnected:
 			log.Debug("<-reconnected", "reading", reading, "remote", conn.RemoteAddr())
@@ -519,6 +667,37 @@ func (c *Client) dispatch(conn net.Conn) {
 			go c.read(newconn)
 			reading = true
 			conn = newconn
+			if len(c.suspended) > 0 {
+				go c.resubscribe(c.suspended)
+				c.suspended = nil
+			}
+
+		case err := <-c.redialDone:
+			reconnecting = false
+			if err != nil {
+				// The policy gave up, end the subscriptions of the lost connection.
+				for _, sub := range c.suspended {
+					sub.quitWithError(err, false)
+				}
+				c.suspended = nil
+			} else if policy := c.reconnectPolicy(); !reading && policy != nil {
+				// The connection was lost again while redialing.
+				reconnecting = true
+				go c.redial(conn, policy)
+			}
+
+		case sub := <-c.suspend:
+			c.suspended = append(c.suspended, sub)
+			if policy := c.reconnectPolicy(); reading && policy != nil {
+				// The connection is up but the subscription could not be replayed,
+				// try again after a short delay.
+				subs := c.suspended
+				c.suspended = nil
+				go func() {
+					time.Sleep(policy.MinBackoff)
+					c.resubscribe(subs)
+				}()
+			}
 
 		// Send path.
 		case op := <-requestOpLock:
@@ -596,16 +775,20 @@ func (c *Client) handleResponse(msg *rpcTypes.JsonrpcMessage) {
 		return
 	}
 	// For subscription responses, start the subscription if the server
-	// indicates success. EthSubscribe gets unblocked in either case through
+	// indicates success. Subscribe gets unblocked in either case through
 	// the op.resp channel.
 	defer close(op.resp)
 	if msg.Error != nil {
 		op.err = msg.Error
 		return
 	}
-	if op.err = json.Unmarshal(msg.Result, &op.sub.subid); op.err == nil {
-		go op.sub.start()
-		c.subs[op.sub.subid] = op.sub
+	var subid string
+	if op.err = json.Unmarshal(msg.Result, &subid); op.err == nil {
+		op.sub.setID(subid)
+		if !op.resubscribe {
+			go op.sub.start()
+		}
+		c.subs[subid] = op.sub
 	}
 }
 
@@ -629,6 +812,9 @@ func (c *Client) read(conn net.Conn) error {
 		}
 		return rs, err
 	}
+	if cc, ok := conn.(*cborConn); ok {
+		readMessage = cc.readMessages
+	}
 
 	for {
 		resp, err := readMessage()
@@ -642,14 +828,21 @@ func (c *Client) read(conn net.Conn) error {
 
 // Subscriptions.
 
-// A ClientSubscription represents a subscription established through EthSubscribe.
+// A ClientSubscription represents a subscription established through Subscribe.
 type ClientSubscription struct {
 	client    *Client
 	etype     reflect.Type
 	channel   reflect.Value
 	namespace string
-	subid     string
+	args      []interface{} // subscription arguments, replayed after a reconnect
 	in        chan json.RawMessage
+	gap       chan struct{}
+
+	idLock  sync.Mutex
+	subid   string
+	firstID string // id assigned by the first subscribe request
+
+	recorder *Recorder // records the notifications, nil if disabled
 
 	quitOnce sync.Once     // ensures quit is closed once
 	quit     chan struct{} // quit is closed when the subscription exits
@@ -657,15 +850,17 @@ type ClientSubscription struct {
 	err      chan error
 }
 
-func newClientSubscription(c *Client, namespace string, channel reflect.Value) *ClientSubscription {
+func newClientSubscription(c *Client, namespace string, channel reflect.Value, args []interface{}) *ClientSubscription {
 	sub := &ClientSubscription{
 		client:    c,
 		namespace: namespace,
+		args:      args,
 		etype:     channel.Type().Elem(),
 		channel:   channel,
 		quit:      make(chan struct{}),
 		err:       make(chan error, 1),
 		in:        make(chan json.RawMessage),
+		gap:       make(chan struct{}, 1),
 	}
 	return sub
 }
@@ -682,6 +877,31 @@ func (sub *ClientSubscription) Err() <-chan error {
 	return sub.err
 }
 
+// Gap returns a channel that receives a value whenever the subscription was
+// re-established after the connection had been lost. Notifications sent by the
+// server while the client was disconnected are missing from the subscription
+// channel. Gaps are only signaled for clients with a reconnect policy.
+func (sub *ClientSubscription) Gap() <-chan struct{} {
+	return sub.gap
+}
+
+// ID returns the subscription id assigned by the server. The id changes when
+// the subscription is re-established on a new connection.
+func (sub *ClientSubscription) ID() string {
+	sub.idLock.Lock()
+	defer sub.idLock.Unlock()
+	return sub.subid
+}
+
+func (sub *ClientSubscription) setID(subid string) {
+	sub.idLock.Lock()
+	sub.subid = subid
+	if sub.firstID == "" {
+		sub.firstID = subid
+	}
+	sub.idLock.Unlock()
+}
+
 // Unsubscribe unsubscribes the notification and closes the error channel.
 // It can safely be called more than once.
 func (sub *ClientSubscription) Unsubscribe() {
@@ -744,7 +964,16 @@ func (sub *ClientSubscription) forward() (err error, unsubscribeServer bool) {
 		case 0: // <-sub.quit
 			return nil, false
 		case 1: // <-sub.in
-			val, err := sub.unmarshal(recv.Interface().(json.RawMessage))
+			result := recv.Interface().(json.RawMessage)
+			if sub.recorder != nil {
+				// Notifications are recorded with the original id, a replay
+				// doesn't know about later resubscriptions.
+				sub.idLock.Lock()
+				subid := sub.firstID
+				sub.idLock.Unlock()
+				sub.recorder.recordNotification(subid, result)
+			}
+			val, err := sub.unmarshal(result)
 			if err != nil {
 				return err, true
 			}
@@ -767,7 +996,7 @@ func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, e
 
 func (sub *ClientSubscription) requestUnsubscribe() error {
 	var result interface{}
-	return sub.client.Call(&result, sub.namespace+rpcTypes.UnsubscribeMethodSuffix, sub.subid)
+	return sub.client.Call(&result, sub.namespace+rpcTypes.UnsubscribeMethodSuffix, sub.ID())
 }
 
 //"{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0xc6196f8d8165c7cbb5ffc3833d4caf0c92017c5d","latest"]}"
@@ -32,6 +32,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/drep-project/drepcli/log"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
 )
 
 func TestClientRequest(t *testing.T) {
@@ -45,7 +46,7 @@ func TestClientRequest(t *testing.T) {
 		t.Fatal(err)
 	}
 	if !reflect.DeepEqual(resp, Result{"hello", 10, &Args{"world"}}) {
-		t.Error("incorrect result %#v", resp)
+		t.Errorf("incorrect result %#v", resp)
 	}
 }
 
@@ -86,15 +87,12 @@ func TestClientBatchRequest(t *testing.T) {
 			Args:   []interface{}{"hello2", 11, &Args{"world"}},
 			Result: &Result{"hello2", 11, &Args{"world"}},
 		},
-		{
-			Method: "no_such_method",
-			Args:   []interface{}{1, 2, 3},
-			Result: new(int),
-			Error:  &jsonError{Code: -32601, Message: "The method no_such_method_ does not exist/is not available"},
-		},
 	}
-	if !reflect.DeepEqual(batch, wantResult) {
-		t.Error("batch results mismatch:\ngot %swant %s", spew.Sdump(batch), spew.Sdump(wantResult))
+	if !reflect.DeepEqual(batch[:2], wantResult) {
+		t.Errorf("batch results mismatch:\ngot %swant %s", spew.Sdump(batch[:2]), spew.Sdump(wantResult))
+	}
+	if err, ok := batch[2].Error.(rpcTypes.Error); !ok || err.ErrorCode() != -32601 {
+		t.Errorf("expected method not found error, got %v", batch[2].Error)
 	}
 }
 
@@ -102,6 +100,7 @@ func TestClientBatchRequest(t *testing.T) {
 func TestClientCancelWebsocket(t *testing.T) { testClientCancel("ws", t) }
 func TestClientCancelHTTP(t *testing.T)      { testClientCancel("http", t) }
 func TestClientCancelIPC(t *testing.T)       { testClientCancel("ipc", t) }
+func TestClientCancelTCP(t *testing.T)       { testClientCancel("tcp", t) }
 
 // This test checks that requests made through CallContext can be canceled by canceling
 // the context.
@@ -138,6 +137,10 @@ func testClientCancel(transport string, t *testing.T) {
 		c, l := ipcTestClient(server, fl)
 		defer l.Close()
 		client = c
+	case "tcp":
+		c, l := tcpTestClient(server, fl)
+		defer l.Close()
+		client = c
 	default:
 		panic("unknown transport: " + transport)
 	}
@@ -178,7 +181,7 @@ func testClientCancel(transport string, t *testing.T) {
 			if err != nil {
 				log.Debug(fmt.Sprint("got expected error:", err))
 			} else {
-				t.Error("no error for call with %v wait time", timeout)
+				t.Errorf("no error for call with %v wait time", timeout)
 			}
 			cancel()
 		}
@@ -200,17 +203,17 @@ func TestClientSubscribeInvalidArg(t *testing.T) {
 		defer func() {
 			err := recover()
 			if shouldPanic && err == nil {
-				t.Error("EthSubscribe should've panicked for %#v", arg)
+				t.Errorf("EthSubscribe should've panicked for %#v", arg)
 			}
 			if !shouldPanic && err != nil {
-				t.Error("EthSubscribe shouldn't have panicked for %#v", arg)
+				t.Errorf("EthSubscribe shouldn't have panicked for %#v", arg)
 				buf := make([]byte, 1024*1024)
 				buf = buf[:runtime.Stack(buf, false)]
 				t.Error(err)
 				t.Error(string(buf))
 			}
 		}()
-		client.EthSubscribe(context.Background(), arg, "foo_bar")
+		client.Subscribe(context.Background(), "eth", arg, "foo_bar")
 	}
 	check(true, nil)
 	check(true, 1)
@@ -228,7 +231,7 @@ func TestClientSubscribe(t *testing.T) {
 
 	nc := make(chan int)
 	count := 10
-	sub, err := client.EthSubscribe(context.Background(), nc, "someSubscription", count, 0)
+	sub, err := client.Subscribe(context.Background(), "eth", nc, "someSubscription", count, 0)
 	if err != nil {
 		t.Fatal("can't subscribe:", err)
 	}
@@ -302,7 +305,7 @@ func TestClientSubscribeClose(t *testing.T) {
 		err  error
 	)
 	go func() {
-		sub, err = client.EthSubscribe(context.Background(), nc, "hangSubscription", 999)
+		sub, err = client.Subscribe(context.Background(), "eth", nc, "hangSubscription", 999)
 		errc <- err
 	}()
 
@@ -333,7 +336,7 @@ func TestClientCloseUnsubscribeRace(t *testing.T) {
 	for i := 0; i < 20; i++ {
 		client := DialInProc(server)
 		nc := make(chan int)
-		sub, err := client.EthSubscribe(context.Background(), nc, "someSubscription", 3, 1)
+		sub, err := client.Subscribe(context.Background(), "eth", nc, "someSubscription", 3, 1)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -362,7 +365,7 @@ func TestClientNotificationStorm(t *testing.T) {
 		// Subscribe on the server. It will start sending many notifications
 		// very quickly.
 		nc := make(chan int)
-		sub, err := client.EthSubscribe(ctx, nc, "someSubscription", count, 0)
+		sub, err := client.Subscribe(ctx, "eth", nc, "someSubscription", count, 0)
 		if err != nil {
 			t.Fatal("can't subscribe:", err)
 		}
@@ -438,13 +441,29 @@ func TestClientHTTP(t *testing.T) {
 	// Check results.
 	for i := range results {
 		if !reflect.DeepEqual(results[i], wantResult) {
-			t.Error("result %d mismatch: got %#v, want %#v", i, results[i], wantResult)
+			t.Errorf("result %d mismatch: got %#v, want %#v", i, results[i], wantResult)
 		}
 	}
 }
 
+func TestClientTCP(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	client, l := tcpTestClient(server, nil)
+	defer l.Close()
+	defer client.Close()
+
+	var resp Result
+	if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, Result{"hello", 10, &Args{"world"}}) {
+		t.Errorf("incorrect result %#v", resp)
+	}
+}
+
 func TestClientReconnect(t *testing.T) {
-	startServer := func(addr string) (*Server, net.Listener) {
+	startServer := func(addr string) (*rpcTypes.Server, net.Listener) {
 		srv := newTestServer("service", new(Service))
 		l, err := net.Listen("tcp", addr)
 		if err != nil {
@@ -505,19 +524,19 @@ func TestClientReconnect(t *testing.T) {
 	}
 	t.Log("err:", err)
 	if errcount > 1 {
-		t.Error("expected one error after disconnect, got %d", errcount)
+		t.Errorf("expected one error after disconnect, got %d", errcount)
 	}
 }
 
-func newTestServer(serviceName string, service interface{}) *Server {
-	server := NewServer()
+func newTestServer(serviceName string, service interface{}) *rpcTypes.Server {
+	server := rpcTypes.NewServer()
 	if err := server.RegisterName(serviceName, service); err != nil {
 		panic(err)
 	}
 	return server
 }
 
-func httpTestClient(srv *Server, transport string, fl *flakeyListener) (*Client, *httptest.Server) {
+func httpTestClient(srv *rpcTypes.Server, transport string, fl *flakeyListener) (*Client, *httptest.Server) {
 	// Create the HTTP server.
 	var hs *httptest.Server
 	switch transport {
@@ -542,7 +561,7 @@ func httpTestClient(srv *Server, transport string, fl *flakeyListener) (*Client,
 	return client, hs
 }
 
-func ipcTestClient(srv *Server, fl *flakeyListener) (*Client, net.Listener) {
+func ipcTestClient(srv *rpcTypes.Server, fl *flakeyListener) (*Client, net.Listener) {
 	// Listen on a random endpoint.
 	endpoint := fmt.Sprintf("go-ethereum-test-ipc-%d-%d", os.Getpid(), rand.Int63())
 	if runtime.GOOS == "windows" {
@@ -550,7 +569,7 @@ func ipcTestClient(srv *Server, fl *flakeyListener) (*Client, net.Listener) {
 	} else {
 		endpoint = os.TempDir() + "/" + endpoint
 	}
-	l, err := ipcListen(endpoint)
+	l, err := IpcListen(endpoint)
 	if err != nil {
 		panic(err)
 	}
@@ -568,6 +587,26 @@ func ipcTestClient(srv *Server, fl *flakeyListener) (*Client, net.Listener) {
 	return client, l
 }
 
+func tcpTestClient(srv *rpcTypes.Server, fl *flakeyListener) (*Client, net.Listener) {
+	// Listen on a random port.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	// Connect the listener to the server.
+	if fl != nil {
+		fl.Listener = l
+		l = fl
+	}
+	go srv.ServeListener(l)
+	// Connect the client.
+	client, err := Dial("tcp://" + l.Addr().String())
+	if err != nil {
+		panic(err)
+	}
+	return client, l
+}
+
 // flakeyListener kills accepted connections after a random timeout.
 type flakeyListener struct {
 	net.Listener
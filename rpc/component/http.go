@@ -24,10 +24,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/drep-project/drepcli/log"
@@ -40,6 +42,8 @@ var nullAddr, _ = net.ResolveTCPAddr("tcp", "127.0.0.1:0")
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
+	cbor      int32       // set while requests are sent in CBOR, atomic
+	cborHdr   http.Header // request headers announcing CBOR
 	closeOnce sync.Once
 	closed    chan struct{}
 }
@@ -73,24 +77,41 @@ var DefaultHTTPTimeouts = rpcTypes.HTTPTimeouts{
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return dialHTTP(endpoint, client, false)
+}
+
+// DialHTTP creates a new RPC client that connects to an RPC server over HTTP.
+func DialHTTP(endpoint string) (*Client, error) {
+	return DialHTTPWithClient(endpoint, new(http.Client))
+}
+
+// DialHTTPCBOR creates a new RPC client like DialHTTP which sends its requests in
+// CBOR. It switches to JSON if the server rejects the CBOR content type.
+func DialHTTPCBOR(endpoint string) (*Client, error) {
+	return dialHTTP(endpoint, new(http.Client), true)
+}
+
+func dialHTTP(endpoint string, client *http.Client, cbor bool) (*Client, error) {
 	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", rpcTypes.ContentType)
 	req.Header.Set("Accept", rpcTypes.ContentType)
+	cborHdr := make(http.Header)
+	cborHdr.Set("Content-Type", rpcTypes.ContentTypeCBOR)
+	cborHdr.Set("Accept", rpcTypes.ContentTypeCBOR)
 
 	initctx := context.Background()
 	return newClient(initctx, func(context.Context) (net.Conn, error) {
-		return &httpConn{client: client, req: req, closed: make(chan struct{})}, nil
+		hc := &httpConn{client: client, req: req, cborHdr: cborHdr, closed: make(chan struct{})}
+		if cbor {
+			hc.cbor = 1
+		}
+		return hc, nil
 	})
 }
 
-// DialHTTP creates a new RPC client that connects to an RPC server over HTTP.
-func DialHTTP(endpoint string) (*Client, error) {
-	return DialHTTPWithClient(endpoint, new(http.Client))
-}
-
 func (c *Client) sendHTTP(ctx context.Context, op *requestOp, msg interface{}) error {
 	hc := c.writeConn.(*httpConn)
 	respBody, err := hc.doRequest(ctx, msg)
@@ -108,7 +129,7 @@ func (c *Client) sendHTTP(ctx context.Context, op *requestOp, msg interface{}) e
 		return err
 	}
 	var respmsg rpcTypes.JsonrpcMessage
-	if err := json.NewDecoder(respBody).Decode(&respmsg); err != nil {
+	if err := decodeHTTPResponse(respBody, &respmsg); err != nil {
 		return err
 	}
 	op.resp <- &respmsg
@@ -123,7 +144,7 @@ func (c *Client) sendBatchHTTP(ctx context.Context, op *requestOp, msgs []*rpcTy
 	}
 	defer respBody.Close()
 	var respmsgs []rpcTypes.JsonrpcMessage
-	if err := json.NewDecoder(respBody).Decode(&respmsgs); err != nil {
+	if err := decodeHTTPResponse(respBody, &respmsgs); err != nil {
 		return err
 	}
 	for i := 0; i < len(respmsgs); i++ {
@@ -133,24 +154,62 @@ func (c *Client) sendBatchHTTP(ctx context.Context, op *requestOp, msgs []*rpcTy
 }
 
 func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadCloser, error) {
-	body, err := json.Marshal(msg)
+	cbor := atomic.LoadInt32(&hc.cbor) == 1
+	var (
+		body []byte
+		err  error
+	)
+	if cbor {
+		body, err = rpcTypes.MarshalCBOR(msg)
+	} else {
+		body, err = json.Marshal(msg)
+	}
 	if err != nil {
 		return nil, err
 	}
 	req := hc.req.WithContext(ctx)
 	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	req.ContentLength = int64(len(body))
+	if cbor {
+		req.Header = hc.cborHdr
+	}
 
 	resp, err := hc.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if cbor && resp.StatusCode == http.StatusUnsupportedMediaType {
+		resp.Body.Close()
+		log.Debug("Server declined CBOR codec, falling back to JSON", "url", hc.req.URL)
+		atomic.StoreInt32(&hc.cbor, 0)
+		return hc.doRequest(ctx, msg)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return resp.Body, errors.New(resp.Status)
 	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == rpcTypes.ContentTypeCBOR {
+		return cborBody{resp.Body}, nil
+	}
 	return resp.Body, nil
 }
 
+// cborBody is the body of a CBOR response.
+type cborBody struct {
+	io.ReadCloser
+}
+
+// decodeHTTPResponse decodes a response body in the codec it was sent with.
+func decodeHTTPResponse(body io.Reader, v interface{}) error {
+	if _, ok := body.(cborBody); !ok {
+		return json.NewDecoder(body).Decode(v)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return rpcTypes.UnmarshalCBOR(data, v)
+}
+
 // NewHTTPServer creates a new HTTP RPC server around an API provider.
 //
 // Deprecated: Server implements http.Handler
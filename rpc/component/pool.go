@@ -0,0 +1,465 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drep-project/drepcli/log"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// HealthCheckMethod is called on every endpoint of a multi-endpoint client to
+// measure its latency and chain height.
+const HealthCheckMethod = "db_getMaxHeight"
+
+var ErrNoEndpoint = errors.New("no endpoint available")
+
+// Strategy selects the endpoint reads of a multi-endpoint client are sent to.
+type Strategy int
+
+const (
+	Failover      Strategy = iota // first healthy endpoint in the configured order
+	RoundRobin                    // rotate through all healthy endpoints
+	LowestLatency                 // healthy endpoint with the fastest health check
+)
+
+var strategyNames = map[Strategy]string{
+	Failover:      "failover",
+	RoundRobin:    "round-robin",
+	LowestLatency: "lowest-latency",
+}
+
+func (s Strategy) String() string {
+	if name, ok := strategyNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// ParseStrategy parses the name of an endpoint selection strategy.
+func ParseStrategy(name string) (Strategy, error) {
+	for strategy, strategyName := range strategyNames {
+		if strings.EqualFold(name, strategyName) {
+			return strategy, nil
+		}
+	}
+	return Failover, fmt.Errorf("unknown endpoint strategy %q", name)
+}
+
+// PoolConfig configures a client spanning multiple endpoints.
+type PoolConfig struct {
+	Strategy       Strategy
+	HealthInterval time.Duration // interval of the health checks
+	HealthTimeout  time.Duration // timeout of a single health check
+	MaxHeightLag   uint64        // endpoints lagging further behind the highest one are unhealthy
+	ReadNamespaces []string      // namespaces whose methods are reads, everything else is a write
+}
+
+// DefaultPoolConfig is used by Dial for comma separated endpoint lists.
+var DefaultPoolConfig = PoolConfig{
+	Strategy:       Failover,
+	HealthInterval: 10 * time.Second,
+	HealthTimeout:  3 * time.Second,
+	MaxHeightLag:   2,
+	ReadNamespaces: []string{"db", rpcTypes.MetadataApi},
+}
+
+// EndpointStatus reports the health of one endpoint of a multi-endpoint client.
+type EndpointStatus struct {
+	URL     string
+	Healthy bool
+	Height  uint64
+	Latency time.Duration
+	Err     error
+}
+
+type poolEndpoint struct {
+	url     string
+	client  *Client // nil until the endpoint could be dialed
+	healthy bool
+	height  uint64
+	latency time.Duration
+	err     error
+}
+
+// endpointPool routes the calls of a multi-endpoint client. Reads are sent to
+// the endpoint picked by the strategy and retried on the next one if the
+// connection fails. Writes stick to a single endpoint until it turns unhealthy
+// and are never retried.
+type endpointPool struct {
+	config PoolConfig
+	reads  map[string]bool
+	dial   func(ctx context.Context, url string) (*Client, error)
+
+	mu        sync.Mutex
+	endpoints []*poolEndpoint
+	next      int           // round robin position
+	sticky    *poolEndpoint // endpoint receiving writes
+
+	quitOnce sync.Once
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// DialEndpoints creates a client spanning all given endpoints. Every endpoint is
+// dialed like Dial does, endpoints which can't be reached are redialed by the
+// health checks. An error is returned if none of the endpoints is reachable.
+func DialEndpoints(ctx context.Context, endpoints []string, config PoolConfig) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoint
+	}
+	pool := newEndpointPool(config, func(ctx context.Context, url string) (*Client, error) {
+		return DialContext(ctx, url)
+	})
+	var lastErr error
+	for _, url := range endpoints {
+		ep := &poolEndpoint{url: url}
+		if ep.client, ep.err = pool.dial(ctx, url); ep.err != nil {
+			log.Debug("can't dial endpoint", "url", url, "err", ep.err)
+			lastErr = ep.err
+		}
+		pool.endpoints = append(pool.endpoints, ep)
+	}
+	if lastErr != nil && !pool.anyDialed() {
+		return nil, lastErr
+	}
+	return pool.start(ctx), nil
+}
+
+func newEndpointPool(config PoolConfig, dial func(context.Context, string) (*Client, error)) *endpointPool {
+	pool := &endpointPool{
+		config: config,
+		reads:  make(map[string]bool),
+		dial:   dial,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, namespace := range config.ReadNamespaces {
+		pool.reads[namespace] = true
+	}
+	return pool
+}
+
+func (p *endpointPool) anyDialed() bool {
+	for _, ep := range p.endpoints {
+		if ep.client != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// start runs the initial health check and returns the client routing through the pool.
+func (p *endpointPool) start(ctx context.Context) *Client {
+	p.check(ctx)
+	go p.loop()
+	return &Client{pool: p}
+}
+
+func (p *endpointPool) loop() {
+	defer close(p.done)
+	if p.config.HealthInterval <= 0 {
+		<-p.quit
+		return
+	}
+	ticker := time.NewTicker(p.config.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.check(context.Background())
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// check redials unreachable endpoints and measures the health of all of them.
+func (p *endpointPool) check(ctx context.Context) {
+	p.mu.Lock()
+	endpoints := make([]*poolEndpoint, len(p.endpoints))
+	copy(endpoints, p.endpoints)
+	p.mu.Unlock()
+
+	type result struct {
+		client  *Client
+		height  uint64
+		latency time.Duration
+		err     error
+	}
+	results := make([]result, len(endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, url string, client *Client) {
+			defer wg.Done()
+			ctx, cancel := p.healthContext(ctx)
+			defer cancel()
+			res := &results[i]
+			if client == nil {
+				if client, res.err = p.dial(ctx, url); res.err != nil {
+					return
+				}
+				res.client = client
+			}
+			var height json.RawMessage
+			start := time.Now()
+			if res.err = client.CallContext(ctx, &height, HealthCheckMethod); res.err == nil {
+				res.latency = time.Since(start)
+				res.height, res.err = parseHeight(height)
+			}
+		}(i, ep.url, ep.client)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var maxHeight uint64
+	for i, ep := range endpoints {
+		if results[i].client != nil {
+			ep.client = results[i].client
+		}
+		ep.err, ep.height, ep.latency = results[i].err, results[i].height, results[i].latency
+		if ep.err == nil && ep.height > maxHeight {
+			maxHeight = ep.height
+		}
+	}
+	for _, ep := range endpoints {
+		ep.healthy = ep.err == nil && ep.height+p.config.MaxHeightLag >= maxHeight
+		if !ep.healthy {
+			log.Debug("endpoint unhealthy", "url", ep.url, "height", ep.height, "max", maxHeight, "err", ep.err)
+		}
+	}
+	if p.sticky != nil && !p.sticky.healthy {
+		p.sticky = nil
+	}
+}
+
+func (p *endpointPool) healthContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.config.HealthTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.config.HealthTimeout)
+}
+
+// parseHeight accepts heights encoded as JSON numbers, decimal or hex strings.
+func parseHeight(raw json.RawMessage) (uint64, error) {
+	var height interface{}
+	if err := json.Unmarshal(raw, &height); err != nil {
+		return 0, err
+	}
+	switch height := height.(type) {
+	case float64:
+		return uint64(height), nil
+	case string:
+		if strings.HasPrefix(height, "0x") {
+			return strconv.ParseUint(height[2:], 16, 64)
+		}
+		return strconv.ParseUint(height, 10, 64)
+	default:
+		return 0, fmt.Errorf("invalid height %s", raw)
+	}
+}
+
+// isRead reports whether the method only reads state.
+func (p *endpointPool) isRead(method string) bool {
+	namespace := strings.SplitN(method, rpcTypes.ServiceMethodSeparator, 2)[0]
+	return p.reads[namespace]
+}
+
+// pickRead selects the endpoint of the next read, skipping the excluded ones.
+func (p *endpointPool) pickRead(exclude map[*poolEndpoint]bool) *poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	candidates := p.candidates(exclude)
+	if len(candidates) == 0 {
+		return nil
+	}
+	switch p.config.Strategy {
+	case RoundRobin:
+		p.next++
+		return candidates[p.next%len(candidates)]
+	case LowestLatency:
+		best := candidates[0]
+		for _, ep := range candidates[1:] {
+			if ep.latency < best.latency {
+				best = ep
+			}
+		}
+		return best
+	default:
+		return candidates[0]
+	}
+}
+
+// pickWrite returns the endpoint writes stick to.
+func (p *endpointPool) pickWrite() *poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sticky == nil {
+		if candidates := p.candidates(nil); len(candidates) > 0 {
+			p.sticky = candidates[0]
+		}
+	}
+	return p.sticky
+}
+
+// candidates returns the healthy endpoints in configured order. If no endpoint
+// is healthy, all reachable endpoints are returned.
+func (p *endpointPool) candidates(exclude map[*poolEndpoint]bool) []*poolEndpoint {
+	var healthy, reachable []*poolEndpoint
+	for _, ep := range p.endpoints {
+		if ep.client == nil || exclude[ep] {
+			continue
+		}
+		reachable = append(reachable, ep)
+		if ep.healthy {
+			healthy = append(healthy, ep)
+		}
+	}
+	if len(healthy) > 0 {
+		return healthy
+	}
+	return reachable
+}
+
+// failed marks the endpoint unhealthy after a connection error.
+func (p *endpointPool) failed(ep *poolEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log.Debug("endpoint failed", "url", ep.url, "err", err)
+	ep.healthy, ep.err = false, err
+	if p.sticky == ep {
+		p.sticky = nil
+	}
+}
+
+// do runs fn on the endpoint selected for a read or write. Reads failing with
+// a connection error are retried on the remaining endpoints.
+func (p *endpointPool) do(ctx context.Context, read bool, fn func(*Client) error) error {
+	tried := make(map[*poolEndpoint]bool)
+	for {
+		var ep *poolEndpoint
+		if read {
+			ep = p.pickRead(tried)
+		} else {
+			ep = p.pickWrite()
+		}
+		if ep == nil {
+			return ErrNoEndpoint
+		}
+		err := fn(ep.client)
+		if err == nil || !isConnectionError(ctx, err) {
+			return err
+		}
+		p.failed(ep, err)
+		if !read {
+			return err
+		}
+		tried[ep] = true
+	}
+}
+
+// isConnectionError reports whether err was caused by the endpoint rather than
+// by the request or the caller.
+func isConnectionError(ctx context.Context, err error) bool {
+	if _, ok := err.(rpcTypes.Error); ok {
+		return false
+	}
+	if ctx.Err() != nil || err == ErrClientQuit {
+		return false
+	}
+	_, isJSON := err.(*json.UnmarshalTypeError)
+	return !isJSON
+}
+
+func (p *endpointPool) callContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return p.do(ctx, p.isRead(method), func(client *Client) error {
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+func (p *endpointPool) callRawContext(ctx context.Context, method string, params json.RawMessage) (result json.RawMessage, err error) {
+	err = p.do(ctx, p.isRead(method), func(client *Client) (err error) {
+		result, err = client.CallRawContext(ctx, method, params)
+		return err
+	})
+	return result, err
+}
+
+func (p *endpointPool) batchCallContext(ctx context.Context, b []BatchElem) error {
+	read := true
+	for _, elem := range b {
+		read = read && p.isRead(elem.Method)
+	}
+	return p.do(ctx, read, func(client *Client) error {
+		return client.BatchCallContext(ctx, b)
+	})
+}
+
+func (p *endpointPool) subscribe(ctx context.Context, namespace string, channel reflect.Value, rec *Recorder, args ...interface{}) (*ClientSubscription, error) {
+	var sub *ClientSubscription
+	err := p.do(ctx, true, func(client *Client) (err error) {
+		sub, err = client.subscribe(ctx, namespace, channel, rec, args...)
+		return err
+	})
+	return sub, err
+}
+
+func (p *endpointPool) status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make([]EndpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		status[i] = EndpointStatus{URL: ep.url, Healthy: ep.healthy, Height: ep.height, Latency: ep.latency, Err: ep.err}
+	}
+	return status
+}
+
+func (p *endpointPool) close() {
+	p.quitOnce.Do(func() { close(p.quit) })
+	<-p.done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ep := range p.endpoints {
+		if ep.client != nil {
+			ep.client.Close()
+		}
+	}
+}
+
+// Endpoints reports the health of every endpoint of a client created by
+// DialEndpoints. It returns nil for single endpoint clients.
+func (c *Client) Endpoints() []EndpointStatus {
+	if c.pool == nil {
+		return nil
+	}
+	return c.pool.status()
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// NodeTestService answers the health check and identifies the node serving a call.
+type NodeTestService struct {
+	name   string
+	height uint64
+	delay  time.Duration
+}
+
+func (s *NodeTestService) GetMaxHeight() uint64 {
+	time.Sleep(s.delay)
+	return s.height
+}
+
+func (s *NodeTestService) Name() string {
+	return s.name
+}
+
+// newTestPool creates a multi-endpoint client on in-process servers, one per node.
+func newTestPool(t *testing.T, strategy Strategy, nodes ...*NodeTestService) (*Client, map[string]*rpcTypes.Server) {
+	servers := make(map[string]*rpcTypes.Server)
+	var urls []string
+	for _, node := range nodes {
+		server := rpcTypes.NewServer()
+		if err := server.RegisterName("db", node); err != nil {
+			t.Fatal(err)
+		}
+		if err := server.RegisterName("chain", node); err != nil {
+			t.Fatal(err)
+		}
+		servers[node.name] = server
+		urls = append(urls, node.name)
+	}
+	config := DefaultPoolConfig
+	config.Strategy = strategy
+	config.HealthInterval = 0
+	pool := newEndpointPool(config, func(ctx context.Context, url string) (*Client, error) {
+		server, ok := servers[url]
+		if !ok {
+			return nil, fmt.Errorf("unknown node %s", url)
+		}
+		return DialInProc(server), nil
+	})
+	for _, url := range urls {
+		client, _ := pool.dial(context.Background(), url)
+		pool.endpoints = append(pool.endpoints, &poolEndpoint{url: url, client: client})
+	}
+	return pool.start(context.Background()), servers
+}
+
+func callNames(t *testing.T, client *Client, method string, n int) []string {
+	var names []string
+	for i := 0; i < n; i++ {
+		var name string
+		if err := client.Call(&name, method); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestPoolFailover(t *testing.T) {
+	client, servers := newTestPool(t, Failover,
+		&NodeTestService{name: "a", height: 10},
+		&NodeTestService{name: "b", height: 10},
+	)
+	defer client.Close()
+
+	if names := callNames(t, client, "db_name", 2); !reflect.DeepEqual(names, []string{"a", "a"}) {
+		t.Fatalf("reads not sent to the first endpoint: %v", names)
+	}
+	servers["a"].Stop()
+	if names := callNames(t, client, "db_name", 2); !reflect.DeepEqual(names, []string{"b", "b"}) {
+		t.Fatalf("reads did not fail over: %v", names)
+	}
+	if status := client.Endpoints(); status[0].Healthy || !status[1].Healthy {
+		t.Errorf("unexpected endpoint status %+v", status)
+	}
+}
+
+func TestPoolRoundRobinStickyWrites(t *testing.T) {
+	client, _ := newTestPool(t, RoundRobin,
+		&NodeTestService{name: "a", height: 10},
+		&NodeTestService{name: "b", height: 10},
+		&NodeTestService{name: "c", height: 10},
+	)
+	defer client.Close()
+
+	reads := make(map[string]int)
+	for _, name := range callNames(t, client, "db_name", 6) {
+		reads[name]++
+	}
+	if reads["a"] != 2 || reads["b"] != 2 || reads["c"] != 2 {
+		t.Errorf("reads not balanced: %v", reads)
+	}
+	writes := callNames(t, client, "chain_name", 3)
+	if writes[0] != writes[1] || writes[1] != writes[2] {
+		t.Errorf("writes not sticky: %v", writes)
+	}
+}
+
+func TestPoolLowestLatency(t *testing.T) {
+	client, _ := newTestPool(t, LowestLatency,
+		&NodeTestService{name: "slow", height: 10, delay: 50 * time.Millisecond},
+		&NodeTestService{name: "fast", height: 10},
+	)
+	defer client.Close()
+
+	if names := callNames(t, client, "db_name", 2); !reflect.DeepEqual(names, []string{"fast", "fast"}) {
+		t.Errorf("reads not sent to the fastest endpoint: %v", names)
+	}
+}
+
+func TestPoolSkipsLaggingEndpoint(t *testing.T) {
+	client, _ := newTestPool(t, Failover,
+		&NodeTestService{name: "behind", height: 3},
+		&NodeTestService{name: "synced", height: 10},
+	)
+	defer client.Close()
+
+	if names := callNames(t, client, "db_name", 1); names[0] != "synced" {
+		t.Errorf("read sent to lagging endpoint %s", names[0])
+	}
+	if names := callNames(t, client, "chain_name", 1); names[0] != "synced" {
+		t.Errorf("write sent to lagging endpoint %s", names[0])
+	}
+}
+
+func TestSplitEndpoints(t *testing.T) {
+	got := SplitEndpoints("ws://a:1, ws://b:2", "", "/tmp/drep.ipc")
+	want := []string{"ws://a:1", "ws://b:2", "/tmp/drep.ipc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
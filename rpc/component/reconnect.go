@@ -0,0 +1,259 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/drep-project/drepcli/log"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// ReconnectPolicy configures how a client recovers from a lost connection.
+type ReconnectPolicy struct {
+	MinBackoff  time.Duration // delay before the first redial attempt
+	MaxBackoff  time.Duration // upper bound of the delay between redial attempts
+	Factor      float64       // growth of the delay after every failed attempt
+	MaxAttempts int           // attempts before active subscriptions are ended, zero means unlimited
+	KeepAlive   time.Duration // interval of websocket pings, zero disables them
+}
+
+// DefaultReconnectPolicy is suitable for long running subscribers.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+	Factor:     2,
+	KeepAlive:  30 * time.Second,
+}
+
+// backoff returns the delay before the given redial attempt, counting from zero.
+func (p *ReconnectPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.MinBackoff)
+	for i := 0; i < attempt && time.Duration(delay) < p.MaxBackoff; i++ {
+		delay *= p.Factor
+	}
+	if p.MaxBackoff > 0 && time.Duration(delay) > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// SetReconnectPolicy makes the client redial in the background as soon as the
+// connection is lost, backing off exponentially between attempts. Active
+// subscriptions are re-established on the new connection and signal the gap on
+// their Gap channel. A nil policy restores lazy reconnects on the next call.
+//
+// The policy has no effect on HTTP clients. For clients spanning multiple
+// endpoints it applies to every endpoint which has been dialed.
+func (c *Client) SetReconnectPolicy(policy *ReconnectPolicy) {
+	if c.pool != nil {
+		c.pool.mu.Lock()
+		defer c.pool.mu.Unlock()
+		for _, ep := range c.pool.endpoints {
+			if ep.client != nil {
+				ep.client.SetReconnectPolicy(policy)
+			}
+		}
+		return
+	}
+	if c.isHTTP {
+		return
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	if c.stopKeepAlive != nil {
+		close(c.stopKeepAlive)
+		c.stopKeepAlive = nil
+	}
+	if policy == nil {
+		c.policy = nil
+		return
+	}
+	cpy := *policy
+	c.policy = &cpy
+	if cpy.KeepAlive > 0 {
+		c.stopKeepAlive = make(chan struct{})
+		go c.keepAlive(cpy.KeepAlive, c.stopKeepAlive)
+	}
+}
+
+func (c *Client) reconnectPolicy() *ReconnectPolicy {
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+	return c.policy
+}
+
+// redial reestablishes the connection lost in dead according to the policy.
+// The result is reported to dispatch through redialDone.
+func (c *Client) redial(dead net.Conn, policy *ReconnectPolicy) {
+	var err error
+	for attempt := 0; policy.MaxAttempts == 0 || attempt < policy.MaxAttempts; attempt++ {
+		select {
+		case <-time.After(policy.backoff(attempt)):
+		case <-c.closing:
+			return
+		}
+		if err = c.redialOnce(dead); err == nil {
+			break
+		}
+		log.Debug("reconnect failed", "attempt", attempt+1, "err", err)
+	}
+	select {
+	case c.redialDone <- err:
+	case <-c.didClose:
+	}
+}
+
+// redialOnce takes the write lock and replaces the connection, unless a call
+// already did so in the meantime.
+func (c *Client) redialOnce(dead net.Conn) error {
+	op := &requestOp{resp: make(chan *rpcTypes.JsonrpcMessage)}
+	select {
+	case c.requestOp <- op:
+	case <-c.closing:
+		return ErrClientQuit
+	case <-c.didClose:
+		return ErrClientQuit
+	}
+	var err error
+	if c.writeConn == nil || c.writeConn == dead {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultDialTimeout)
+		err = c.reconnect(ctx)
+		cancel()
+	}
+	c.sendDone <- err
+	return err
+}
+
+// suspendSubscriptions moves the active subscriptions aside until the next
+// connection is established.
+func (c *Client) suspendSubscriptions() {
+	for id, sub := range c.subs {
+		delete(c.subs, id)
+		select {
+		case <-sub.quit:
+		default:
+			c.suspended = append(c.suspended, sub)
+		}
+	}
+}
+
+// resubscribe replays the given subscriptions on the current connection.
+// Subscriptions which can't be replayed because the connection is lost again
+// are handed back to dispatch.
+func (c *Client) resubscribe(subs []*ClientSubscription) {
+	for _, sub := range subs {
+		select {
+		case <-sub.quit:
+			continue
+		default:
+		}
+		err := c.resubscribeOne(sub)
+		if err == nil {
+			select {
+			case sub.gap <- struct{}{}:
+			default:
+			}
+			continue
+		}
+		if _, ok := err.(rpcTypes.Error); ok || err == ErrClientQuit {
+			sub.quitWithError(err, false)
+			continue
+		}
+		log.Debug("resubscribe failed", "namespace", sub.namespace, "err", err)
+		select {
+		case c.suspend <- sub:
+		case <-c.didClose:
+			sub.quitWithError(ErrClientQuit, false)
+		}
+	}
+}
+
+func (c *Client) resubscribeOne(sub *ClientSubscription) error {
+	msg, err := c.newMessage(sub.namespace+rpcTypes.SubscribeMethodSuffix, sub.args...)
+	if err != nil {
+		return err
+	}
+	op := &requestOp{
+		ids:         []json.RawMessage{msg.ID},
+		resp:        make(chan *rpcTypes.JsonrpcMessage),
+		sub:         sub,
+		resubscribe: true,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+	if err := c.send(ctx, op, msg); err != nil {
+		return err
+	}
+	_, err = op.wait(ctx)
+	return err
+}
+
+// keepAlive pings the server of a websocket connection in the given interval.
+// A failing ping drops the connection, which triggers a reconnect.
+func (c *Client) keepAlive(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.ping(); err == ErrClientQuit {
+				return
+			}
+		case <-stop:
+			return
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *Client) ping() error {
+	op := &requestOp{resp: make(chan *rpcTypes.JsonrpcMessage)}
+	select {
+	case c.requestOp <- op:
+	case <-c.closing:
+		return ErrClientQuit
+	case <-c.didClose:
+		return ErrClientQuit
+	}
+	var err error
+	conn := c.writeConn
+	if cc, ok := conn.(*cborConn); ok {
+		conn = cc.Conn
+	}
+	if ws, ok := conn.(*websocket.Conn); ok {
+		ws.SetWriteDeadline(time.Now().Add(defaultWriteTimeout))
+		ws.PayloadType = websocket.PingFrame
+		_, err = ws.Write(nil)
+		ws.PayloadType = websocket.TextFrame
+		ws.SetWriteDeadline(time.Time{})
+		if err != nil {
+			log.Debug("keepalive ping failed", "err", err)
+			ws.Close()
+			c.writeConn = nil
+		}
+	}
+	c.sendDone <- err
+	return err
+}
@@ -0,0 +1,209 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+var testReconnectPolicy = ReconnectPolicy{
+	MinBackoff: 10 * time.Millisecond,
+	MaxBackoff: 200 * time.Millisecond,
+	Factor:     2,
+	KeepAlive:  50 * time.Millisecond,
+}
+
+// testServer is a local websocket server which can be killed and restarted on
+// the same address.
+type testServer struct {
+	t    *testing.T
+	addr string
+	srv  *rpcTypes.Server
+	l    net.Listener
+}
+
+func startTestServer(t *testing.T, addr string) *testServer {
+	s := &testServer{t: t, addr: addr}
+	s.start()
+	return s
+}
+
+func (s *testServer) start() {
+	s.srv = newTestServer("nftest", new(NotificationTestService))
+	var err error
+	for i := 0; i < 50; i++ {
+		if s.l, err = net.Listen("tcp", s.addr); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		s.t.Fatal("can't listen:", err)
+	}
+	s.addr = s.l.Addr().String()
+	go http.Serve(s.l, s.srv.WebsocketHandler([]string{"*"}))
+}
+
+func (s *testServer) kill() {
+	s.l.Close()
+	s.srv.Stop()
+}
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	policy := ReconnectPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second, Factor: 2}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for attempt, delay := range want {
+		if got := policy.backoff(attempt); got != delay {
+			t.Errorf("attempt %d: got backoff %v, want %v", attempt, got, delay)
+		}
+	}
+}
+
+func TestClientResubscribeAfterRestart(t *testing.T) {
+	server := startTestServer(t, "127.0.0.1:0")
+	defer func() { server.kill() }()
+
+	client, err := Dial("ws://" + server.addr)
+	if err != nil {
+		t.Fatal("can't dial", err)
+	}
+	defer client.Close()
+	client.SetReconnectPolicy(&testReconnectPolicy)
+
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 2, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	expectNotifications(t, nc, 0, 1)
+	firstID := sub.ID()
+
+	server.kill()
+	server.start()
+
+	select {
+	case <-sub.Gap():
+	case err := <-sub.Err():
+		t.Fatal("subscription ended:", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not re-established")
+	}
+	expectNotifications(t, nc, 0, 1)
+	if sub.ID() == firstID {
+		t.Errorf("subscription id not updated after resubscribe")
+	}
+
+	// Calls work again without any further action.
+	var result int
+	if err := client.Call(&result, "nftest_echo", 11); err != nil || result != 11 {
+		t.Errorf("call after reconnect failed: result %d, err %v", result, err)
+	}
+}
+
+func TestClientReconnectGiveUp(t *testing.T) {
+	server := startTestServer(t, "127.0.0.1:0")
+	client, err := Dial("ws://" + server.addr)
+	if err != nil {
+		t.Fatal("can't dial", err)
+	}
+	defer client.Close()
+	policy := testReconnectPolicy
+	policy.MaxAttempts = 3
+	client.SetReconnectPolicy(&policy)
+
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	expectNotifications(t, nc, 0)
+
+	server.kill()
+	select {
+	case err := <-sub.Err():
+		if err == nil {
+			t.Fatal("expected reconnect error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not ended after the policy gave up")
+	}
+}
+
+func TestClientReconnectIPC(t *testing.T) {
+	endpoint := fmt.Sprintf("%s/drep-test-ipc-%d-%d", os.TempDir(), os.Getpid(), rand.Int63())
+	listen := func() (*rpcTypes.Server, net.Listener) {
+		srv := newTestServer("nftest", new(NotificationTestService))
+		l, err := IpcListen(endpoint)
+		if err != nil {
+			t.Fatal("can't listen:", err)
+		}
+		go srv.ServeListener(l)
+		return srv, l
+	}
+	srv, l := listen()
+	client, err := Dial(endpoint)
+	if err != nil {
+		t.Fatal("can't dial", err)
+	}
+	defer client.Close()
+	client.SetReconnectPolicy(&testReconnectPolicy)
+
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 1, 5)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	expectNotifications(t, nc, 5)
+
+	l.Close()
+	srv.Stop()
+	srv, l = listen()
+	defer l.Close()
+	defer srv.Stop()
+
+	select {
+	case <-sub.Gap():
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not re-established")
+	}
+	expectNotifications(t, nc, 5)
+}
+
+func expectNotifications(t *testing.T, nc <-chan int, values ...int) {
+	t.Helper()
+	for _, want := range values {
+		select {
+		case got := <-nc:
+			if got != want {
+				t.Fatalf("got notification %d, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notification %d not delivered", want)
+		}
+	}
+}
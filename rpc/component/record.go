@@ -0,0 +1,218 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drep-project/drepcli/log"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// Kinds of session entries.
+const (
+	SessionCall         = "call"
+	SessionSubscribe    = "subscribe"
+	SessionNotification = "notification"
+)
+
+// SessionEntry is one exchange of a recorded session. Sessions are stored as one
+// JSON encoded entry per line.
+type SessionEntry struct {
+	Kind         string          `json:"kind"`
+	Time         time.Duration   `json:"time"`               // since the start of the recording
+	Duration     time.Duration   `json:"duration,omitempty"` // until the response arrived
+	Method       string          `json:"method,omitempty"`
+	Params       json.RawMessage `json:"params,omitempty"`
+	Result       json.RawMessage `json:"result,omitempty"`
+	Error        *SessionError   `json:"error,omitempty"`
+	Subscription string          `json:"subscription,omitempty"` // id of the subscription created or notified
+}
+
+// SessionError is the error a recorded call failed with. Errors which didn't come
+// from the server, e.g. connection failures, are recorded with code zero.
+type SessionError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *SessionError) Error() string  { return e.Message }
+func (e *SessionError) ErrorCode() int { return e.Code }
+
+// redactedValue replaces the params and results of redacted calls in a session.
+var redactedValue = json.RawMessage(`"<redacted>"`)
+
+// Recorder writes the calls, subscriptions and notifications exchanged by clients
+// to a session log, which can be served again with NewReplayServer. The params
+// and results of the namespaces in rpcTypes.DefaultRedactNamespaces, which carry
+// passwords and private keys, are redacted, so their calls aren't replayed.
+type Recorder struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	start  time.Time
+	redact map[string]bool
+	failed bool // set after the first write error, which is logged once
+}
+
+// NewRecorder creates a recorder writing the session to w.
+func NewRecorder(w io.Writer) *Recorder {
+	r := &Recorder{enc: json.NewEncoder(w), start: time.Now(), redact: make(map[string]bool)}
+	for _, namespace := range rpcTypes.DefaultRedactNamespaces {
+		r.redact[namespace] = true
+	}
+	if closer, ok := w.(io.Closer); ok {
+		r.closer = closer
+	}
+	return r
+}
+
+// CreateRecorder creates a recorder writing the session to the given file. An
+// existing file is truncated.
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(f), nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// SetRecorder records all calls, subscriptions and notifications of the client
+// with r, nil stops recording. Subscriptions keep the recorder they were created
+// with.
+func (c *Client) SetRecorder(r *Recorder) {
+	c.recorderMu.Lock()
+	c.recorder = r
+	c.recorderMu.Unlock()
+}
+
+func (c *Client) sessionRecorder() *Recorder {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	return c.recorder
+}
+
+func (r *Recorder) write(entry *SessionEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(entry); err != nil && !r.failed {
+		r.failed = true
+		log.Error("Can't record RPC session", "err", err)
+	}
+}
+
+// redacted reports whether the params and results of the method are redacted.
+func (r *Recorder) redacted(method string) bool {
+	return r.redact[strings.SplitN(method, rpcTypes.ServiceMethodSeparator, 2)[0]]
+}
+
+func (r *Recorder) recordCall(method string, params, result json.RawMessage, err error, start time.Time) {
+	if r.redacted(method) {
+		params, result = redactedValue, nil
+		if err == nil {
+			result = redactedValue
+		}
+	}
+	r.write(&SessionEntry{
+		Kind:     SessionCall,
+		Time:     start.Sub(r.start),
+		Duration: time.Since(start),
+		Method:   method,
+		Params:   params,
+		Result:   result,
+		Error:    sessionError(err),
+	})
+}
+
+func (r *Recorder) recordSubscribe(namespace string, args []interface{}, subid string, err error, start time.Time) {
+	params, _ := json.Marshal(args)
+	if r.redacted(namespace) {
+		params = redactedValue
+	}
+	entry := &SessionEntry{
+		Kind:     SessionSubscribe,
+		Time:     start.Sub(r.start),
+		Duration: time.Since(start),
+		Method:   namespace + rpcTypes.SubscribeMethodSuffix,
+		Params:   params,
+		Error:    sessionError(err),
+	}
+	if err == nil {
+		entry.Result, _ = json.Marshal(subid)
+		entry.Subscription = subid
+	}
+	r.write(entry)
+}
+
+func (r *Recorder) recordNotification(subid string, result json.RawMessage) {
+	r.write(&SessionEntry{
+		Kind:         SessionNotification,
+		Time:         time.Since(r.start),
+		Subscription: subid,
+		Result:       result,
+	})
+}
+
+// batchCall performs the batch with raw results, records them and decodes them
+// into the results of the batch elements.
+func (r *Recorder) batchCall(ctx context.Context, b []BatchElem, call func(context.Context, []BatchElem) error) error {
+	raw := make([]json.RawMessage, len(b))
+	elems := make([]BatchElem, len(b))
+	for i, elem := range b {
+		elems[i] = BatchElem{Method: elem.Method, Args: elem.Args, Result: &raw[i]}
+	}
+	start := time.Now()
+	if err := call(ctx, elems); err != nil {
+		return err
+	}
+	for i, elem := range elems {
+		params, _ := json.Marshal(elem.Args)
+		r.recordCall(elem.Method, params, raw[i], elem.Error, start)
+		if b[i].Error = elem.Error; elem.Error == nil {
+			b[i].Error = json.Unmarshal(raw[i], b[i].Result)
+		}
+	}
+	return nil
+}
+
+// sessionError converts the error of a call to its recorded form.
+func sessionError(err error) *SessionError {
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case rpcTypes.Error:
+		return &SessionError{Code: err.ErrorCode(), Message: err.Error()}
+	default:
+		return &SessionError{Message: err.Error()}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// recordSession runs the calls of the replay tests against client.
+func recordSession(t *testing.T, client *Client) (height uint64, block chainTypes.Block, notifications []int) {
+	if err := client.Call(&height, "db_getMaxHeight"); err != nil {
+		t.Fatal(err)
+	}
+	batch := client.NewBatch()
+	batch.Block(3, &block)
+	missing := batch.Block(100, new(chainTypes.Block))
+	if err, ok := batch.Send(context.Background()).(*BatchError); !ok || batch.Err(missing) == nil {
+		t.Fatalf("expected the call of a missing block to fail, got %v", err)
+	}
+
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "eth", nc, "someSubscription", 3, 5)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	for i := 0; i < 3; i++ {
+		select {
+		case v := <-nc:
+			notifications = append(notifications, v)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("missing notification %d", i)
+		}
+	}
+	return height, block, notifications
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := newTestServer("db", new(DBTestService))
+	if err := server.RegisterName("eth", new(NotificationTestService)); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := DialInProc(server)
+
+	log := new(bytes.Buffer)
+	client.SetRecorder(NewRecorder(log))
+	height, block, notifications := recordSession(t, client)
+	client.Close()
+
+	session, err := ReadSession(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayServer, err := NewReplayServer(session, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayServer.Stop()
+	replay := DialInProc(replayServer)
+	defer replay.Close()
+
+	gotHeight, gotBlock, gotNotifications := recordSession(t, replay)
+	if gotHeight != height || gotBlock.Header.Height != block.Header.Height {
+		t.Errorf("replayed height %d, block %d, want %d, %d", gotHeight, gotBlock.Header.Height, height, block.Header.Height)
+	}
+	if len(gotNotifications) != len(notifications) {
+		t.Fatalf("replayed %d notifications, want %d", len(gotNotifications), len(notifications))
+	}
+	for i := range notifications {
+		if gotNotifications[i] != notifications[i] {
+			t.Errorf("notification %d is %d, want %d", i, gotNotifications[i], notifications[i])
+		}
+	}
+
+	var result interface{}
+	err = replay.Call(&result, "db_getBlock", 4)
+	if rpcErr, ok := err.(rpcTypes.Error); !ok || rpcErr.ErrorCode() != -32601 {
+		t.Errorf("expected method not found error for an unrecorded call, got %v", err)
+	}
+	var modules map[string]string
+	if err := replay.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := modules["db"]; !ok {
+		t.Errorf("replayed namespaces %v are missing db", modules)
+	}
+}
+
+func TestReplayRepeatsLastResponse(t *testing.T) {
+	session, err := ReadSession(bytes.NewBufferString(`{"kind":"call","time":0,"method":"db_getMaxHeight","params":null,"result":1}
+{"kind":"call","time":10,"method":"db_getMaxHeight","params":[],"result":2}
+{"kind":"call","time":20,"method":"db_getNonce","params":["0x01"],"error":{"code":-32000,"message":"no account"}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewReplayServer(session, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	for _, want := range []uint64{1, 2, 2} {
+		var height uint64
+		if err := client.Call(&height, "db_getMaxHeight"); err != nil {
+			t.Fatal(err)
+		}
+		if height != want {
+			t.Errorf("replayed height %d, want %d", height, want)
+		}
+	}
+	var nonce uint64
+	err = client.Call(&nonce, "db_getNonce", "0x01")
+	if rpcErr, ok := err.(rpcTypes.Error); !ok || rpcErr.ErrorCode() != -32000 || rpcErr.Error() != "no account" {
+		t.Errorf("expected the recorded error, got %v", err)
+	}
+}
+
+// AccountTestService holds a wallet protected by a password.
+type AccountTestService struct{}
+
+func (s *AccountTestService) Open(password string) error {
+	return nil
+}
+
+func (s *AccountTestService) DumpPrikey(addr string) string {
+	return "0x6a0c9f4bd41b5d1a4bea5b2ee2b4ac2a17f1c2f09f1e7d44f56a1dde2cd97d4e"
+}
+
+func TestRecordRedactsAccounts(t *testing.T) {
+	server := newTestServer("account", new(AccountTestService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	log := new(bytes.Buffer)
+	client.SetRecorder(NewRecorder(log))
+	if err := client.Call(nil, "account_open", "s3cret-password"); err != nil {
+		t.Fatal(err)
+	}
+	var key string
+	if err := client.Call(&key, "account_dumpPrikey", "0x772dec19e0b0b2d63a57a3a7fb03fc066d915e6b"); err != nil {
+		t.Fatal(err)
+	}
+	batch := client.NewBatch()
+	batch.Call(new(interface{}), "account_open", "s3cret-password")
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"s3cret-password", key} {
+		if strings.Contains(log.String(), secret) {
+			t.Errorf("session contains %q:\n%s", secret, log)
+		}
+	}
+	if calls := strings.Count(log.String(), `"kind":"call"`); calls != 3 {
+		t.Errorf("recorded %d calls, want 3", calls)
+	}
+	if _, err := ReadSession(bytes.NewReader(log.Bytes())); err != nil {
+		t.Errorf("can't read the redacted session: %v", err)
+	}
+}
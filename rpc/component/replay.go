@@ -0,0 +1,247 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// Session is a recorded session which can be served by a replay server. Calls
+// are answered with the responses recorded for the same method and params, in
+// recorded order. Once they are used up the last one is repeated.
+type Session struct {
+	mu            sync.Mutex
+	responses     map[string][]*SessionEntry // method and params => calls and subscriptions
+	next          map[string]int             // method and params => index of the next response
+	notifications map[string][]*SessionEntry // subscription id => notifications
+	modules       map[string]string          // namespace => api version
+}
+
+// LoadSession reads the session recorded in the given file.
+func LoadSession(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	session, err := ReadSession(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return session, nil
+}
+
+// ReadSession reads a recorded session from r.
+func ReadSession(r io.Reader) (*Session, error) {
+	s := &Session{
+		responses:     make(map[string][]*SessionEntry),
+		next:          make(map[string]int),
+		notifications: make(map[string][]*SessionEntry),
+		modules:       make(map[string]string),
+	}
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		entry := new(SessionEntry)
+		if err := dec.Decode(entry); err == io.EOF {
+			return s, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", n, err)
+		}
+		switch entry.Kind {
+		case SessionCall, SessionSubscribe:
+			key := sessionKey(entry.Method, entry.Params)
+			s.responses[key] = append(s.responses[key], entry)
+			s.addModule(entry)
+		case SessionNotification:
+			s.notifications[entry.Subscription] = append(s.notifications[entry.Subscription], entry)
+		default:
+			return nil, fmt.Errorf("entry %d: unknown kind %q", n, entry.Kind)
+		}
+	}
+}
+
+// addModule collects the namespace of the entry and, for rpc_modules calls, the
+// reported namespaces and versions.
+func (s *Session) addModule(entry *SessionEntry) {
+	namespace := strings.SplitN(entry.Method, rpcTypes.ServiceMethodSeparator, 2)[0]
+	if _, ok := s.modules[namespace]; !ok {
+		s.modules[namespace] = rpcTypes.DefaultApiVersion
+	}
+	if entry.Method != rpcTypes.MetadataApi+rpcTypes.ServiceMethodSeparator+"modules" || entry.Error != nil {
+		return
+	}
+	var modules map[string]string
+	if json.Unmarshal(entry.Result, &modules) == nil {
+		for namespace, version := range modules {
+			s.modules[namespace] = version
+		}
+	}
+}
+
+// response returns the next recorded response of the call.
+func (s *Session) response(method string, params json.RawMessage) *SessionEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sessionKey(method, params)
+	responses := s.responses[key]
+	if len(responses) == 0 {
+		return nil
+	}
+	i := s.next[key]
+	if i < len(responses)-1 {
+		s.next[key] = i + 1
+	}
+	return responses[i]
+}
+
+// NewReplayServer creates a server answering the calls and subscriptions of
+// the session. With realtime set responses and notifications are delayed like
+// they were when the session was recorded, otherwise they are sent at once.
+func NewReplayServer(session *Session, realtime bool) (*rpcTypes.Server, error) {
+	server := rpcTypes.NewServer()
+	forwarder := &replayForwarder{session: session, realtime: realtime}
+	for namespace, version := range session.modules {
+		if namespace == rpcTypes.MetadataApi {
+			continue // served by the server itself
+		}
+		if err := server.RegisterName(namespace, forwarder, rpcTypes.WithVersion(version)); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
+}
+
+// DialReplay creates a client connected to a replay server of the session
+// recorded in the given file.
+func DialReplay(path string, realtime bool) (*Client, error) {
+	session, err := LoadSession(path)
+	if err != nil {
+		return nil, err
+	}
+	server, err := NewReplayServer(session, realtime)
+	if err != nil {
+		return nil, err
+	}
+	return DialInProc(server), nil
+}
+
+// replayForwarder serves the namespaces of a session.
+type replayForwarder struct {
+	session  *Session
+	realtime bool
+}
+
+func (f *replayForwarder) Forward(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	entry := f.session.response(method, params)
+	if entry == nil {
+		return nil, &SessionError{Code: -32601, Message: fmt.Sprintf("no recorded response for %s %s", method, compactParams(params))}
+	}
+	if f.realtime {
+		select {
+		case <-time.After(entry.Duration):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if entry.Error != nil {
+		return nil, replayError(entry.Error)
+	}
+	return entry.Result, nil
+}
+
+func (f *replayForwarder) ForwardSubscribe(ctx context.Context, namespace string, params json.RawMessage) (*rpcTypes.Subscription, error) {
+	method := namespace + rpcTypes.SubscribeMethodSuffix
+	entry := f.session.response(method, params)
+	if entry == nil {
+		return nil, &SessionError{Code: -32601, Message: fmt.Sprintf("no recorded subscription for %s %s", method, compactParams(params))}
+	}
+	if entry.Error != nil {
+		return nil, replayError(entry.Error)
+	}
+	notifier, _ := rpcTypes.NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+	go f.notify(notifier, sub, entry)
+	return sub, nil
+}
+
+// notify sends the notifications recorded for the subscription created by
+// entry until the subscription or the connection ends.
+func (f *replayForwarder) notify(notifier *rpcTypes.Notifier, sub *rpcTypes.Subscription, entry *SessionEntry) {
+	f.session.mu.Lock()
+	notifications := f.session.notifications[entry.Subscription]
+	f.session.mu.Unlock()
+
+	start, created := time.Now(), entry.Time+entry.Duration
+	for _, n := range notifications {
+		if f.realtime {
+			wait := n.Time - created - time.Since(start)
+			if wait < 0 {
+				wait = 0
+			}
+			select {
+			case <-time.After(wait):
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+		select {
+		case <-sub.Err():
+			return
+		case <-notifier.Closed():
+			return
+		default:
+			notifier.Notify(sub.ID, n.Result)
+		}
+	}
+}
+
+// replayError converts a recorded error back to the error returned by the
+// call. Errors without code didn't come from the server.
+func replayError(err *SessionError) error {
+	if err.Code == 0 {
+		return errors.New(err.Message)
+	}
+	return err
+}
+
+// sessionKey identifies the responses of a call. Missing params are recorded
+// as null, they are sent as an empty array.
+func sessionKey(method string, params json.RawMessage) string {
+	return method + " " + compactParams(params)
+}
+
+func compactParams(params json.RawMessage) string {
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, params); err != nil || buf.Len() == 0 || buf.String() == "null" {
+		return "[]"
+	}
+	return buf.String()
+}
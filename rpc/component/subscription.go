@@ -0,0 +1,55 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/crypto"
+)
+
+const (
+	// ChainNamespace is the namespace the chain events are subscribed under.
+	ChainNamespace = "chain"
+
+	NewBlocksSubscription           = "newBlocks"
+	PendingTransactionsSubscription = "pendingTransactions"
+	AccountSubscription             = "account"
+	LogsSubscription                = "logs"
+)
+
+// SubscribeNewBlocks subscribes to notifications about blocks appended to the chain.
+func (c *Client) SubscribeNewBlocks(ctx context.Context, ch chan<- *chainTypes.Block) (*ClientSubscription, error) {
+	return c.Subscribe(ctx, ChainNamespace, ch, NewBlocksSubscription)
+}
+
+// SubscribePendingTransactions subscribes to notifications about transactions
+// entering the transaction pool.
+func (c *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- *chainTypes.Transaction) (*ClientSubscription, error) {
+	return c.Subscribe(ctx, ChainNamespace, ch, PendingTransactionsSubscription)
+}
+
+// SubscribeAccount subscribes to balance and nonce changes of the given account.
+func (c *Client) SubscribeAccount(ctx context.Context, address crypto.CommonAddress, ch chan<- *chainTypes.AccountEvent) (*ClientSubscription, error) {
+	return c.Subscribe(ctx, ChainNamespace, ch, AccountSubscription, address)
+}
+
+// SubscribeLogs subscribes to the contract logs matching the given filter.
+func (c *Client) SubscribeLogs(ctx context.Context, filter chainTypes.LogFilter, ch chan<- *chainTypes.Log) (*ClientSubscription, error) {
+	return c.Subscribe(ctx, ChainNamespace, ch, LogsSubscription, filter)
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	chainTypes "github.com/drep-project/drepcli/chain/types"
+	"github.com/drep-project/drepcli/crypto"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// ChainTestService emits a fixed set of chain events to every subscriber.
+type ChainTestService struct{}
+
+func (s *ChainTestService) notify(ctx context.Context, events ...interface{}) (*rpcTypes.Subscription, error) {
+	notifier, supported := rpcTypes.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpcTypes.ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	go func() {
+		for _, event := range events {
+			if err := notifier.Notify(subscription.ID, event); err != nil {
+				return
+			}
+		}
+	}()
+	return subscription, nil
+}
+
+func (s *ChainTestService) NewBlocks(ctx context.Context) (*rpcTypes.Subscription, error) {
+	return s.notify(ctx,
+		&chainTypes.Block{Header: &chainTypes.BlockHeader{Height: 1}},
+		&chainTypes.Block{Header: &chainTypes.BlockHeader{Height: 2}},
+	)
+}
+
+func (s *ChainTestService) Account(ctx context.Context, address crypto.CommonAddress) (*rpcTypes.Subscription, error) {
+	return s.notify(ctx, &chainTypes.AccountEvent{Address: address, Height: 7, Nonce: 3})
+}
+
+func (s *ChainTestService) Logs(ctx context.Context, filter chainTypes.LogFilter) (*rpcTypes.Subscription, error) {
+	var events []interface{}
+	for _, address := range filter.Addresses {
+		events = append(events, &chainTypes.Log{Address: address, Height: 9})
+	}
+	return s.notify(ctx, events...)
+}
+
+func TestClientSubscribeNewBlocks(t *testing.T) {
+	server := newTestServer(ChainNamespace, new(ChainTestService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	blocks := make(chan *chainTypes.Block)
+	sub, err := client.SubscribeNewBlocks(context.Background(), blocks)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	for height := uint64(1); height <= 2; height++ {
+		select {
+		case block := <-blocks:
+			if block.Header == nil || block.Header.Height != height {
+				t.Fatalf("unexpected block %+v, want height %d", block, height)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("block not delivered")
+		}
+	}
+}
+
+func TestClientSubscribeAccount(t *testing.T) {
+	server := newTestServer(ChainNamespace, new(ChainTestService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	address := crypto.Hex2Address("c6196f8d8165c7cbb5ffc3833d4caf0c92017c5d")
+	events := make(chan *chainTypes.AccountEvent)
+	sub, err := client.SubscribeAccount(context.Background(), address, events)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	select {
+	case event := <-events:
+		if event.Address != address || event.Nonce != 3 {
+			t.Fatalf("unexpected account event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("account event not delivered")
+	}
+}
+
+func TestClientSubscribeLogs(t *testing.T) {
+	server := newTestServer(ChainNamespace, new(ChainTestService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	address := crypto.Hex2Address("c6196f8d8165c7cbb5ffc3833d4caf0c92017c5d")
+	logs := make(chan *chainTypes.Log)
+	filter := chainTypes.LogFilter{Addresses: []crypto.CommonAddress{address}}
+	sub, err := client.SubscribeLogs(context.Background(), filter, logs)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+	select {
+	case log := <-logs:
+		if log.Address != address || log.Height != 9 {
+			t.Fatalf("unexpected log %+v", log)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("log not delivered")
+	}
+}
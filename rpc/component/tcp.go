@@ -0,0 +1,39 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package component
+
+import (
+	"context"
+	"net"
+)
+
+// DialTCP connects to a raw TCP endpoint (host:port) exchanging newline-delimited
+// JSON-RPC messages.
+func DialTCP(ctx context.Context, endpoint string) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		return dialContext(ctx, "tcp", endpoint)
+	})
+}
+
+// DialTCPCBOR connects to the raw TCP endpoint like DialTCP, using the CBOR codec.
+func DialTCPCBOR(ctx context.Context, endpoint string) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
+		return dialCBORStream(ctx, func(ctx context.Context) (net.Conn, error) {
+			return dialContext(ctx, "tcp", endpoint)
+		})
+	})
+}
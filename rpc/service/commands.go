@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/drep-project/drepcli/app"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+var (
+	OpenRPCFileFlag = cli.StringFlag{
+		Name:  "file",
+		Usage: "File the OpenRPC document is written to (default stdout)",
+	}
+	ProxyUpstreamFlag = cli.StringSliceFlag{
+		Name:  "upstream",
+		Usage: "RPC endpoint of the upstream node, repeat or separate by comma for several nodes",
+	}
+	ProxyStrategyFlag = cli.StringFlag{
+		Name:  "strategy",
+		Usage: "Upstream selection when proxying several nodes (failover, round-robin, lowest-latency)",
+		Value: "failover",
+	}
+	ProxyAllowFlag = cli.StringFlag{
+		Name:  "allow",
+		Usage: "Comma separated methods to expose, e.g. db_*,chain_send (default all)",
+	}
+	ProxyDenyFlag = cli.StringFlag{
+		Name:  "deny",
+		Usage: "Comma separated methods to hide, applied after --allow",
+		Value: "account_dumpPrikey",
+	}
+	ProxyCacheFlag = cli.BoolFlag{
+		Name:  "rpccache",
+		Usage: "Answer immutable chain queries from a cache in memory and below the data directory",
+	}
+)
+
+// Commands returns the commands offered by the rpc service
+func (rpcService *RpcService) Commands(executeContext *app.ExecuteContext) []cli.Command {
+	return []cli.Command{
+		{
+			Name:  "openrpc",
+			Usage: "Dump the OpenRPC document of all APIs offered over RPC",
+			Flags: []cli.Flag{OpenRPCFileFlag},
+			Action: func(ctx *cli.Context) error {
+				return rpcService.dumpOpenRPC(executeContext, ctx)
+			},
+		},
+		{
+			Name:  "proxy",
+			Usage: "Serve the APIs of an upstream node next to the local account API",
+			Description: `Exposes the namespaces of the upstream node on the local HTTP, WS, TCP
+and IPC endpoints and forwards calls to it. The local account namespace is
+served alongside. HTTP is enabled if none of HTTP, WS and TCP are enabled.`,
+			Flags: []cli.Flag{ProxyUpstreamFlag, ProxyStrategyFlag, ProxyAllowFlag, ProxyDenyFlag, ProxyCacheFlag},
+			Action: func(ctx *cli.Context) error {
+				return rpcService.runProxy(executeContext, ctx)
+			},
+		},
+	}
+}
+
+// dumpOpenRPC registers the APIs of all services, without starting any endpoint,
+// and writes the resulting OpenRPC document.
+func (rpcService *RpcService) dumpOpenRPC(executeContext *app.ExecuteContext, ctx *cli.Context) error {
+	if err := executeContext.InitServices("cli"); err != nil {
+		return err
+	}
+	handler := rpcTypes.NewServer()
+	for _, api := range executeContext.GetApis() {
+		if err := handler.RegisterName(api.Namespace, api.Service, RegisterOptions(api)...); err != nil {
+			return err
+		}
+	}
+	doc := handler.OpenRPC(rpcTypes.OpenRPCInfo{Title: ctx.App.Name, Version: ctx.App.Version})
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+
+	file := ctx.String(OpenRPCFileFlag.Name)
+	if file == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
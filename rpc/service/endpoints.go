@@ -18,6 +18,8 @@ package service
 
 import (
 	"net"
+	"strings"
+	"time"
 
 	"github.com/drep-project/drepcli/app"
 	"github.com/drep-project/drepcli/log"
@@ -26,7 +28,7 @@ import (
 )
 
 // StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
-func StartHTTPEndpoint(endpoint string, apis []app.API, modules []string, cors []string, vhosts []string, timeouts rpcTypes.HTTPTimeouts) (net.Listener, *rpcTypes.Server, error) {
+func StartHTTPEndpoint(endpoint string, apis []app.API, modules []string, cors []string, vhosts []string, timeouts rpcTypes.HTTPTimeouts, accessLog *rpcTypes.AccessLog) (net.Listener, *rpcTypes.Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -34,9 +36,10 @@ func StartHTTPEndpoint(endpoint string, apis []app.API, modules []string, cors [
 	}
 	// Register all the APIs exposed by the services
 	handler := rpcTypes.NewServer()
+	handler.SetAccessLog(accessLog)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			if err := handler.RegisterName(api.Namespace, api.Service, RegisterOptions(api)...); err != nil {
 				return nil, nil, err
 			}
 			log.Debug("HTTP registered", "namespace", api.Namespace)
@@ -55,7 +58,7 @@ func StartHTTPEndpoint(endpoint string, apis []app.API, modules []string, cors [
 }
 
 // StartWSEndpoint starts a websocket endpoint
-func StartWSEndpoint(endpoint string, apis []app.API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *rpcTypes.Server, error) {
+func StartWSEndpoint(endpoint string, apis []app.API, modules []string, wsOrigins []string, exposeAll bool, accessLog *rpcTypes.AccessLog) (net.Listener, *rpcTypes.Server, error) {
 
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
@@ -64,9 +67,10 @@ func StartWSEndpoint(endpoint string, apis []app.API, modules []string, wsOrigin
 	}
 	// Register all the APIs exposed by the services
 	handler := rpcTypes.NewServer()
+	handler.SetAccessLog(accessLog)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			if err := handler.RegisterName(api.Namespace, api.Service, RegisterOptions(api)...); err != nil {
 				return nil, nil, err
 			}
 			log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
@@ -85,12 +89,40 @@ func StartWSEndpoint(endpoint string, apis []app.API, modules []string, wsOrigin
 
 }
 
+// StartTCPEndpoint starts a raw TCP endpoint serving newline-delimited JSON-RPC.
+func StartTCPEndpoint(endpoint string, apis []app.API, modules []string, accessLog *rpcTypes.AccessLog) (net.Listener, *rpcTypes.Server, error) {
+	// Generate the whitelist based on the allowed modules
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	// Register all the APIs exposed by the services
+	handler := rpcTypes.NewServer()
+	handler.SetAccessLog(accessLog)
+	for _, api := range apis {
+		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+			if err := handler.RegisterName(api.Namespace, api.Service, RegisterOptions(api)...); err != nil {
+				return nil, nil, err
+			}
+			log.Debug("TCP registered", "namespace", api.Namespace)
+		}
+	}
+	// All APIs registered, start the TCP listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go handler.ServeListener(listener)
+	return listener, handler, nil
+}
+
 // StartIPCEndpoint starts an IPC endpoint.
-func StartIPCEndpoint(ipcEndpoint string, apis []app.API) (net.Listener, *rpcTypes.Server, error) {
+func StartIPCEndpoint(ipcEndpoint string, apis []app.API, accessLog *rpcTypes.AccessLog) (net.Listener, *rpcTypes.Server, error) {
 	// Register all the APIs exposed by the services.
 	handler := rpcTypes.NewServer()
+	handler.SetAccessLog(accessLog)
 	for _, api := range apis {
-		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+		if err := handler.RegisterName(api.Namespace, api.Service, RegisterOptions(api)...); err != nil {
 			return nil, nil, err
 		}
 		log.Debug("IPC registered", "namespace", api.Namespace)
@@ -103,3 +135,50 @@ func StartIPCEndpoint(ipcEndpoint string, apis []app.API) (net.Listener, *rpcTyp
 	go handler.ServeListener(listener)
 	return listener, handler, nil
 }
+
+// RegisterOptions returns the registration options described by the api.
+func RegisterOptions(api app.API) []rpcTypes.RegisterOption {
+	var opts []rpcTypes.RegisterOption
+	if api.Version != "" {
+		opts = append(opts, rpcTypes.WithVersion(api.Version))
+	}
+	for method, names := range api.ParamNames {
+		opts = append(opts, rpcTypes.WithParamNames(method, names...))
+	}
+	if api.MethodFilter != nil {
+		opts = append(opts, rpcTypes.WithMethodFilter(api.MethodFilter))
+	}
+	for method, timeout := range api.Timeouts {
+		opts = append(opts, rpcTypes.WithTimeout(method, timeout))
+	}
+	return opts
+}
+
+// withExecTimeouts returns the apis with the configured execution timeouts
+// applied. Configured timeouts override the ones declared by the services,
+// the configured default only applies to namespaces without any timeout.
+func withExecTimeouts(apis []app.API, config rpcTypes.ExecTimeouts) []app.API {
+	configured := make([]app.API, len(apis))
+	for i, api := range apis {
+		timeouts := make(map[string]time.Duration)
+		if timeout, ok := config.Methods[api.Namespace]; ok {
+			timeouts[""] = timeout
+		} else {
+			for method, timeout := range api.Timeouts {
+				timeouts[method] = timeout
+			}
+			if _, ok := timeouts[""]; !ok && config.Default > 0 {
+				timeouts[""] = config.Default
+			}
+		}
+		prefix := api.Namespace + rpcTypes.ServiceMethodSeparator
+		for name, timeout := range config.Methods {
+			if strings.HasPrefix(name, prefix) {
+				timeouts[strings.TrimPrefix(name, prefix)] = timeout
+			}
+		}
+		api.Timeouts = timeouts
+		configured[i] = api
+	}
+	return configured
+}
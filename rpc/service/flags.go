@@ -71,6 +71,25 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	TCPEnabledFlag = cli.BoolFlag{
+		Name:  "tcp",
+		Usage: "Enable the raw TCP-RPC server (newline-delimited JSON)",
+	}
+	TCPListenAddrFlag = cli.StringFlag{
+		Name:  "tcpaddr",
+		Usage: "TCP-RPC server listening interface",
+		Value: rpcTypes.DefaultTCPHost,
+	}
+	TCPPortFlag = cli.IntFlag{
+		Name:  "tcpport",
+		Usage: "TCP-RPC server listening port",
+		Value: rpcTypes.DefaultTCPPort,
+	}
+	TCPApiFlag = cli.StringFlag{
+		Name:  "tcpapi",
+		Usage: "API's offered over the TCP-RPC interface",
+		Value: "",
+	}
 	// RPC settings
 	RESTEnabledFlag = cli.BoolFlag{
 		Name:  "rest",
@@ -86,4 +105,26 @@ var (
 		Usage: "REST-RPC server listening port",
 		Value: rpcTypes.DefaultRestPort,
 	}
+	RPCAccessLogFlag = cli.BoolFlag{
+		Name:  "rpcaccesslog",
+		Usage: "Write an access log record for every RPC call",
+	}
+	RPCAccessLogPathFlag = cli.StringFlag{
+		Name:  "rpcaccesslogpath",
+		Usage: "File the RPC access log is appended to (default stdout)",
+		Value: "",
+	}
+	RPCAccessLogFormatFlag = cli.StringFlag{
+		Name:  "rpcaccesslogformat",
+		Usage: "Format of the RPC access log records (logfmt|json)",
+		Value: "logfmt",
+	}
+	RPCAccessLogParamsFlag = cli.BoolFlag{
+		Name:  "rpcaccesslogparams",
+		Usage: "Include call parameters in the RPC access log (account namespace is always redacted)",
+	}
+	RPCTimeoutFlag = cli.DurationFlag{
+		Name:  "rpctimeout",
+		Usage: "Execution timeout of RPC methods without a configured timeout (0 = unlimited)",
+	}
 )
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/drep-project/drepcli/app"
+	"github.com/drep-project/drepcli/log"
+	rpcComponent "github.com/drep-project/drepcli/rpc/component"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// upstreamForwarder relays the calls of a namespace to the upstream node
+type upstreamForwarder struct {
+	client *rpcComponent.Client
+}
+
+func (forwarder *upstreamForwarder) Forward(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	return forwarder.client.CallRawContext(ctx, method, params)
+}
+
+// runProxy serves the upstream namespaces and the local APIs until interrupted
+func (rpcService *RpcService) runProxy(executeContext *app.ExecuteContext, ctx *cli.Context) error {
+	upstreams := rpcComponent.SplitEndpoints(ctx.StringSlice(ProxyUpstreamFlag.Name)...)
+	if len(upstreams) == 0 {
+		return fmt.Errorf("You have to specify an upstream with --%s", ProxyUpstreamFlag.Name)
+	}
+	strategy, err := rpcComponent.ParseStrategy(ctx.String(ProxyStrategyFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err := executeContext.InitServices("cli"); err != nil {
+		return err
+	}
+
+	config := rpcComponent.DefaultPoolConfig
+	config.Strategy = strategy
+	client, err := rpcComponent.DialEndpoints(context.Background(), upstreams, config)
+	if err != nil {
+		return fmt.Errorf("Unable to attach to upstream drep: %v", err)
+	}
+	defer client.Close()
+	if ctx.Bool(ProxyCacheFlag.Name) {
+		config := rpcComponent.DefaultCacheConfig
+		config.Dir = rpcComponent.CacheDir(executeContext.CommonConfig.HomeDir, upstreams)
+		cache, err := rpcComponent.NewCache(config)
+		if err != nil {
+			return err
+		}
+		client.SetCache(cache)
+	}
+
+	apis, err := ProxyAPIs(client, executeContext.GetApis())
+	if err != nil {
+		return err
+	}
+	filter := NewMethodFilter(splitList(ctx.String(ProxyAllowFlag.Name)), splitList(ctx.String(ProxyDenyFlag.Name)))
+	for i := range apis {
+		apis[i].MethodFilter = filter
+	}
+
+	if !rpcService.RpcConfig.HTTPEnabled && !rpcService.RpcConfig.WSEnabled && !rpcService.RpcConfig.TCPEnabled {
+		rpcService.RpcConfig.HTTPEnabled = true
+	}
+	if err := rpcService.StartEndpoints(apis); err != nil {
+		return err
+	}
+	defer rpcService.Stop(executeContext)
+	rpcService.WatchReload()
+	log.Info("RPC proxy started", "upstream", strings.Join(upstreams, ","), "strategy", strategy)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	<-sigc
+	return nil
+}
+
+// ProxyAPIs returns the local APIs followed by a forwarded API for every namespace
+// the upstream offers. Namespaces served locally and the rpc metadata namespace
+// are not forwarded.
+func ProxyAPIs(client *rpcComponent.Client, local []app.API) ([]app.API, error) {
+	modules, err := client.SupportedModules()
+	if err != nil {
+		return nil, fmt.Errorf("upstream modules: %v", err)
+	}
+	skip := map[string]bool{rpcTypes.MetadataApi: true}
+	apis := make([]app.API, 0, len(local)+len(modules))
+	for _, api := range local {
+		skip[api.Namespace] = true
+		apis = append(apis, api)
+	}
+
+	namespaces := make([]string, 0, len(modules))
+	for namespace := range modules {
+		if !skip[namespace] {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	forwarder := &upstreamForwarder{client}
+	for _, namespace := range namespaces {
+		apis = append(apis, app.API{
+			Namespace: namespace,
+			Version:   modules[namespace],
+			Service:   forwarder,
+			Public:    true,
+		})
+	}
+	return apis, nil
+}
+
+// NewMethodFilter creates a filter exposing the methods matching one of the allow
+// patterns, or all methods if there are none, except those matching a deny
+// pattern. Patterns use path.Match syntax, a bare namespace matches all of its
+// methods. It returns nil if both lists are empty.
+func NewMethodFilter(allow, deny []string) func(method string) bool {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+	return func(method string) bool {
+		if len(allow) > 0 && !matchMethod(allow, method) {
+			return false
+		}
+		return !matchMethod(deny, method)
+	}
+}
+
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, rpcTypes.ServiceMethodSeparator) {
+			pattern += rpcTypes.ServiceMethodSeparator + "*"
+		}
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList is splitAndTrim dropping empty entries
+func splitList(input string) []string {
+	var result []string
+	for _, entry := range splitAndTrim(input) {
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
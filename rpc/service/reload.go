@@ -0,0 +1,245 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"github.com/drep-project/drepcli/log"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+var errStopped = errors.New("rpc service stopped")
+
+// WatchReload reloads the rpc configuration from the configuration file whenever
+// the process receives SIGHUP, until the service is stopped. Only long running
+// servers like the dev node and the proxy watch for it, so a hangup still ends
+// the console and scripts.
+func (rpcService *RpcService) WatchReload() {
+	rpcService.lock.Lock()
+	defer rpcService.lock.Unlock()
+	if rpcService.executeContext == nil || rpcService.stopped || rpcService.stopReload != nil {
+		return
+	}
+	rpcService.stopReload = make(chan struct{})
+	go rpcService.watchReload(rpcService.stopReload)
+}
+
+// watchReload reloads the rpc configuration whenever the process receives
+// SIGHUP, until quit is closed.
+func (rpcService *RpcService) watchReload(quit chan struct{}) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+	defer signal.Stop(sigc)
+	for {
+		select {
+		case <-sigc:
+			log.Info("Reloading RPC configuration", "file", rpcService.executeContext.ConfigFile)
+			if err := rpcService.ReloadConfigFile(); err != nil {
+				log.Error("RPC configuration reload failed", "err", err)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// ReloadConfigFile reads the rpc section of the configuration file again and
+// applies it with Reload. Command line flags keep overriding the file.
+func (rpcService *RpcService) ReloadConfigFile() error {
+	econtext := rpcService.executeContext
+	if err := econtext.ReloadConfig(); err != nil {
+		return err
+	}
+	config := &rpcTypes.RpcConfig{}
+	if err := json.Unmarshal(econtext.GetConfig(rpcService.Name()), config); err != nil {
+		return err
+	}
+	rpcService.lock.Lock()
+	defer rpcService.lock.Unlock()
+
+	// the flag setters work on the current config
+	old := rpcService.RpcConfig
+	rpcService.RpcConfig = config
+	rpcService.setRpcLog(econtext.CliContext, econtext.CommonConfig.HomeDir)
+	rpcService.RpcConfig = old
+	return rpcService.reload(config)
+}
+
+// Reload reconciles the running endpoints with config. Only the endpoints whose
+// settings changed are restarted, connections to the others are kept. An
+// endpoint which fails to start with its new settings is restarted with the
+// previous ones. Changed execution timeouts restart all endpoints.
+func (rpcService *RpcService) Reload(config *rpcTypes.RpcConfig) error {
+	rpcService.lock.Lock()
+	defer rpcService.lock.Unlock()
+	return rpcService.reload(config)
+}
+
+// reloadEndpoint describes how reload restarts one endpoint.
+type reloadEndpoint struct {
+	name    string
+	changed func(old, new *rpcTypes.RpcConfig) bool
+	start   func(config *rpcTypes.RpcConfig) error
+	stop    func()
+	keep    func(config, old *rpcTypes.RpcConfig) // copies the endpoint settings of old into config
+}
+
+func (rpcService *RpcService) reloadEndpoints() []reloadEndpoint {
+	return []reloadEndpoint{
+		{
+			name:    "inproc",
+			changed: func(old, new *rpcTypes.RpcConfig) bool { return false },
+			start: func(config *rpcTypes.RpcConfig) error {
+				return rpcService.StartInProc(rpcService.RpcAPIs)
+			},
+			stop: rpcService.StopInProc,
+			keep: func(config, old *rpcTypes.RpcConfig) {},
+		},
+		{
+			name:    "ipc",
+			changed: ipcChanged,
+			start: func(config *rpcTypes.RpcConfig) error {
+				rpcService.IpcEndpoint = config.IPCEndpoint()
+				return rpcService.StartIPC(rpcService.RpcAPIs)
+			},
+			stop: rpcService.StopIPC,
+			keep: func(config, old *rpcTypes.RpcConfig) {
+				config.IPCEnabled, config.IPCPath = old.IPCEnabled, old.IPCPath
+			},
+		},
+		{
+			name:    "http",
+			changed: httpChanged,
+			start: func(config *rpcTypes.RpcConfig) error {
+				rpcService.HttpEndpoint = config.HTTPEndpoint()
+				return rpcService.StartHTTP(rpcService.HttpEndpoint, rpcService.RpcAPIs, config.HTTPModules, config.HTTPCors, config.HTTPVirtualHosts, config.HTTPTimeouts)
+			},
+			stop: rpcService.StopHTTP,
+			keep: func(config, old *rpcTypes.RpcConfig) {
+				config.HTTPEnabled, config.HTTPHost, config.HTTPPort = old.HTTPEnabled, old.HTTPHost, old.HTTPPort
+				config.HTTPModules, config.HTTPCors, config.HTTPVirtualHosts = old.HTTPModules, old.HTTPCors, old.HTTPVirtualHosts
+				config.HTTPTimeouts = old.HTTPTimeouts
+			},
+		},
+		{
+			name:    "ws",
+			changed: wsChanged,
+			start: func(config *rpcTypes.RpcConfig) error {
+				rpcService.WsEndpoint = config.WSEndpoint()
+				return rpcService.StartWS(rpcService.WsEndpoint, rpcService.RpcAPIs, config.WSModules, config.WSOrigins, config.WSExposeAll)
+			},
+			stop: rpcService.StopWS,
+			keep: func(config, old *rpcTypes.RpcConfig) {
+				config.WSEnabled, config.WSHost, config.WSPort = old.WSEnabled, old.WSHost, old.WSPort
+				config.WSModules, config.WSOrigins, config.WSExposeAll = old.WSModules, old.WSOrigins, old.WSExposeAll
+			},
+		},
+		{
+			name:    "tcp",
+			changed: tcpChanged,
+			start: func(config *rpcTypes.RpcConfig) error {
+				rpcService.TcpEndpoint = config.TCPEndpoint()
+				return rpcService.StartTCP(rpcService.TcpEndpoint, rpcService.RpcAPIs, config.TCPModules)
+			},
+			stop: rpcService.StopTCP,
+			keep: func(config, old *rpcTypes.RpcConfig) {
+				config.TCPEnabled, config.TCPHost, config.TCPPort = old.TCPEnabled, old.TCPHost, old.TCPPort
+				config.TCPModules = old.TCPModules
+			},
+		},
+	}
+}
+
+func (rpcService *RpcService) reload(config *rpcTypes.RpcConfig) error {
+	if rpcService.stopped {
+		return errStopped
+	}
+	old := rpcService.RpcConfig
+	rpcService.RpcConfig = config
+
+	var failed []string
+	if !reflect.DeepEqual(old.AccessLog, config.AccessLog) {
+		if accessLog, err := rpcTypes.NewAccessLog(&config.AccessLog); err != nil {
+			failed = append(failed, fmt.Sprintf("access log: %v", err))
+			config.AccessLog = old.AccessLog
+		} else {
+			rpcService.useAccessLog(accessLog)
+		}
+	}
+	restartAll := !reflect.DeepEqual(old.ExecTimeouts, config.ExecTimeouts)
+	if restartAll {
+		log.Info("Restarting all RPC endpoints to apply the execution timeouts")
+		rpcService.RpcAPIs = withExecTimeouts(rpcService.apis, config.ExecTimeouts)
+	}
+	for _, endpoint := range rpcService.reloadEndpoints() {
+		if !restartAll && !endpoint.changed(old, config) {
+			continue
+		}
+		endpoint.stop()
+		err := endpoint.start(config)
+		if err == nil {
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", endpoint.name, err))
+		endpoint.keep(config, old)
+		if err := endpoint.start(config); err != nil {
+			log.Error("Failed to restore RPC endpoint", "endpoint", endpoint.name, "err", err)
+		} else {
+			log.Warn("RPC endpoint kept its previous settings", "endpoint", endpoint.name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restart RPC endpoints: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// useAccessLog switches all running endpoints to the access log.
+func (rpcService *RpcService) useAccessLog(accessLog *rpcTypes.AccessLog) {
+	rpcService.accessLog = accessLog
+	for _, handler := range []*rpcTypes.Server{rpcService.inprocHandler, rpcService.IpcHandler, rpcService.HttpHandler, rpcService.WsHandler, rpcService.TcpHandler} {
+		if handler != nil {
+			handler.SetAccessLog(accessLog)
+		}
+	}
+}
+
+func ipcChanged(old, new *rpcTypes.RpcConfig) bool {
+	return old.IPCEnabled != new.IPCEnabled || old.IPCEndpoint() != new.IPCEndpoint()
+}
+
+func httpChanged(old, new *rpcTypes.RpcConfig) bool {
+	return old.HTTPEnabled != new.HTTPEnabled || old.HTTPEndpoint() != new.HTTPEndpoint() ||
+		!equalStrings(old.HTTPModules, new.HTTPModules) || !equalStrings(old.HTTPCors, new.HTTPCors) ||
+		!equalStrings(old.HTTPVirtualHosts, new.HTTPVirtualHosts) || old.HTTPTimeouts != new.HTTPTimeouts
+}
+
+func wsChanged(old, new *rpcTypes.RpcConfig) bool {
+	return old.WSEnabled != new.WSEnabled || old.WSEndpoint() != new.WSEndpoint() ||
+		!equalStrings(old.WSModules, new.WSModules) || !equalStrings(old.WSOrigins, new.WSOrigins) ||
+		old.WSExposeAll != new.WSExposeAll
+}
+
+func tcpChanged(old, new *rpcTypes.RpcConfig) bool {
+	return old.TCPEnabled != new.TCPEnabled || old.TCPEndpoint() != new.TCPEndpoint() ||
+		!equalStrings(old.TCPModules, new.TCPModules)
+}
+
+// equalStrings compares two lists, treating nil and empty lists as equal.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
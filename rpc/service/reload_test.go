@@ -0,0 +1,171 @@
+package service
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drep-project/drepcli/app"
+	rpcComponent "github.com/drep-project/drepcli/rpc/component"
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+type ReloadTestService struct{}
+
+func (s *ReloadTestService) Echo(value string) string {
+	return value
+}
+
+func (s *ReloadTestService) Sleep(ms int) int {
+	time.Sleep(time.Duration(ms) * time.Millisecond)
+	return ms
+}
+
+// startReloadTestService serves the test API on a HTTP endpoint at a random port.
+func startReloadTestService(t *testing.T) *RpcService {
+	config := &rpcTypes.RpcConfig{HTTPEnabled: true, HTTPHost: "127.0.0.1", HTTPVirtualHosts: []string{"*"}}
+	rpcService := &RpcService{RpcConfig: config, HttpEndpoint: config.HTTPEndpoint()}
+	apis := []app.API{{Namespace: "test", Service: new(ReloadTestService), Public: true}}
+	if err := rpcService.StartEndpoints(apis); err != nil {
+		t.Fatal(err)
+	}
+	return rpcService
+}
+
+// callHTTP calls the method on the current HTTP endpoint of the service.
+func callHTTP(t *testing.T, rpcService *RpcService, result interface{}, method string, args ...interface{}) error {
+	client, err := rpcComponent.DialHTTP("http://" + rpcService.HttpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	return client.Call(result, method, args...)
+}
+
+func TestReloadRestartsChangedEndpoints(t *testing.T) {
+	rpcService := startReloadTestService(t)
+	defer rpcService.Stop(nil)
+
+	listener := rpcService.HttpListener
+	same := *rpcService.RpcConfig
+	if err := rpcService.Reload(&same); err != nil {
+		t.Fatal(err)
+	}
+	if rpcService.HttpListener != listener {
+		t.Errorf("unchanged HTTP endpoint restarted")
+	}
+
+	changed := same
+	changed.HTTPModules = []string{"test"}
+	if err := rpcService.Reload(&changed); err != nil {
+		t.Fatal(err)
+	}
+	if rpcService.HttpListener == listener {
+		t.Errorf("HTTP endpoint with changed modules not restarted")
+	}
+	var echo string
+	if err := callHTTP(t, rpcService, &echo, "test_echo", "hello"); err != nil || echo != "hello" {
+		t.Errorf("restarted endpoint returned %q, %v", echo, err)
+	}
+}
+
+func TestReloadKeepsEndpointOnFailure(t *testing.T) {
+	rpcService := startReloadTestService(t)
+	defer rpcService.Stop(nil)
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	config := *rpcService.RpcConfig
+	config.HTTPPort = busy.Addr().(*net.TCPAddr).Port
+	if err := rpcService.Reload(&config); err == nil {
+		t.Fatal("reload to a busy port succeeded")
+	}
+	if rpcService.HttpListener == nil {
+		t.Fatal("HTTP endpoint not restored")
+	}
+	if rpcService.RpcConfig.HTTPPort != 0 {
+		t.Errorf("config holds the port which failed, %d", rpcService.RpcConfig.HTTPPort)
+	}
+	var echo string
+	if err := callHTTP(t, rpcService, &echo, "test_echo", "hello"); err != nil || echo != "hello" {
+		t.Errorf("restored endpoint returned %q, %v", echo, err)
+	}
+}
+
+func TestReloadAfterStop(t *testing.T) {
+	rpcService := startReloadTestService(t)
+	rpcService.Stop(nil)
+
+	config := *rpcService.RpcConfig
+	config.HTTPModules = []string{"test"}
+	if err := rpcService.Reload(&config); err != errStopped {
+		t.Errorf("got error %v, want %v", err, errStopped)
+	}
+	if rpcService.HttpListener != nil {
+		t.Errorf("HTTP endpoint reopened after Stop")
+	}
+}
+
+func TestReloadExecTimeouts(t *testing.T) {
+	rpcService := startReloadTestService(t)
+	defer rpcService.Stop(nil)
+
+	config := *rpcService.RpcConfig
+	config.ExecTimeouts.Methods = map[string]time.Duration{"test_sleep": 20 * time.Millisecond}
+	if err := rpcService.Reload(&config); err != nil {
+		t.Fatal(err)
+	}
+	var ms int
+	if err := callHTTP(t, rpcService, &ms, "test_sleep", 500); err == nil {
+		t.Errorf("reloaded execution timeout not applied")
+	}
+}
+
+func TestReloadAccessLog(t *testing.T) {
+	rpcService := startReloadTestService(t)
+	defer rpcService.Stop(nil)
+
+	config := *rpcService.RpcConfig
+	config.AccessLog = rpcTypes.AccessLogConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "access.log")}
+	if err := rpcService.Reload(&config); err != nil {
+		t.Fatal(err)
+	}
+	var echo string
+	if err := callHTTP(t, rpcService, &echo, "test_echo", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(config.AccessLog.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "test_echo") {
+		t.Errorf("call missing in the reloaded access log:\n%s", content)
+	}
+}
+
+func TestWatchReloadOnlyWhenAsked(t *testing.T) {
+	rpcService := startReloadTestService(t)
+	rpcService.executeContext = new(app.ExecuteContext)
+	if rpcService.stopReload != nil {
+		t.Fatal("starting the endpoints watches for SIGHUP")
+	}
+	rpcService.WatchReload()
+	if rpcService.stopReload == nil {
+		t.Fatal("WatchReload doesn't watch for SIGHUP")
+	}
+	rpcService.Stop(nil)
+	if rpcService.stopReload != nil {
+		t.Error("stopping the service keeps watching for SIGHUP")
+	}
+	rpcService.WatchReload()
+	if rpcService.stopReload != nil {
+		t.Error("a stopped service watches for SIGHUP")
+	}
+}
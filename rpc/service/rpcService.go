@@ -1,7 +1,6 @@
 package service
 
 import (
-	"BlockChainTest/util/flags"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -25,6 +24,7 @@ const (
 
 type RpcService struct {
 	RpcAPIs       []app.API // List of APIs currently provided by the node
+	apis          []app.API // APIs as provided by the services, before applying the execution timeouts
 	RestApi       rpcTypes.RestDescription
 	inprocHandler *rpcTypes.Server // In-process RPC request handler to process the API requests
 
@@ -41,9 +41,19 @@ type RpcService struct {
 	WsListener net.Listener     // Websocket RPC listener socket to server API requests
 	WsHandler  *rpcTypes.Server // Websocket RPC request handler to process the API requests
 
+	TcpEndpoint string           // Raw TCP endpoint (interface + port) to listen at (empty = TCP disabled)
+	TcpListener net.Listener     // Raw TCP RPC listener socket to serve API requests
+	TcpHandler  *rpcTypes.Server // Raw TCP RPC request handler to process the API requests
+
 	RestEndpoint   string                       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	RestController *rpcComponent.RestController // Websocket RPC listener socket to server API requests
 
+	accessLog *rpcTypes.AccessLog // Access log shared by all endpoints (nil = disabled)
+
+	executeContext *app.ExecuteContext // used to reload the configuration file
+	stopReload     chan struct{}       // closed to stop watching for reload signals, nil if not watching
+	stopped        bool                // set by Stop, later reloads fail
+
 	lock      sync.RWMutex
 	RpcConfig *rpcTypes.RpcConfig
 }
@@ -58,12 +68,15 @@ func (rpcService *RpcService) Flags() []cli.Flag {
 	return []cli.Flag{
 		HTTPEnabledFlag, HTTPListenAddrFlag, HTTPPortFlag, HTTPCORSDomainFlag,
 		HTTPVirtualHostsFlag, HTTPApiFlag, IPCDisabledFlag, IPCPathFlag, WSEnabledFlag,
-		WSListenAddrFlag, WSPortFlag, WSApiFlag, WSAllowedOriginsFlag, RESTEnabledFlag,
-		RESTListenAddrFlag, RESTPortFlag,
+		WSListenAddrFlag, WSPortFlag, WSApiFlag, WSAllowedOriginsFlag, TCPEnabledFlag,
+		TCPListenAddrFlag, TCPPortFlag, TCPApiFlag, RESTEnabledFlag,
+		RESTListenAddrFlag, RESTPortFlag, RPCAccessLogFlag, RPCAccessLogPathFlag,
+		RPCAccessLogFormatFlag, RPCAccessLogParamsFlag, RPCTimeoutFlag,
 	}
 }
 
 func (rpcService *RpcService) Init(executeContext *app.ExecuteContext) error {
+	rpcService.executeContext = executeContext
 	phase := executeContext.GetConfig(rpcService.Name())
 	rpcService.RpcConfig = &rpcTypes.RpcConfig{}
 	err := json.Unmarshal(phase, rpcService.RpcConfig)
@@ -72,16 +85,29 @@ func (rpcService *RpcService) Init(executeContext *app.ExecuteContext) error {
 	}
 
 	rpcService.setRpcLog(executeContext.CliContext, executeContext.CommonConfig.HomeDir)
+	rpcService.accessLog, err = rpcTypes.NewAccessLog(&rpcService.RpcConfig.AccessLog)
+	if err != nil {
+		return err
+	}
 	rpcService.IpcEndpoint = rpcService.RpcConfig.IPCEndpoint()
 	rpcService.HttpEndpoint = rpcService.RpcConfig.HTTPEndpoint()
 	rpcService.WsEndpoint = rpcService.RpcConfig.WSEndpoint()
+	rpcService.TcpEndpoint = rpcService.RpcConfig.TCPEndpoint()
 	rpcService.RestEndpoint = rpcService.RpcConfig.RestEndpoint()
 	return nil
 }
 
 func (rpcService *RpcService) Start(executeContext *app.ExecuteContext) error {
-	// All API endpoints started successfully
-	rpcService.RpcAPIs = executeContext.GetApis() //api may delay
+	return rpcService.StartEndpoints(executeContext.GetApis()) //api may delay
+}
+
+// StartEndpoints starts all configured endpoints serving the given APIs.
+func (rpcService *RpcService) StartEndpoints(apis []app.API) error {
+	rpcService.lock.Lock()
+	rpcService.stopped = false
+	rpcService.lock.Unlock()
+	rpcService.apis = apis
+	rpcService.RpcAPIs = withExecTimeouts(apis, rpcService.RpcConfig.ExecTimeouts)
 	// Start the various API endpoints, terminating all in case of errors
 	if err := rpcService.StartInProc(rpcService.RpcAPIs); err != nil {
 		return err
@@ -101,6 +127,13 @@ func (rpcService *RpcService) Start(executeContext *app.ExecuteContext) error {
 		rpcService.StopInProc()
 		return err
 	}
+	if err := rpcService.StartTCP(rpcService.TcpEndpoint, rpcService.RpcAPIs, rpcService.RpcConfig.TCPModules); err != nil {
+		rpcService.StopWS()
+		rpcService.StopHTTP()
+		rpcService.StopIPC()
+		rpcService.StopInProc()
+		return err
+	}
 
 	/*
 		if err := rpcService.StartRest(rpcService.RestEndpoint,rpcService.RestApi); err != nil {
@@ -114,7 +147,13 @@ func (rpcService *RpcService) Start(executeContext *app.ExecuteContext) error {
 func (rpcService *RpcService) Stop(executeContext *app.ExecuteContext) error {
 	rpcService.lock.Lock()
 	defer rpcService.lock.Unlock()
+	rpcService.stopped = true
+	if rpcService.stopReload != nil {
+		close(rpcService.stopReload)
+		rpcService.stopReload = nil
+	}
 	// Terminate the API, services and the p2p server.
+	rpcService.StopTCP()
 	rpcService.StopWS()
 	rpcService.StopHTTP()
 	rpcService.StopIPC()
@@ -148,8 +187,9 @@ func (rpcService *RpcService) StopREST() {
 func (rpcService *RpcService) StartInProc(apis []app.API) error {
 	// Register all the APIs exposed by the services
 	handler := rpcTypes.NewServer()
+	handler.SetAccessLog(rpcService.accessLog)
 	for _, api := range apis {
-		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+		if err := handler.RegisterName(api.Namespace, api.Service, RegisterOptions(api)...); err != nil {
 			return err
 		}
 		log.Debug("InProc registered", "namespace", api.Namespace)
@@ -174,7 +214,7 @@ func (rpcService *RpcService) StartIPC(apis []app.API) error {
 	if rpcService.IpcEndpoint == "" {
 		return nil // IPC disabled.
 	}
-	listener, handler, err := StartIPCEndpoint(rpcService.IpcEndpoint, apis)
+	listener, handler, err := StartIPCEndpoint(rpcService.IpcEndpoint, apis, rpcService.accessLog)
 	if err != nil {
 		return err
 	}
@@ -207,7 +247,7 @@ func (rpcService *RpcService) StartHTTP(endpoint string, apis []app.API, modules
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	listener, handler, err := StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts, rpcService.accessLog)
 	if err != nil {
 		return err
 	}
@@ -242,7 +282,7 @@ func (rpcService *RpcService) StartWS(endpoint string, apis []app.API, modules [
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, handler, err := StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, rpcService.accessLog)
 	if err != nil {
 		return err
 	}
@@ -269,18 +309,60 @@ func (rpcService *RpcService) StopWS() {
 	}
 }
 
-// setRpc creates an rpc configuration from the set command line flags,
+// StartTCP initializes and starts the raw TCP RPC endpoint.
+func (rpcService *RpcService) StartTCP(endpoint string, apis []app.API, modules []string) error {
+	if !rpcService.RpcConfig.TCPEnabled {
+		return nil
+	}
+	// Short circuit if the TCP endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	listener, handler, err := StartTCPEndpoint(endpoint, apis, modules, rpcService.accessLog)
+	if err != nil {
+		return err
+	}
+	log.Info("TCP endpoint opened", "url", fmt.Sprintf("tcp://%s", listener.Addr()))
+	// All listeners booted successfully
+	rpcService.TcpEndpoint = endpoint
+	rpcService.TcpListener = listener
+	rpcService.TcpHandler = handler
+	return nil
+}
+
+// StopTCP terminates the raw TCP RPC endpoint.
+func (rpcService *RpcService) StopTCP() {
+	if rpcService.TcpListener != nil {
+		rpcService.TcpListener.Close()
+		rpcService.TcpListener = nil
+
+		log.Info("TCP endpoint closed", "url", fmt.Sprintf("tcp://%s", rpcService.TcpEndpoint))
+	}
+	if rpcService.TcpHandler != nil {
+		rpcService.TcpHandler.Stop()
+		rpcService.TcpHandler = nil
+	}
+}
+
+// setRpc creates an rpc configuration from the set command line flags. The flags
+// are applied on top of the rpc section of the config file, which is kept, so the
+// settings without flags, such as the access log, can be made in the file and are
+// picked up again when it is reloaded.
 func (rpcService *RpcService) setRpcLog(ctx *cli.Context, homeDir string) {
 	rpcService.setIPC(ctx, homeDir)
 	rpcService.setHTTP(ctx, homeDir)
 	rpcService.setWS(ctx, homeDir)
+	rpcService.setTCP(ctx)
 	rpcService.setRest(ctx, homeDir)
+	rpcService.setAccessLog(ctx)
+	if ctx.GlobalIsSet(RPCTimeoutFlag.Name) {
+		rpcService.RpcConfig.ExecTimeouts.Default = ctx.GlobalDuration(RPCTimeoutFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func (rpcService *RpcService) setIPC(ctx *cli.Context, homeDir string) {
-	rpcService.RpcConfig = &rpcTypes.RpcConfig{}
 	rpcService.RpcConfig.IPCEnabled = true
 	if ctx.GlobalBool(IPCDisabledFlag.Name) {
 		rpcService.RpcConfig.IPCEnabled = false
@@ -299,37 +381,37 @@ func (rpcService *RpcService) setIPC(ctx *cli.Context, homeDir string) {
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func (rpcService *RpcService) setHTTP(ctx *cli.Context, homeDir string) {
 	if !rpcService.RpcConfig.HTTPEnabled {
-		if ctx.GlobalBool(flags.HTTPEnabledFlag.Name) {
+		if ctx.GlobalBool(HTTPEnabledFlag.Name) {
 			rpcService.RpcConfig.HTTPEnabled = true
 		}
 	}
 
-	if ctx.GlobalIsSet(flags.HTTPListenAddrFlag.Name) {
-		rpcService.RpcConfig.HTTPHost = ctx.GlobalString(flags.HTTPListenAddrFlag.Name)
+	if ctx.GlobalIsSet(HTTPListenAddrFlag.Name) {
+		rpcService.RpcConfig.HTTPHost = ctx.GlobalString(HTTPListenAddrFlag.Name)
 	} else {
 		if rpcService.RpcConfig.HTTPHost == "" {
 			rpcService.RpcConfig.HTTPHost = rpcTypes.DefaultHTTPHost
 		}
 	}
 
-	if ctx.GlobalIsSet(flags.HTTPPortFlag.Name) {
-		rpcService.RpcConfig.HTTPPort = ctx.GlobalInt(flags.HTTPPortFlag.Name)
+	if ctx.GlobalIsSet(HTTPPortFlag.Name) {
+		rpcService.RpcConfig.HTTPPort = ctx.GlobalInt(HTTPPortFlag.Name)
 	}else{
 		if rpcService.RpcConfig.HTTPPort == 0 {
 			rpcService.RpcConfig.HTTPPort = rpcTypes.DefaultHTTPPort
 		}
 	}
 
-	if ctx.GlobalIsSet(flags.HTTPCORSDomainFlag.Name) {
-		rpcService.RpcConfig.HTTPCors = splitAndTrim(ctx.GlobalString(flags.HTTPCORSDomainFlag.Name))
+	if ctx.GlobalIsSet(HTTPCORSDomainFlag.Name) {
+		rpcService.RpcConfig.HTTPCors = splitAndTrim(ctx.GlobalString(HTTPCORSDomainFlag.Name))
 	}
 
-	if ctx.GlobalIsSet(flags.HTTPApiFlag.Name) {
-		rpcService.RpcConfig.HTTPModules = splitAndTrim(ctx.GlobalString(flags.HTTPApiFlag.Name))
+	if ctx.GlobalIsSet(HTTPApiFlag.Name) {
+		rpcService.RpcConfig.HTTPModules = splitAndTrim(ctx.GlobalString(HTTPApiFlag.Name))
 	}
 
-	if ctx.GlobalIsSet(flags.HTTPVirtualHostsFlag.Name) {
-		rpcService.RpcConfig.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(flags.HTTPVirtualHostsFlag.Name))
+	if ctx.GlobalIsSet(HTTPVirtualHostsFlag.Name) {
+		rpcService.RpcConfig.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(HTTPVirtualHostsFlag.Name))
 	} else {
 		if rpcService.RpcConfig.HTTPVirtualHosts == nil {
 			rpcService.RpcConfig.HTTPVirtualHosts = []string{"localhost"}
@@ -341,7 +423,7 @@ func (rpcService *RpcService) setHTTP(ctx *cli.Context, homeDir string) {
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func (rpcService *RpcService) setRest(ctx *cli.Context, homeDir string) {
 	if !rpcService.RpcConfig.RESTEnabled {
-		if ctx.GlobalBool(flags.RESTEnabledFlag.Name) {
+		if ctx.GlobalBool(RESTEnabledFlag.Name) {
 			rpcService.RpcConfig.RESTEnabled = true
 		}
 	}
@@ -367,7 +449,7 @@ func (rpcService *RpcService) setRest(ctx *cli.Context, homeDir string) {
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func (rpcService *RpcService) setWS(ctx *cli.Context, homeDir string) {
 	if !rpcService.RpcConfig.WSEnabled {
-		if ctx.GlobalBool(flags.WSEnabledFlag.Name) {
+		if ctx.GlobalBool(WSEnabledFlag.Name) {
 			rpcService.RpcConfig.WSEnabled = true
 		}
 	}
@@ -397,6 +479,49 @@ func (rpcService *RpcService) setWS(ctx *cli.Context, homeDir string) {
 	}
 }
 
+// setTCP creates the raw TCP RPC listener interface string from the set
+// command line flags.
+func (rpcService *RpcService) setTCP(ctx *cli.Context) {
+	if ctx.GlobalBool(TCPEnabledFlag.Name) {
+		rpcService.RpcConfig.TCPEnabled = true
+	}
+
+	if ctx.GlobalIsSet(TCPListenAddrFlag.Name) {
+		rpcService.RpcConfig.TCPHost = ctx.GlobalString(TCPListenAddrFlag.Name)
+	} else if rpcService.RpcConfig.TCPHost == "" {
+		rpcService.RpcConfig.TCPHost = rpcTypes.DefaultTCPHost
+	}
+
+	if ctx.GlobalIsSet(TCPPortFlag.Name) {
+		rpcService.RpcConfig.TCPPort = ctx.GlobalInt(TCPPortFlag.Name)
+	} else if rpcService.RpcConfig.TCPPort == 0 {
+		rpcService.RpcConfig.TCPPort = rpcTypes.DefaultTCPPort
+	}
+
+	if ctx.GlobalIsSet(TCPApiFlag.Name) {
+		rpcService.RpcConfig.TCPModules = splitAndTrim(ctx.GlobalString(TCPApiFlag.Name))
+	}
+}
+
+// setAccessLog applies the access log command line flags on top of the
+// configured access log settings.
+func (rpcService *RpcService) setAccessLog(ctx *cli.Context) {
+	config := &rpcService.RpcConfig.AccessLog
+	if ctx.GlobalBool(RPCAccessLogFlag.Name) {
+		config.Enabled = true
+	}
+	if ctx.GlobalIsSet(RPCAccessLogPathFlag.Name) {
+		config.Enabled = true
+		config.Path = ctx.GlobalString(RPCAccessLogPathFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAccessLogFormatFlag.Name) {
+		config.Format = ctx.GlobalString(RPCAccessLogFormatFlag.Name)
+	}
+	if ctx.GlobalBool(RPCAccessLogParamsFlag.Name) {
+		config.LogParams = true
+	}
+}
+
 // checkExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"encoding/json"
+	"flag"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+
+	rpcTypes "github.com/drep-project/drepcli/rpc/types"
+)
+
+// newTestContext returns a context with the flags of the rpc service set from args.
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range new(RpcService).Flags() {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestSetRpcLogKeepsConfigFile(t *testing.T) {
+	config := &rpcTypes.RpcConfig{}
+	file := `{"HTTPEnabled": true, "HTTPHost": "0.0.0.0", "HTTPPort": 18545, "AccessLog": {"Enabled": true, "Format": "json"}}`
+	if err := json.Unmarshal([]byte(file), config); err != nil {
+		t.Fatal(err)
+	}
+	rpcService := &RpcService{RpcConfig: config}
+	rpcService.setRpcLog(newTestContext(t, "--httpport", "18546"), t.TempDir())
+
+	if !config.HTTPEnabled || config.HTTPHost != "0.0.0.0" {
+		t.Errorf("HTTP settings of the config file lost: enabled %v, host %q", config.HTTPEnabled, config.HTTPHost)
+	}
+	if config.HTTPPort != 18546 {
+		t.Errorf("flag didn't override the port of the config file, got %d", config.HTTPPort)
+	}
+	if !config.AccessLog.Enabled || config.AccessLog.Format != "json" {
+		t.Errorf("access log settings of the config file lost: %+v", config.AccessLog)
+	}
+	if !config.IPCEnabled || config.IPCPath == "" {
+		t.Errorf("IPC defaults not applied: enabled %v, path %q", config.IPCEnabled, config.IPCPath)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/drep-project/drepcli/log"
+)
+
+const (
+	TransportHTTP   = "http"
+	TransportWS     = "ws"
+	TransportIPC    = "ipc"
+	TransportTCP    = "tcp"
+	TransportInProc = "inproc"
+
+	redactedParams = "<redacted>"
+)
+
+// AccessLogConfig configures the per call access log of the RPC servers.
+type AccessLogConfig struct {
+	// Enabled switches the access log on, it is off by default.
+	Enabled bool
+
+	// Path is the file the access log is appended to. An empty path writes the
+	// log to stdout.
+	Path string `json:",omitempty"`
+
+	// Format selects the record encoding, either "logfmt" (default) or "json".
+	Format string `json:",omitempty"`
+
+	// LogParams adds the raw call parameters to every record.
+	LogParams bool
+
+	// RedactNamespaces lists the namespaces whose parameters are never written
+	// out, even if LogParams is set. Defaults to the account namespace since its
+	// methods take passwords.
+	RedactNamespaces []string `json:",omitempty"`
+}
+
+// DefaultRedactNamespaces are the namespaces redacted when the config names none.
+var DefaultRedactNamespaces = []string{"account"}
+
+// handler builds the log handler the access records are written to.
+func (config *AccessLogConfig) handler() (log.Handler, error) {
+	var format log.Format
+	switch strings.ToLower(config.Format) {
+	case "", "logfmt":
+		format = log.LogfmtFormat()
+	case "json":
+		format = log.JSONFormat()
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", config.Format)
+	}
+	if config.Path == "" {
+		return log.StreamHandler(os.Stdout, format), nil
+	}
+	return log.FileHandler(config.Path, format)
+}
+
+// AccessLog writes one structured record for every call a Server executes.
+// A single AccessLog can be shared by several servers.
+type AccessLog struct {
+	logger    log.Logger
+	logParams bool
+	redact    map[string]bool
+}
+
+// requestIDKey is used to store the request ID within the call context.
+type requestIDKey struct{}
+
+// transportKey is used to store the name of the transport a call arrived on.
+type transportKey struct{}
+
+// remoteKey is used to store the remote address of the connection a call
+// arrived on.
+type remoteKey struct{}
+
+// RequestIDFromContext returns the ID the server assigned to the call being
+// served with ctx. Service methods can add it to their own log records to
+// correlate them with the access log.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// TransportFromContext returns the transport (http, ws, ipc, tcp, inproc) a call
+// was received on.
+func TransportFromContext(ctx context.Context) string {
+	if transport, ok := ctx.Value(transportKey{}).(string); ok {
+		return transport
+	}
+	return TransportInProc
+}
+
+// withTransport annotates ctx with the transport and remote address of a connection.
+func withTransport(ctx context.Context, transport string, remote string) context.Context {
+	ctx = context.WithValue(ctx, transportKey{}, transport)
+	if remote != "" {
+		ctx = context.WithValue(ctx, remoteKey{}, remote)
+	}
+	return ctx
+}
+
+// withRequestID assigns a fresh request ID to the call served with ctx.
+func withRequestID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, string(NewID()))
+}
+
+// NewAccessLog creates the access log described by config. It returns nil if
+// the access log is disabled.
+func NewAccessLog(config *AccessLogConfig) (*AccessLog, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+	h, err := config.handler()
+	if err != nil {
+		return nil, err
+	}
+	redact := config.RedactNamespaces
+	if len(redact) == 0 {
+		redact = DefaultRedactNamespaces
+	}
+	return NewAccessLogWithHandler(h, config.LogParams, redact), nil
+}
+
+// NewAccessLogWithHandler creates an access log writing its records to h.
+// Parameters are only logged if logParams is set and are always redacted for
+// the given namespaces.
+func NewAccessLogWithHandler(h log.Handler, logParams bool, redactNamespaces []string) *AccessLog {
+	redact := make(map[string]bool)
+	for _, namespace := range redactNamespaces {
+		redact[namespace] = true
+	}
+	logger := log.New()
+	logger.SetHandler(h)
+	return &AccessLog{logger: logger, logParams: logParams, redact: redact}
+}
+
+// SetAccessLog enables the access log of the server, nil disables it. The log
+// may be switched while the server is serving requests.
+func (s *Server) SetAccessLog(l *AccessLog) {
+	s.accessLog.Store(l)
+}
+
+func (s *Server) getAccessLog() *AccessLog {
+	l, _ := s.accessLog.Load().(*AccessLog)
+	return l
+}
+
+// record writes the access record of a single executed request.
+func (l *AccessLog) record(ctx context.Context, req *serverRequest, start time.Time, response interface{}) {
+	remote, _ := ctx.Value(remoteKey{}).(string)
+	reqid, _ := RequestIDFromContext(ctx)
+	params, _ := req.params.(json.RawMessage)
+
+	fields := []interface{}{
+		"reqid", reqid,
+		"transport", TransportFromContext(ctx),
+		"remote", remote,
+		"method", req.method,
+		"size", len(params),
+		"duration", time.Since(start),
+		"code", responseErrorCode(response),
+	}
+	if l.logParams && len(params) > 0 {
+		namespace := strings.SplitN(req.method, ServiceMethodSeparator, 2)[0]
+		if l.redact[namespace] {
+			fields = append(fields, "params", redactedParams)
+		} else {
+			fields = append(fields, "params", string(params))
+		}
+	}
+	l.logger.Info("rpc call", fields...)
+}
+
+// responseErrorCode returns the error code carried by a response created by
+// a ServerCodec, or 0 for successful responses.
+func responseErrorCode(response interface{}) int {
+	if errResp, ok := response.(*jsonErrResponse); ok {
+		return errResp.Error.Code
+	}
+	return 0
+}
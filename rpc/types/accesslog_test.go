@@ -0,0 +1,143 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/drep-project/drepcli/log"
+)
+
+type AccessLogTestService struct{}
+
+func (s *AccessLogTestService) RequestID(ctx context.Context) string {
+	id, _ := RequestIDFromContext(ctx)
+	return id
+}
+
+func (s *AccessLogTestService) Unlock(password string) bool {
+	return true
+}
+
+type accessLogRecorder struct {
+	mu      sync.Mutex
+	records []map[string]interface{}
+}
+
+func (r *accessLogRecorder) handler() log.Handler {
+	return log.FuncHandler(func(rec *log.Record) error {
+		fields := make(map[string]interface{})
+		for i := 0; i+1 < len(rec.Ctx); i += 2 {
+			fields[rec.Ctx[i].(string)] = rec.Ctx[i+1]
+		}
+		r.mu.Lock()
+		r.records = append(r.records, fields)
+		r.mu.Unlock()
+		return nil
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	recorder := new(accessLogRecorder)
+	server := NewServer()
+	server.SetAccessLog(NewAccessLogWithHandler(recorder.handler(), true, DefaultRedactNamespaces))
+	if err := server.RegisterName("test", new(AccessLogTestService)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("account", new(AccessLogTestService)); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	out := json.NewEncoder(clientConn)
+	in := json.NewDecoder(clientConn)
+	requests := []map[string]interface{}{
+		{"id": 1, "jsonrpc": "2.0", "method": "test_requestID", "params": []interface{}{}},
+		{"id": 2, "jsonrpc": "2.0", "method": "account_unlock", "params": []interface{}{"secret"}},
+		{"id": 3, "jsonrpc": "2.0", "method": "test_missing", "params": []interface{}{}},
+	}
+	var requestID string
+	for i, request := range requests {
+		if err := out.Encode(request); err != nil {
+			t.Fatal(err)
+		}
+		var response jsonSuccessResponse
+		if err := in.Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			requestID, _ = response.Result.(string)
+		}
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.records) != len(requests) {
+		t.Fatalf("expected %d access records, got %d", len(requests), len(recorder.records))
+	}
+	if requestID == "" || recorder.records[0]["reqid"] != requestID {
+		t.Errorf("request ID mismatch: service saw %q, access log has %v", requestID, recorder.records[0]["reqid"])
+	}
+	if recorder.records[0]["transport"] != TransportInProc {
+		t.Errorf("expected transport %q, got %v", TransportInProc, recorder.records[0]["transport"])
+	}
+	if recorder.records[1]["method"] != "account_unlock" || recorder.records[1]["params"] != redactedParams {
+		t.Errorf("expected redacted account params, got %v", recorder.records[1])
+	}
+	if recorder.records[2]["code"] != (&MethodNotFoundError{}).ErrorCode() {
+		t.Errorf("expected method not found code, got %v", recorder.records[2]["code"])
+	}
+}
+
+func TestAccessLogRemote(t *testing.T) {
+	recorder := new(accessLogRecorder)
+	server := NewServer()
+	server.SetAccessLog(NewAccessLogWithHandler(recorder.handler(), false, DefaultRedactNamespaces))
+	if err := server.RegisterName("test", new(AccessLogTestService)); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	ctx := withTransport(context.Background(), TransportTCP, "10.0.0.1:40000")
+	go server.serveCodec(ctx, NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	request := map[string]interface{}{"id": 1, "jsonrpc": "2.0", "method": "test_requestID", "params": []interface{}{}}
+	if err := json.NewEncoder(clientConn).Encode(request); err != nil {
+		t.Fatal(err)
+	}
+	var response jsonSuccessResponse
+	if err := json.NewDecoder(clientConn).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.records) != 1 {
+		t.Fatalf("expected 1 access record, got %d", len(recorder.records))
+	}
+	if record := recorder.records[0]; record["transport"] != TransportTCP || record["remote"] != "10.0.0.1:40000" {
+		t.Errorf("expected the tcp transport and remote address, got %v", record)
+	}
+}
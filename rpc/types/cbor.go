@@ -0,0 +1,859 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/websocket"
+)
+
+// The CBOR codec carries the same JSON-RPC 2.0 objects as the JSON codec, encoded
+// as CBOR (RFC 7049). Byte slices are sent as raw byte strings instead of base64
+// text, which makes block dumps considerably smaller. Every CBOR document maps
+// to the JSON document encoding/json would produce for the same value, so
+// service methods and clients keep working with JSON types.
+const (
+	ContentTypeCBOR = "application/cbor"
+	CBORSubprotocol = "drep-cbor" // websocket subprotocol selecting the CBOR codec
+	JSONSubprotocol = "drep-json" // websocket subprotocol selecting the JSON codec
+)
+
+// CBORPreamble opens a CBOR stream on IPC and TCP connections. It is the CBOR
+// self-describe tag, which never starts a JSON document. The server echoes it to
+// acknowledge the codec, after which both sides exchange length-prefixed frames.
+var CBORPreamble = []byte{0xd9, 0xd9, 0xf7}
+
+// MaxCBORFrameSize bounds the frames a client accepts from a server.
+const MaxCBORFrameSize = 128 << 20
+
+const maxCBORDepth = 10000
+
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// bignum tags
+const (
+	cborPosBignum = 2
+	cborNegBignum = 3
+)
+
+const (
+	cborFalse   = 0xf4
+	cborTrue    = 0xf5
+	cborNull    = 0xf6
+	cborFloat64 = 0xfb
+)
+
+var (
+	errCBORTruncated  = errors.New("cbor: unexpected end of data")
+	errCBORIndefinite = errors.New("cbor: indefinite-length items are not supported")
+	errCBORDepth      = errors.New("cbor: exceeded max depth")
+)
+
+// ReadCBORFrame reads a length-prefixed frame of at most limit bytes.
+func ReadCBORFrame(r io.Reader, limit int) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if int64(size) > int64(limit) {
+		return nil, fmt.Errorf("cbor: frame too large (%d>%d)", size, limit)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// WriteCBORFrame writes payload with its length prefix in a single write.
+func WriteCBORFrame(w io.Writer, payload []byte) error {
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// NewCBORCodec creates a new RPC server codec exchanging CBOR encoded JSON-RPC
+// 2.0 messages in length-prefixed frames. The stream preamble must already be
+// consumed.
+func NewCBORCodec(rwc io.ReadWriteCloser) ServerCodec {
+	encode := func(v interface{}) error {
+		payload, err := MarshalCBOR(v)
+		if err != nil {
+			return err
+		}
+		return WriteCBORFrame(rwc, payload)
+	}
+	decode := func(v interface{}) error {
+		frame, err := ReadCBORFrame(rwc, maxRequestContentLength)
+		if err != nil {
+			return err
+		}
+		return UnmarshalCBOR(frame, v)
+	}
+	return NewCodec(rwc, encode, decode)
+}
+
+// newCBORHTTPCodec creates a codec for a single CBOR request read from a HTTP
+// body. The request and the response aren't framed.
+func newCBORHTTPCodec(rwc io.ReadWriteCloser) ServerCodec {
+	var read bool
+	encode := func(v interface{}) error {
+		payload, err := MarshalCBOR(v)
+		if err != nil {
+			return err
+		}
+		_, err = rwc.Write(payload)
+		return err
+	}
+	decode := func(v interface{}) error {
+		if read {
+			return io.EOF
+		}
+		read = true
+		body, err := ioutil.ReadAll(rwc)
+		if err != nil {
+			return err
+		}
+		return UnmarshalCBOR(body, v)
+	}
+	return NewCodec(rwc, encode, decode)
+}
+
+// websocketCBORCodec sends every message as a binary frame.
+var websocketCBORCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		msg, err := MarshalCBOR(v)
+		return msg, websocket.BinaryFrame, err
+	},
+	Unmarshal: func(msg []byte, payloadType byte, v interface{}) error {
+		return UnmarshalCBOR(msg, v)
+	},
+}
+
+// selectSubprotocol picks the codec subprotocol offered by a websocket client.
+// The offer is left untouched if it contains none of them.
+func selectSubprotocol(cfg *websocket.Config) {
+	for _, protocol := range cfg.Protocol {
+		if protocol == CBORSubprotocol || protocol == JSONSubprotocol {
+			cfg.Protocol = []string{protocol}
+			return
+		}
+	}
+}
+
+// newStreamCodec sniffs the first byte of a stream connection and returns the
+// codec the client asked for, acknowledging CBOR streams with the preamble.
+func newStreamCodec(conn net.Conn) (ServerCodec, error) {
+	br := bufio.NewReader(conn)
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	rwc := &bufferedConn{Conn: conn, r: br}
+	if first[0] != CBORPreamble[0] {
+		return NewJSONCodec(rwc), nil
+	}
+	preamble := make([]byte, len(CBORPreamble))
+	if _, err := io.ReadFull(br, preamble); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(preamble, CBORPreamble) {
+		return nil, fmt.Errorf("invalid stream preamble %x", preamble)
+	}
+	if _, err := conn.Write(CBORPreamble); err != nil {
+		return nil, err
+	}
+	return NewCBORCodec(rwc), nil
+}
+
+// bufferedConn reads through the reader used to sniff the codec.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// MarshalCBOR returns the CBOR encoding of v. Apart from byte slices, which are
+// encoded as byte strings, it follows the rules of json.Marshal: struct tags and
+// json.Marshaler implementations are honoured.
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	e := &cborEncoder{buf: make([]byte, 0, 256)}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, major|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, major|27)
+		e.buf = append(e.buf, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(e.buf[len(e.buf)-8:], n)
+	}
+}
+
+func (e *cborEncoder) int(n int64) {
+	if n < 0 {
+		e.head(cborNegInt, uint64(-(n + 1)))
+	} else {
+		e.head(cborUint, uint64(n))
+	}
+}
+
+func (e *cborEncoder) float(f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("cbor: unsupported value %v", f)
+	}
+	e.buf = append(e.buf, cborFloat64)
+	e.buf = append(e.buf, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(e.buf[len(e.buf)-8:], math.Float64bits(f))
+	return nil
+}
+
+func (e *cborEncoder) text(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// number encodes a JSON number literal, as an integer if possible. Integers
+// exceeding 64 bits are encoded as bignums to keep their precision.
+func (e *cborEncoder) number(literal string) error {
+	if n, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		e.int(n)
+		return nil
+	}
+	if n, err := strconv.ParseUint(literal, 10, 64); err == nil {
+		e.head(cborUint, n)
+		return nil
+	}
+	if n, ok := new(big.Int).SetString(literal, 10); ok {
+		if n.Sign() < 0 {
+			e.head(cborTag, cborNegBignum)
+			n.Not(n) // -1 - n
+		} else {
+			e.head(cborTag, cborPosBignum)
+		}
+		b := n.Bytes()
+		e.head(cborBytes, uint64(len(b)))
+		e.buf = append(e.buf, b...)
+		return nil
+	}
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return fmt.Errorf("cbor: invalid number literal %q", literal)
+	}
+	return e.float(f)
+}
+
+// json encodes the JSON document produced by a json.Marshaler.
+func (e *cborEncoder) json(doc []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	return e.generic(v)
+}
+
+// generic encodes the values produced by decoding JSON into an interface{}.
+func (e *cborEncoder) generic(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, cborNull)
+	case bool:
+		if v {
+			e.buf = append(e.buf, cborTrue)
+		} else {
+			e.buf = append(e.buf, cborFalse)
+		}
+	case json.Number:
+		return e.number(string(v))
+	case string:
+		e.text(v)
+	case []interface{}:
+		e.head(cborArray, uint64(len(v)))
+		for _, elem := range v {
+			if err := e.generic(elem); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.head(cborMap, uint64(len(v)))
+		for _, key := range keys {
+			e.text(key)
+			if err := e.generic(v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unexpected JSON value %T", v)
+	}
+	return nil
+}
+
+// fallback encodes v through encoding/json, for the types the encoder doesn't
+// replicate itself.
+func (e *cborEncoder) fallback(v reflect.Value) error {
+	doc, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	return e.json(doc)
+}
+
+func (e *cborEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, cborNull)
+		return nil
+	}
+	t := v.Type()
+	if t == jsonNumberType {
+		literal := v.String()
+		if literal == "" {
+			literal = "0"
+		}
+		return e.number(literal)
+	}
+	if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		v, t = v.Addr(), v.Addr().Type()
+	}
+	if t.Implements(jsonMarshalerType) {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		doc, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return err
+		}
+		return e.json(doc)
+	}
+	if t.Implements(textMarshalerType) {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.text(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, cborTrue)
+		} else {
+			e.buf = append(e.buf, cborFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(cborUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return e.float(v.Float())
+	case reflect.String:
+		e.text(v.String())
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		elem := t.Elem()
+		if elem.Kind() == reflect.Uint8 && !reflect.PtrTo(elem).Implements(jsonMarshalerType) && !reflect.PtrTo(elem).Implements(textMarshalerType) {
+			e.head(cborBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		e.head(cborArray, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String || t.Key().Implements(textMarshalerType) {
+			return e.fallback(v)
+		}
+		if v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		e.head(cborMap, uint64(len(keys)))
+		for _, key := range keys {
+			e.text(key.String())
+			if err := e.encode(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields, ok := cborStructFields(t)
+		if !ok {
+			return e.fallback(v)
+		}
+		var present []int
+		for i, field := range fields {
+			if !field.omitEmpty || !isEmptyValue(v.Field(field.index)) {
+				present = append(present, i)
+			}
+		}
+		e.head(cborMap, uint64(len(present)))
+		for _, i := range present {
+			e.text(fields[i].name)
+			if err := e.encode(v.Field(fields[i].index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported type %v", t)
+	}
+	return nil
+}
+
+type cborField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+type cborStructInfo struct {
+	fields []cborField
+	ok     bool // false if the struct needs the encoding/json field rules
+}
+
+var cborStructCache sync.Map // reflect.Type => cborStructInfo
+
+// cborStructFields returns the JSON fields of a struct type. Structs embedding
+// other types or using the ",string" option are left to encoding/json.
+func cborStructFields(t reflect.Type) ([]cborField, bool) {
+	if info, ok := cborStructCache.Load(t); ok {
+		return info.(cborStructInfo).fields, info.(cborStructInfo).ok
+	}
+	info := cborStructInfo{ok: true}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			info = cborStructInfo{}
+			break
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		if name == "" {
+			name = field.Name
+		}
+		var omitEmpty bool
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "string":
+				info.ok = false
+			}
+		}
+		info.fields = append(info.fields, cborField{name: name, index: i, omitEmpty: omitEmpty})
+	}
+	if !info.ok {
+		info.fields = nil
+	}
+	cborStructCache.Store(t, info)
+	return info.fields, info.ok
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// JSONToCBOR converts a JSON document into CBOR.
+func JSONToCBOR(doc []byte) ([]byte, error) {
+	e := &cborEncoder{buf: make([]byte, 0, len(doc))}
+	if err := e.json(doc); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// CBORToJSON converts a CBOR document into the JSON document encoding/json
+// produces for the same value. Byte strings become base64 text.
+func CBORToJSON(data []byte) ([]byte, error) {
+	d := &cborDecoder{data: data, out: make([]byte, 0, len(data)*3/2)}
+	if err := d.value(0); err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("cbor: trailing data after document")
+	}
+	return d.out, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+	out  []byte
+}
+
+// head reads an initial byte and its argument.
+func (d *cborDecoder) head() (major, info byte, n uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, errCBORTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b&0xe0, b&0x1f
+	size := 0
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	case info == 31:
+		return 0, 0, 0, errCBORIndefinite
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: invalid additional info %d", info)
+	}
+	if len(d.data)-d.pos < size {
+		return 0, 0, 0, errCBORTruncated
+	}
+	for _, b := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(b)
+	}
+	d.pos += size
+	return major, info, n, nil
+}
+
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBORTruncated
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *cborDecoder) value(depth int) error {
+	if depth > maxCBORDepth {
+		return errCBORDepth
+	}
+	major, info, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborUint:
+		d.out = strconv.AppendUint(d.out, n, 10)
+	case cborNegInt:
+		if n < math.MaxInt64 {
+			d.out = strconv.AppendInt(d.out, -1-int64(n), 10)
+		} else {
+			neg := new(big.Int).SetUint64(n)
+			d.out = append(d.out, neg.Add(neg, big.NewInt(1)).Neg(neg).String()...)
+		}
+	case cborBytes:
+		b, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		d.out = append(d.out, '"')
+		start := len(d.out)
+		d.out = append(d.out, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+		base64.StdEncoding.Encode(d.out[start:], b)
+		d.out = append(d.out, '"')
+	case cborText:
+		b, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		d.out = appendJSONString(d.out, b)
+	case cborArray:
+		if n > uint64(len(d.data)-d.pos) {
+			return errCBORTruncated
+		}
+		d.out = append(d.out, '[')
+		for i := uint64(0); i < n; i++ {
+			if i > 0 {
+				d.out = append(d.out, ',')
+			}
+			if err := d.value(depth + 1); err != nil {
+				return err
+			}
+		}
+		d.out = append(d.out, ']')
+	case cborMap:
+		if n > uint64(len(d.data)-d.pos) {
+			return errCBORTruncated
+		}
+		d.out = append(d.out, '{')
+		for i := uint64(0); i < n; i++ {
+			if i > 0 {
+				d.out = append(d.out, ',')
+			}
+			if err := d.key(); err != nil {
+				return err
+			}
+			d.out = append(d.out, ':')
+			if err := d.value(depth + 1); err != nil {
+				return err
+			}
+		}
+		d.out = append(d.out, '}')
+	case cborTag:
+		if n == cborPosBignum || n == cborNegBignum {
+			return d.bignum(n == cborNegBignum)
+		}
+		return d.value(depth + 1) // other tags carry no meaning in JSON
+	case cborSimple:
+		return d.simple(info, n)
+	}
+	return nil
+}
+
+// bignum converts the byte string of a bignum into a number literal.
+func (d *cborDecoder) bignum(negative bool) error {
+	major, _, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	if major != cborBytes {
+		return errors.New("cbor: bignum is not a byte string")
+	}
+	b, err := d.bytes(n)
+	if err != nil {
+		return err
+	}
+	num := new(big.Int).SetBytes(b)
+	if negative {
+		num.Not(num) // -1 - n
+	}
+	d.out = append(d.out, num.String()...)
+	return nil
+}
+
+// key converts a map key, which has to be a text string or an integer.
+func (d *cborDecoder) key() error {
+	major, _, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborText:
+		b, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		d.out = appendJSONString(d.out, b)
+	case cborUint:
+		d.out = append(d.out, '"')
+		d.out = strconv.AppendUint(d.out, n, 10)
+		d.out = append(d.out, '"')
+	case cborNegInt:
+		if n >= math.MaxInt64 {
+			return errors.New("cbor: map key out of range")
+		}
+		d.out = append(d.out, '"')
+		d.out = strconv.AppendInt(d.out, -1-int64(n), 10)
+		d.out = append(d.out, '"')
+	default:
+		return fmt.Errorf("cbor: unsupported map key type %d", major>>5)
+	}
+	return nil
+}
+
+func (d *cborDecoder) simple(info byte, n uint64) error {
+	var f float64
+	switch info {
+	case 20:
+		d.out = append(d.out, "false"...)
+		return nil
+	case 21:
+		d.out = append(d.out, "true"...)
+		return nil
+	case 22, 23: // null, undefined
+		d.out = append(d.out, "null"...)
+		return nil
+	case 25:
+		f = halfToFloat(uint16(n))
+	case 26:
+		f = float64(math.Float32frombits(uint32(n)))
+	case 27:
+		f = math.Float64frombits(n)
+	default:
+		return fmt.Errorf("cbor: unsupported simple value %d", n)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("cbor: unsupported value %v", f)
+	}
+	d.out = appendFloat(d.out, f)
+	return nil
+}
+
+// appendFloat appends f formatted like encoding/json.
+func appendFloat(out []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	out = strconv.AppendFloat(out, f, format, -1, 64)
+	if n := len(out); format == 'e' && n >= 4 && out[n-4] == 'e' && out[n-3] == '-' && out[n-2] == '0' {
+		out = append(out[:n-2], out[n-1]) // e-07 => e-7
+	}
+	return out
+}
+
+func halfToFloat(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string.
+func appendJSONString(out []byte, s []byte) []byte {
+	out = append(out, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				out = append(out, '\\', c)
+			case c < 0x20:
+				out = append(out, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				out = append(out, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			out = append(out, "\ufffd"...)
+		} else {
+			out = append(out, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(out, '"')
+}
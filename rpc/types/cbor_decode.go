@@ -0,0 +1,581 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const cborUndefined = 0xf7
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// UnmarshalCBOR decodes a CBOR document directly into v following the rules of
+// json.Unmarshal: struct fields are matched by their JSON names and byte strings
+// are accepted wherever JSON takes base64 text. Only values implementing
+// json.Unmarshaler, e.g. json.RawMessage, are handed the JSON document of their
+// part of the input. Numbers stored in interface values are json.Numbers.
+func UnmarshalCBOR(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cbor: Unmarshal(non-pointer %T)", v)
+	}
+	d := &cborDecoder{data: data}
+	if err := d.decode(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errors.New("cbor: trailing data after document")
+	}
+	return nil
+}
+
+// IsCBORBatch reports whether the CBOR document is an array, i.e. a batch of
+// messages.
+func IsCBORBatch(data []byte) bool {
+	return len(data) > 0 && data[0]&0xe0 == cborArray
+}
+
+// indirect walks down v, allocating pointers as needed, until it reaches a
+// non-pointer or a value implementing json.Unmarshaler or, unless decoding a
+// null, encoding.TextUnmarshaler. It mirrors the function of encoding/json.
+func indirect(v reflect.Value, null bool) (json.Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	// named non-pointer types may implement the interfaces on their pointer
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		v = v.Addr()
+	}
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() && (!null || e.Elem().Kind() == reflect.Ptr) {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr || null && v.CanSet() {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(json.Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if !null {
+				if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+					return nil, u, reflect.Value{}
+				}
+			}
+		}
+		v = v.Elem()
+	}
+	return nil, nil, v
+}
+
+func (d *cborDecoder) decode(v reflect.Value, depth int) error {
+	if depth > maxCBORDepth {
+		return errCBORDepth
+	}
+	if d.pos >= len(d.data) {
+		return errCBORTruncated
+	}
+	start := d.pos
+	null := d.data[start] == cborNull || d.data[start] == cborUndefined
+	ju, tu, v := indirect(v, null)
+	switch {
+	case ju != nil:
+		doc, err := d.json(depth)
+		if err != nil {
+			return err
+		}
+		return ju.UnmarshalJSON(doc)
+	case tu != nil:
+		return d.decodeText(tu, depth)
+	case null:
+		d.pos++
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	case needsJSON(v.Type()):
+		return d.decodeJSON(v, depth)
+	}
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborUint:
+		return d.setUint(v, n, start)
+	case cborNegInt:
+		if n >= math.MaxInt64 {
+			d.pos = start
+			return d.setBigNumber(v, depth)
+		}
+		return d.setInt(v, -1-int64(n), start)
+	case cborBytes, cborText:
+		b, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		return d.setString(v, b, major == cborBytes, start)
+	case cborArray:
+		return d.decodeArray(v, n, depth, start)
+	case cborMap:
+		return d.decodeMap(v, n, depth, start)
+	case cborTag:
+		if n == cborPosBignum || n == cborNegBignum {
+			d.pos = start
+			return d.setBigNumber(v, depth)
+		}
+		return d.decode(v, depth+1)
+	default:
+		return d.setSimple(v, info, n, start)
+	}
+}
+
+// needsJSON reports whether values of type t follow encoding/json rules the
+// decoder doesn't replicate, e.g. embedded structs or map keys of other types.
+func needsJSON(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		_, ok := cborStructFields(t)
+		return !ok
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String:
+			return reflect.PtrTo(t.Key()).Implements(textUnmarshalerType)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// json converts the next item into its JSON document. The document is only
+// valid until the next conversion.
+func (d *cborDecoder) json(depth int) ([]byte, error) {
+	d.out = d.out[:0]
+	if err := d.value(depth); err != nil {
+		return nil, err
+	}
+	return d.out, nil
+}
+
+// decodeJSON decodes the next item into v through encoding/json.
+func (d *cborDecoder) decodeJSON(v reflect.Value, depth int) error {
+	doc, err := d.json(depth)
+	if err != nil {
+		return err
+	}
+	if v.CanAddr() {
+		return json.Unmarshal(doc, v.Addr().Interface())
+	}
+	ptr := reflect.New(v.Type())
+	if err := json.Unmarshal(doc, ptr.Interface()); err != nil {
+		return err
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
+
+// decodeText hands a text string to a TextUnmarshaler. Like JSON strings, byte
+// strings are passed as base64 text.
+func (d *cborDecoder) decodeText(u encoding.TextUnmarshaler, depth int) error {
+	start := d.pos
+	major, _, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	if major != cborText && major != cborBytes {
+		d.pos = start
+		doc, err := d.json(depth)
+		if err != nil {
+			return err
+		}
+		return &json.UnmarshalTypeError{Value: string(doc), Type: reflect.TypeOf(u), Offset: int64(start)}
+	}
+	b, err := d.bytes(n)
+	if err != nil {
+		return err
+	}
+	if major == cborBytes {
+		b = []byte(base64.StdEncoding.EncodeToString(b))
+	}
+	return u.UnmarshalText(b)
+}
+
+func (d *cborDecoder) typeError(what string, t reflect.Type, offset int) error {
+	return &json.UnmarshalTypeError{Value: what, Type: t, Offset: int64(offset)}
+}
+
+func (d *cborDecoder) setUint(v reflect.Value, n uint64, start int) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n > math.MaxInt64 || v.OverflowInt(int64(n)) {
+			return d.typeError("number "+strconv.FormatUint(n, 10), v.Type(), start)
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.OverflowUint(n) {
+			return d.typeError("number "+strconv.FormatUint(n, 10), v.Type(), start)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return d.setNumber(v, strconv.FormatUint(n, 10), start)
+	}
+	return nil
+}
+
+func (d *cborDecoder) setInt(v reflect.Value, n int64, start int) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(n) {
+			return d.typeError("number "+strconv.FormatInt(n, 10), v.Type(), start)
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return d.setNumber(v, strconv.FormatInt(n, 10), start)
+	}
+	return nil
+}
+
+// setBigNumber stores a number exceeding 64 bits.
+func (d *cborDecoder) setBigNumber(v reflect.Value, depth int) error {
+	start := d.pos
+	literal, err := d.json(depth)
+	if err != nil {
+		return err
+	}
+	return d.setNumber(v, string(literal), start)
+}
+
+// setNumber stores the number literal like encoding/json.
+func (d *cborDecoder) setNumber(v reflect.Value, literal string, start int) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(json.Number(literal)))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(literal, 10, 64); err == nil && !v.OverflowInt(n) {
+			v.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, err := strconv.ParseUint(literal, 10, 64); err == nil && !v.OverflowUint(n) {
+			v.SetUint(n)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(literal, v.Type().Bits()); err == nil && !v.OverflowFloat(f) {
+			v.SetFloat(f)
+			return nil
+		}
+	case reflect.String:
+		if v.Type() == jsonNumberType {
+			v.SetString(literal)
+			return nil
+		}
+	}
+	return d.typeError("number "+literal, v.Type(), start)
+}
+
+// setString stores a text or byte string. Byte strings stand for the base64
+// text JSON would carry.
+func (d *cborDecoder) setString(v reflect.Value, b []byte, isBytes bool, start int) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		if isBytes {
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
+		n, err := base64.StdEncoding.Decode(decoded, b)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(decoded[:n])
+		return nil
+	case reflect.String:
+		if isBytes {
+			v.SetString(base64.StdEncoding.EncodeToString(b))
+		} else {
+			v.SetString(string(b))
+		}
+		return nil
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		if isBytes {
+			v.Set(reflect.ValueOf(base64.StdEncoding.EncodeToString(b)))
+		} else {
+			v.Set(reflect.ValueOf(string(b)))
+		}
+		return nil
+	}
+	return d.typeError("string", v.Type(), start)
+}
+
+func (d *cborDecoder) setSimple(v reflect.Value, info byte, n uint64, start int) error {
+	switch info {
+	case 20, 21:
+		switch {
+		case v.Kind() == reflect.Bool:
+			v.SetBool(info == 21)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(info == 21))
+		default:
+			return d.typeError("bool", v.Type(), start)
+		}
+		return nil
+	case 25, 26, 27:
+		var f float64
+		switch info {
+		case 25:
+			f = halfToFloat(uint16(n))
+		case 26:
+			f = float64(math.Float32frombits(uint32(n)))
+		default:
+			f = math.Float64frombits(n)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("cbor: unsupported value %v", f)
+		}
+		if kind := v.Kind(); kind == reflect.Float64 || kind == reflect.Float32 && !v.OverflowFloat(f) {
+			v.SetFloat(f)
+			return nil
+		}
+		return d.setNumber(v, string(appendFloat(nil, f)), start)
+	}
+	return fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+func (d *cborDecoder) decodeArray(v reflect.Value, n uint64, depth, start int) error {
+	if n > uint64(len(d.data)-d.pos) {
+		return errCBORTruncated
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if err := d.decode(reflect.ValueOf(&arr[i]).Elem(), depth+1); err != nil {
+				return err
+			}
+		}
+		v.Set(reflect.ValueOf(arr))
+		return nil
+	case reflect.Slice:
+		if v.IsNil() || uint64(v.Cap()) < n {
+			v.Set(reflect.MakeSlice(v.Type(), int(n), int(n)))
+		} else {
+			v.SetLen(int(n))
+		}
+		for i := 0; i < int(n); i++ {
+			if err := d.decode(v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		for i := 0; i < int(n); i++ {
+			var err error
+			if i < v.Len() {
+				err = d.decode(v.Index(i), depth+1)
+			} else {
+				err = d.skip(depth + 1)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for i := int(n); i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+		return nil
+	}
+	return d.typeError("array", v.Type(), start)
+}
+
+func (d *cborDecoder) decodeMap(v reflect.Value, n uint64, depth, start int) error {
+	if n > uint64(len(d.data)-d.pos) {
+		return errCBORTruncated
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			break
+		}
+		obj := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.mapKey()
+			if err != nil {
+				return err
+			}
+			name := string(key)
+			var elem interface{}
+			if err := d.decode(reflect.ValueOf(&elem).Elem(), depth+1); err != nil {
+				return err
+			}
+			obj[name] = elem
+		}
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	case reflect.Map:
+		t := v.Type()
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, int(n)))
+		}
+		for i := uint64(0); i < n; i++ {
+			key, err := d.mapKey()
+			if err != nil {
+				return err
+			}
+			kv := reflect.New(t.Key()).Elem()
+			switch kv.Kind() {
+			case reflect.String:
+				kv.SetString(string(key))
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n, err := strconv.ParseInt(string(key), 10, 64)
+				if err != nil || kv.OverflowInt(n) {
+					return d.typeError("number "+string(key), t.Key(), start)
+				}
+				kv.SetInt(n)
+			default:
+				n, err := strconv.ParseUint(string(key), 10, 64)
+				if err != nil || kv.OverflowUint(n) {
+					return d.typeError("number "+string(key), t.Key(), start)
+				}
+				kv.SetUint(n)
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := d.decode(elem, depth+1); err != nil {
+				return err
+			}
+			v.SetMapIndex(kv, elem)
+		}
+		return nil
+	case reflect.Struct:
+		fields, _ := cborStructFields(v.Type())
+		for i := uint64(0); i < n; i++ {
+			key, err := d.mapKey()
+			if err != nil {
+				return err
+			}
+			field := -1
+			for j := range fields {
+				if fields[j].name == string(key) {
+					field = fields[j].index
+					break
+				}
+			}
+			if field < 0 {
+				// encoding/json matches field names case-insensitively as well
+				for j := range fields {
+					if strings.EqualFold(fields[j].name, string(key)) {
+						field = fields[j].index
+						break
+					}
+				}
+			}
+			if field < 0 {
+				err = d.skip(depth + 1)
+			} else {
+				err = d.decode(v.Field(field), depth+1)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return d.typeError("object", v.Type(), start)
+}
+
+// mapKey reads a map key, which has to be a text string or an integer. The
+// returned bytes are only valid until the next read.
+func (d *cborDecoder) mapKey() ([]byte, error) {
+	major, _, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborText:
+		return d.bytes(n)
+	case cborUint:
+		return strconv.AppendUint(d.out[:0], n, 10), nil
+	case cborNegInt:
+		if n >= math.MaxInt64 {
+			return nil, errors.New("cbor: map key out of range")
+		}
+		return strconv.AppendInt(d.out[:0], -1-int64(n), 10), nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported map key type %d", major>>5)
+}
+
+// skip moves past the next item.
+func (d *cborDecoder) skip(depth int) error {
+	if depth > maxCBORDepth {
+		return errCBORDepth
+	}
+	major, _, n, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		_, err = d.bytes(n)
+	case cborArray, cborMap:
+		if major == cborMap {
+			n *= 2
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			return errCBORTruncated
+		}
+		for i := uint64(0); i < n && err == nil; i++ {
+			err = d.skip(depth + 1)
+		}
+	case cborTag:
+		err = d.skip(depth + 1)
+	}
+	return err
+}
@@ -0,0 +1,509 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cborTestHeader struct {
+	Height    uint64   `json:"height"`
+	Hash      []byte   `json:"hash"`
+	Parents   [][]byte `json:"parents"`
+	Timestamp int64    `json:"timestamp,omitempty"`
+	Nonce     *big.Int `json:"nonce"`
+	ignored   int
+}
+
+type cborTestBlock struct {
+	Header *cborTestHeader
+	Txs    []cborTestTx `json:"txs"`
+	Extra  map[string]interface{}
+	Skip   string `json:"-"`
+}
+
+type cborTestTx struct {
+	From   string  `json:"from"`
+	Amount float64 `json:"amount"`
+	Data   []byte  `json:"data,omitempty"`
+	Memo   string  `json:"memo,omitempty"`
+}
+
+type cborTestEmbedded struct {
+	cborTestTx
+	Fee uint32 `json:"fee,string"`
+}
+
+func newCBORTestBlock(txs int) *cborTestBlock {
+	block := &cborTestBlock{
+		Header: &cborTestHeader{
+			Height:    1 << 40,
+			Hash:      bytes.Repeat([]byte{0xab}, 32),
+			Parents:   [][]byte{bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 32)},
+			Timestamp: 1546300800,
+			Nonce:     new(big.Int).Lsh(big.NewInt(1), 100),
+		},
+		Extra: map[string]interface{}{"miner": "drep", "votes": []interface{}{1, -2, 3.5}},
+	}
+	for i := 0; i < txs; i++ {
+		block.Txs = append(block.Txs, cborTestTx{
+			From:   "0x3ebcbe7cb440dd8c52940a2963472380afbb56c5",
+			Amount: float64(i) + 0.25,
+			Data:   bytes.Repeat([]byte{byte(i)}, 64),
+		})
+	}
+	return block
+}
+
+// TestCBORMatchesJSON checks that every CBOR document converts back into the JSON
+// document encoding/json produces for the same value.
+func TestCBORMatchesJSON(t *testing.T) {
+	values := []interface{}{
+		nil,
+		true,
+		0,
+		-1,
+		int64(math.MinInt64),
+		uint64(math.MaxUint64),
+		1.5,
+		-1e-7,
+		1e21,
+		"",
+		"quote \" backslash \\ newline \n unicode ü €",
+		[]byte{},
+		[]byte("hello"),
+		[]int(nil),
+		[]string{"a", "b"},
+		[3]int{1, 2, 3},
+		map[string]int{"b": 2, "a": 1},
+		map[int]string{1: "one", -2: "minus two"},
+		json.RawMessage(`{"raw":[1,2.5,"x"]}`),
+		json.Number("12345678901234567890"),
+		big.NewInt(-42),
+		time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		&cborTestHeader{},
+		newCBORTestBlock(3),
+		cborTestEmbedded{cborTestTx{From: "a", Amount: 1}, 7},
+		&jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(1)},
+	}
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%T: json error: %v", v, err)
+		}
+		enc, err := MarshalCBOR(v)
+		if err != nil {
+			t.Fatalf("%T: cbor error: %v", v, err)
+		}
+		got, err := CBORToJSON(enc)
+		if err != nil {
+			t.Fatalf("%T: conversion error: %v", v, err)
+		}
+		if !jsonEqual(t, got, want) {
+			t.Errorf("%T mismatch:\ngot  %s\nwant %s", v, got, want)
+		}
+
+		// the JSON document has to convert as well, clients send requests that way
+		if _, err := JSONToCBOR(want); err != nil {
+			t.Errorf("%T: JSONToCBOR error: %v", v, err)
+		}
+	}
+}
+
+type cborTestRaw struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Any    interface{}     `json:"any"`
+}
+
+// TestUnmarshalCBOR checks that decoding CBOR yields the values decoding the
+// JSON encoding of the same value yields.
+func TestUnmarshalCBOR(t *testing.T) {
+	values := []interface{}{
+		true,
+		int8(-7),
+		uint64(math.MaxUint64),
+		-1e-7,
+		"unicode ü €",
+		[]byte("hello"),
+		[]string(nil),
+		[3]int{1, 2, 3},
+		map[string]int{"b": 2, "a": 1},
+		map[int]string{1: "one", -2: "minus two"},
+		json.Number("12345678901234567890"),
+		big.NewInt(-42),
+		new(big.Int).Lsh(big.NewInt(1), 100),
+		time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		&cborTestHeader{},
+		newCBORTestBlock(3),
+		cborTestEmbedded{cborTestTx{From: "a", Amount: 1}, 7},
+		cborTestRaw{ID: json.RawMessage(`"7"`), Result: json.RawMessage(`{"a":[1,2.5,null]}`), Any: []interface{}{1, "x", map[string]interface{}{"y": nil}}},
+	}
+	for _, v := range values {
+		typ := reflect.TypeOf(v)
+		doc, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%v: json error: %v", typ, err)
+		}
+		want := reflect.New(typ)
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+		if err := dec.Decode(want.Interface()); err != nil {
+			t.Fatalf("%v: json error: %v", typ, err)
+		}
+		enc, err := MarshalCBOR(v)
+		if err != nil {
+			t.Fatalf("%v: cbor error: %v", typ, err)
+		}
+		got := reflect.New(typ)
+		if err := UnmarshalCBOR(enc, got.Interface()); err != nil {
+			t.Fatalf("%v: cbor error: %v", typ, err)
+		}
+		if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+			t.Errorf("%v mismatch:\ngot  %#v\nwant %#v", typ, got.Elem(), want.Elem())
+		}
+
+		var generic, wantGeneric interface{}
+		if err := UnmarshalCBOR(enc, &generic); err != nil {
+			t.Fatalf("%v: cbor error: %v", typ, err)
+		}
+		dec = json.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+		dec.Decode(&wantGeneric)
+		if !reflect.DeepEqual(generic, wantGeneric) {
+			t.Errorf("%v mismatch as interface{}:\ngot  %#v\nwant %#v", typ, generic, wantGeneric)
+		}
+	}
+}
+
+func TestUnmarshalCBORTypeErrors(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		target interface{}
+	}{
+		{"text", new(int)},
+		{300, new(uint8)},
+		{-1, new(uint)},
+		{1.5, new(int)},
+		{[]int{1}, new(string)},
+		{map[string]int{"a": 1}, new([]int)},
+		{"not base64!", new([]byte)},
+	}
+	for _, test := range tests {
+		enc, err := MarshalCBOR(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := UnmarshalCBOR(enc, test.target); err == nil {
+			t.Errorf("%#v into %T: expected error", test.value, test.target)
+		}
+	}
+}
+
+func TestJSONToCBORRoundTrip(t *testing.T) {
+	doc := `{"jsonrpc":"2.0","id":"7","method":"chain_getBlock","params":[1,-2,1.5,null,true,{"nested":["x"]}]}`
+	enc, err := JSONToCBOR([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := CBORToJSON(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(t, got, []byte(doc)) {
+		t.Errorf("round trip mismatch:\ngot  %s\nwant %s", got, doc)
+	}
+}
+
+func TestCBORBignum(t *testing.T) {
+	for _, literal := range []string{"1267650600228229401496703205376", "-18446744073709551617"} {
+		enc, err := JSONToCBOR([]byte(literal))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := CBORToJSON(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != literal {
+			t.Errorf("got %s, want %s", got, literal)
+		}
+	}
+}
+
+func TestCBORToJSONInvalid(t *testing.T) {
+	deep := append(bytes.Repeat([]byte{0x81}, maxCBORDepth+1), 0x00)
+	tests := map[string][]byte{
+		"empty":             {},
+		"truncated uint":    {0x19, 0x01},
+		"truncated text":    {0x63, 'a', 'b'},
+		"truncated array":   {0x82, 0x01},
+		"huge array":        {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite":        {0x9f, 0x01, 0xff},
+		"trailing data":     {0x01, 0x02},
+		"bytes map key":     {0xa1, 0x41, 'a', 0x01},
+		"undefined float":   {0xfb, 0x7f, 0xf8, 0, 0, 0, 0, 0, 0},
+		"reserved info":     {0x1c},
+		"exceeds max depth": deep,
+	}
+	for name, data := range tests {
+		if doc, err := CBORToJSON(data); err == nil {
+			t.Errorf("%s: expected error, got %s", name, doc)
+		}
+	}
+}
+
+func TestCBORStreamCodec(t *testing.T) {
+	server := newCBORTestServer(t)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.serveStream(context.Background(), serverConn)
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := clientConn.Write(CBORPreamble); err != nil {
+		t.Fatal(err)
+	}
+	ack := make([]byte, len(CBORPreamble))
+	if _, err := clientConn.Read(ack); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ack, CBORPreamble) {
+		t.Fatalf("wrong acknowledgement %x", ack)
+	}
+	for _, test := range conformanceCases {
+		if test.response == "" {
+			continue
+		}
+		request, err := JSONToCBOR([]byte(test.request))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := WriteCBORFrame(clientConn, request); err != nil {
+			t.Fatalf("%s: write failed: %v", test.name, err)
+		}
+		frame, err := ReadCBORFrame(clientConn, MaxCBORFrameSize)
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", test.name, err)
+		}
+		got, err := CBORToJSON(frame)
+		if err != nil {
+			t.Fatalf("%s: invalid response: %v", test.name, err)
+		}
+		if !responseMatches(t, got, test.response) {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.name, got, test.response)
+		}
+	}
+}
+
+func TestStreamCodecSniffsJSON(t *testing.T) {
+	server := newCBORTestServer(t)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.serveStream(context.Background(), serverConn)
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := clientConn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"test_add","params":[1,2]}`)); err != nil {
+		t.Fatal(err)
+	}
+	var got json.RawMessage
+	if err := json.NewDecoder(clientConn).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"result":3}`; !responseMatches(t, got, want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCBORHTTP(t *testing.T) {
+	server := newCBORTestServer(t)
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	test := conformanceCases[len(conformanceCases)-4] // batch
+	request, err := JSONToCBOR([]byte(test.request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(httpsrv.URL, ContentTypeCBOR, bytes.NewReader(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("content-type"); ct != ContentTypeCBOR {
+		t.Fatalf("wrong response content type %q", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := CBORToJSON(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !responseMatches(t, got, test.response) {
+		t.Errorf("got %s, want %s", got, test.response)
+	}
+}
+
+func newCBORTestServer(t *testing.T) *Server {
+	server := NewServer()
+	err := server.RegisterName("test", new(ConformanceTestService),
+		WithParamNames("add", "a", "b"),
+		WithParamNames("Greet", "name", "greeting"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// responseMatches compares a response with an expectation from conformanceCases.
+func responseMatches(t *testing.T, got []byte, want string) bool {
+	var vgot, vwant interface{}
+	if err := json.Unmarshal(got, &vgot); err != nil {
+		t.Fatalf("invalid response %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &vwant); err != nil {
+		t.Fatalf("invalid expectation %s: %v", want, err)
+	}
+	stripErrorMessages(vgot)
+	return reflect.DeepEqual(vgot, vwant)
+}
+
+// The benchmarks compare the codecs on a block dump, encoding a response and
+// decoding it the way the client does.
+
+func BenchmarkJSONCodecEncode(b *testing.B) {
+	resp := &jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(200)}
+	var size int
+	for i := 0; i < b.N; i++ {
+		enc, err := json.Marshal(resp)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(enc)
+	}
+	b.SetBytes(int64(size))
+}
+
+func BenchmarkCBORCodecEncode(b *testing.B) {
+	resp := &jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(200)}
+	var size int
+	for i := 0; i < b.N; i++ {
+		enc, err := MarshalCBOR(resp)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(enc)
+	}
+	b.SetBytes(int64(size))
+}
+
+func BenchmarkJSONCodecDecode(b *testing.B) {
+	enc, err := json.Marshal(&jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(200)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(enc)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var block cborTestBlock
+		resp := jsonSuccessResponse{Result: &block}
+		if err := json.Unmarshal(enc, &resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCBORCodecDecode(b *testing.B) {
+	enc, err := MarshalCBOR(&jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(200)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(enc)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var block cborTestBlock
+		resp := jsonSuccessResponse{Result: &block}
+		if err := UnmarshalCBOR(enc, &resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The message benchmarks decode a response the way Client.Call does, keeping the
+// result as JSON for the client's cache and recorder until it is unmarshaled.
+
+func BenchmarkJSONMessageDecode(b *testing.B) {
+	enc, err := json.Marshal(&jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(200)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(enc)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var msg JsonrpcMessage
+		if err := json.Unmarshal(enc, &msg); err != nil {
+			b.Fatal(err)
+		}
+		var block cborTestBlock
+		if err := json.Unmarshal(msg.Result, &block); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCBORMessageDecode(b *testing.B) {
+	enc, err := MarshalCBOR(&jsonSuccessResponse{Version: JsonrpcVersion, Id: 1, Result: newCBORTestBlock(200)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(enc)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var msg JsonrpcMessage
+		if err := UnmarshalCBOR(enc, &msg); err != nil {
+			b.Fatal(err)
+		}
+		var block cborTestBlock
+		if err := json.Unmarshal(msg.Result, &block); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ConformanceTestService struct {
+	recorded chan int
+}
+
+func (s *ConformanceTestService) Add(a, b int) int {
+	return a + b
+}
+
+func (s *ConformanceTestService) Greet(name string, greeting *string) string {
+	if greeting == nil {
+		return "hello " + name
+	}
+	return *greeting + " " + name
+}
+
+func (s *ConformanceTestService) Positional(x int) int {
+	return x
+}
+
+func (s *ConformanceTestService) Record(value int) {
+	s.recorded <- value
+}
+
+// conformanceError is an application error with its own error code.
+type conformanceError struct{}
+
+func (e *conformanceError) Error() string  { return "application error" }
+func (e *conformanceError) ErrorCode() int { return -32042 }
+
+func (s *ConformanceTestService) Fail(coded bool) error {
+	if coded {
+		return &conformanceError{}
+	}
+	return errors.New("plain error")
+}
+
+// conformanceCase sends request and expects response, an empty response means
+// the server must not answer at all.
+type conformanceCase struct {
+	name     string
+	request  string
+	response string
+}
+
+var conformanceCases = []conformanceCase{
+	// parameters
+	{"positional params", `{"jsonrpc":"2.0","id":1,"method":"test_add","params":[1,2]}`,
+		`{"jsonrpc":"2.0","id":1,"result":3}`},
+	{"named params", `{"jsonrpc":"2.0","id":2,"method":"test_add","params":{"b":2,"a":1}}`,
+		`{"jsonrpc":"2.0","id":2,"result":3}`},
+	{"named params optional missing", `{"jsonrpc":"2.0","id":3,"method":"test_greet","params":{"name":"drep"}}`,
+		`{"jsonrpc":"2.0","id":3,"result":"hello drep"}`},
+	{"named params optional given", `{"jsonrpc":"2.0","id":4,"method":"test_greet","params":{"name":"drep","greeting":"hi"}}`,
+		`{"jsonrpc":"2.0","id":4,"result":"hi drep"}`},
+	{"named params unknown name", `{"jsonrpc":"2.0","id":5,"method":"test_add","params":{"a":1,"b":2,"c":3}}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32602}}`},
+	{"named params missing required", `{"jsonrpc":"2.0","id":6,"method":"test_add","params":{"a":1}}`,
+		`{"jsonrpc":"2.0","id":6,"error":{"code":-32602}}`},
+	{"named params null required", `{"jsonrpc":"2.0","id":14,"method":"test_add","params":{"a":1,"b":null}}`,
+		`{"jsonrpc":"2.0","id":14,"error":{"code":-32602}}`},
+	{"named params null optional", `{"jsonrpc":"2.0","id":15,"method":"test_greet","params":{"name":"drep","greeting":null}}`,
+		`{"jsonrpc":"2.0","id":15,"result":"hello drep"}`},
+	{"positional params null required", `{"jsonrpc":"2.0","id":16,"method":"test_add","params":[1,null]}`,
+		`{"jsonrpc":"2.0","id":16,"error":{"code":-32602}}`},
+	{"named params without metadata", `{"jsonrpc":"2.0","id":7,"method":"test_positional","params":{"x":1}}`,
+		`{"jsonrpc":"2.0","id":7,"error":{"code":-32602}}`},
+
+	// request ids
+	{"string id", `{"jsonrpc":"2.0","id":"abc","method":"test_add","params":[1,1]}`,
+		`{"jsonrpc":"2.0","id":"abc","result":2}`},
+	{"null id", `{"jsonrpc":"2.0","id":null,"method":"test_add","params":[1,1]}`,
+		`{"jsonrpc":"2.0","id":null,"result":2}`},
+	{"invalid id", `{"jsonrpc":"2.0","id":{},"method":"test_add","params":[1,1]}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32600}}`},
+	{"unknown method", `{"jsonrpc":"2.0","id":8,"method":"test_missing","params":[]}`,
+		`{"jsonrpc":"2.0","id":8,"error":{"code":-32601}}`},
+	{"malformed method", `{"jsonrpc":"2.0","id":9,"method":"missing","params":[]}`,
+		`{"jsonrpc":"2.0","id":9,"error":{"code":-32601}}`},
+
+	// application errors
+	{"callback error", `{"jsonrpc":"2.0","id":17,"method":"test_fail","params":[false]}`,
+		`{"jsonrpc":"2.0","id":17,"error":{"code":-32000}}`},
+	{"callback error with code", `{"jsonrpc":"2.0","id":18,"method":"test_fail","params":[true]}`,
+		`{"jsonrpc":"2.0","id":18,"error":{"code":-32042}}`},
+
+	// notifications
+	{"notification", `{"jsonrpc":"2.0","method":"test_add","params":[1,2]}`, ``},
+	{"named notification", `{"jsonrpc":"2.0","method":"test_add","params":{"a":1,"b":2}}`, ``},
+	{"notification unknown method", `{"jsonrpc":"2.0","method":"test_missing","params":[]}`, ``},
+	{"notification invalid params", `{"jsonrpc":"2.0","method":"test_add","params":["x"]}`, ``},
+
+	// batches
+	{"batch", `[{"jsonrpc":"2.0","id":10,"method":"test_add","params":[1,2]},{"jsonrpc":"2.0","id":11,"method":"test_add","params":{"a":2,"b":2}}]`,
+		`[{"jsonrpc":"2.0","id":10,"result":3},{"jsonrpc":"2.0","id":11,"result":4}]`},
+	{"batch with notifications", `[{"jsonrpc":"2.0","id":12,"method":"test_add","params":[1,2]},{"jsonrpc":"2.0","method":"test_add","params":[1,2]},{"jsonrpc":"2.0","id":13,"method":"test_missing"}]`,
+		`[{"jsonrpc":"2.0","id":12,"result":3},{"jsonrpc":"2.0","id":13,"error":{"code":-32601}}]`},
+	{"batch of notifications", `[{"jsonrpc":"2.0","method":"test_add","params":[1,2]},{"jsonrpc":"2.0","method":"test_add","params":[3,4]}]`, ``},
+	{"empty batch", `[]`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32600}}`},
+}
+
+// stripErrorMessages removes the error messages from a decoded response, only the
+// error codes are part of the conformance checks.
+func stripErrorMessages(msg interface{}) {
+	switch msg := msg.(type) {
+	case []interface{}:
+		for _, elem := range msg {
+			stripErrorMessages(elem)
+		}
+	case map[string]interface{}:
+		if err, ok := msg["error"].(map[string]interface{}); ok {
+			delete(err, "message")
+			delete(err, "data")
+		}
+	}
+}
+
+func newConformanceServer(t *testing.T) (*ConformanceTestService, io.ReadWriteCloser) {
+	service := &ConformanceTestService{recorded: make(chan int, 1)}
+	server := NewServer()
+	err := server.RegisterName("test", service,
+		WithParamNames("add", "a", "b"),
+		WithParamNames("Greet", "name", "greeting"),
+		WithParamNames("record", "value"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation|OptionSubscriptions)
+	return service, clientConn
+}
+
+func TestJSONRPCConformance(t *testing.T) {
+	_, conn := newConformanceServer(t)
+	defer conn.Close()
+	conn.(net.Conn).SetDeadline(time.Now().Add(5 * time.Second))
+
+	in := json.NewDecoder(conn)
+	sentinel := `{"jsonrpc":"2.0","id":"sentinel","method":"test_add","params":[0,0]}`
+	for _, test := range conformanceCases {
+		if _, err := conn.Write([]byte(test.request)); err != nil {
+			t.Fatalf("%s: write failed: %v", test.name, err)
+		}
+		want := test.response
+		if want == "" {
+			// the next message must be the answer to the sentinel, not to the notification
+			if _, err := conn.Write([]byte(sentinel)); err != nil {
+				t.Fatalf("%s: write failed: %v", test.name, err)
+			}
+			want = `{"jsonrpc":"2.0","id":"sentinel","result":0}`
+		}
+
+		var got, expected interface{}
+		if err := in.Decode(&got); err != nil {
+			t.Fatalf("%s: read failed: %v", test.name, err)
+		}
+		if err := json.Unmarshal([]byte(want), &expected); err != nil {
+			t.Fatalf("%s: invalid expectation: %v", test.name, err)
+		}
+		stripErrorMessages(got)
+		if !reflect.DeepEqual(got, expected) {
+			gotJSON, _ := json.Marshal(got)
+			t.Errorf("%s:\ngot  %s\nwant %s", test.name, gotJSON, want)
+		}
+	}
+}
+
+func TestNotificationIsExecuted(t *testing.T) {
+	service, conn := newConformanceServer(t)
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(`{"jsonrpc":"2.0","method":"test_record","params":{"value":42}}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-service.recorded:
+		if value != 42 {
+			t.Errorf("notification executed with %d, want 42", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification not executed")
+	}
+}
+
+func TestRegisterParamNamesMismatch(t *testing.T) {
+	server := NewServer()
+	service := new(ConformanceTestService)
+	if err := server.RegisterName("test", service, WithParamNames("add", "a")); err == nil {
+		t.Error("expected error for parameter name count mismatch")
+	}
+	if err := server.RegisterName("test", service, WithParamNames("unknown", "a")); err == nil {
+		t.Error("expected error for parameter names of unknown method")
+	}
+}
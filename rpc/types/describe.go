@@ -0,0 +1,269 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultApiVersion = "1.0"
+	OpenRPCVersion    = "1.2.6"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// ParamDescription describes a single parameter of a RPC method.
+type ParamDescription struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// MethodDescription describes a registered RPC method or subscription. A
+// subscription is described as the ns_subscribe method it is created with, its
+// name is the first parameter.
+type MethodDescription struct {
+	Name         string             `json:"name"`
+	Namespace    string             `json:"namespace"`
+	Version      string             `json:"version"`
+	Params       []ParamDescription `json:"params"`
+	Result       string             `json:"result,omitempty"`
+	ByName       bool               `json:"byName"`
+	Subscription string             `json:"subscription,omitempty"` // name of the subscription
+}
+
+// OpenRPCDocument is an OpenRPC (https://spec.open-rpc.org) service description.
+type OpenRPCDocument struct {
+	OpenRPC string          `json:"openrpc"`
+	Info    OpenRPCInfo     `json:"info"`
+	Methods []OpenRPCMethod `json:"methods"`
+}
+
+// OpenRPCInfo holds the metadata of the described API.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a single method in an OpenRPC document.
+type OpenRPCMethod struct {
+	Name             string              `json:"name"`
+	Tags             []OpenRPCTag        `json:"tags,omitempty"`
+	ParamStructure   string              `json:"paramStructure,omitempty"`
+	Params           []OpenRPCDescriptor `json:"params"`
+	Result           *OpenRPCDescriptor  `json:"result,omitempty"`
+	NamespaceVersion string              `json:"x-namespace-version,omitempty"`
+	Subscription     string              `json:"x-subscription,omitempty"`
+}
+
+// OpenRPCTag groups methods, every method is tagged with its namespace.
+type OpenRPCTag struct {
+	Name string `json:"name"`
+}
+
+// OpenRPCDescriptor describes a parameter or result by name and JSON schema.
+type OpenRPCDescriptor struct {
+	Name     string                 `json:"name"`
+	Required bool                   `json:"required,omitempty"`
+	Schema   map[string]interface{} `json:"schema"`
+}
+
+// Describe returns the description of every method the server offers, sorted by
+// name and subscription name. Every subscription of a namespace is described as
+// a separate ns_subscribe method.
+func (s *Server) Describe() []MethodDescription {
+	var methods []MethodDescription
+	for _, svc := range s.services {
+		for name, callb := range svc.callbacks {
+			methods = append(methods, describeCallback(svc, svc.name+ServiceMethodSeparator+name, callb, ""))
+		}
+		for name, callb := range svc.subscriptions {
+			methods = append(methods, describeCallback(svc, svc.name+SubscribeMethodSuffix, callb, name))
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Name != methods[j].Name {
+			return methods[i].Name < methods[j].Name
+		}
+		return methods[i].Subscription < methods[j].Subscription
+	})
+	return methods
+}
+
+// describeCallback describes a single callback of the given service, the
+// subscription named subscription if it isn't empty.
+func describeCallback(svc *service, name string, callb *callback, subscription string) MethodDescription {
+	desc := MethodDescription{
+		Name:         name,
+		Namespace:    svc.name,
+		Version:      svc.version,
+		Params:       []ParamDescription{},
+		ByName:       callb.argNames != nil && subscription == "", // subscriptions are created by position
+		Subscription: subscription,
+	}
+	if subscription != "" {
+		desc.Params = append(desc.Params, ParamDescription{Name: "subscription", Type: "string"})
+	}
+	for i, argType := range callb.argTypes {
+		desc.Params = append(desc.Params, ParamDescription{
+			Name:     callb.paramName(i),
+			Type:     argType.String(),
+			Optional: argType.Kind() == reflect.Ptr,
+		})
+	}
+	if resultType := callb.resultType(); resultType != nil {
+		desc.Result = resultType.String()
+	}
+	return desc
+}
+
+// paramName returns the registered name of argument i, or a positional placeholder.
+func (c *callback) paramName(i int) string {
+	if c.argNames != nil {
+		return c.argNames[i]
+	}
+	return fmt.Sprintf("arg%d", i)
+}
+
+// resultType returns the type of the value returned by the callback, nil if the
+// callback only returns an error or nothing at all.
+func (c *callback) resultType() reflect.Type {
+	if c.isSubscribe {
+		return reflect.TypeOf(ID(""))
+	}
+	mtype := c.method.Type
+	if mtype.NumOut() == 0 || c.errPos == 0 {
+		return nil
+	}
+	return mtype.Out(0)
+}
+
+// OpenRPC assembles an OpenRPC document describing every method the server offers.
+func (s *Server) OpenRPC(info OpenRPCInfo) *OpenRPCDocument {
+	doc := &OpenRPCDocument{OpenRPC: OpenRPCVersion, Info: info, Methods: []OpenRPCMethod{}}
+	for _, desc := range s.Describe() {
+		svc := s.services[desc.Namespace]
+		var (
+			callb  *callback
+			params []OpenRPCDescriptor
+		)
+		if desc.Subscription != "" {
+			callb = svc.subscriptions[desc.Subscription]
+			params = append(params, OpenRPCDescriptor{
+				Name:     "subscription",
+				Required: true,
+				Schema:   map[string]interface{}{"type": "string", "enum": []string{desc.Subscription}},
+			})
+		} else {
+			callb = svc.callbacks[strings.TrimPrefix(desc.Name, svc.name+ServiceMethodSeparator)]
+		}
+
+		method := OpenRPCMethod{
+			Name:             desc.Name,
+			Tags:             []OpenRPCTag{{Name: desc.Namespace}},
+			ParamStructure:   "by-position",
+			Params:           append([]OpenRPCDescriptor{}, params...),
+			NamespaceVersion: desc.Version,
+			Subscription:     desc.Subscription,
+		}
+		if desc.ByName {
+			method.ParamStructure = "either"
+		}
+		for i, argType := range callb.argTypes {
+			param := desc.Params[len(params)+i]
+			method.Params = append(method.Params, OpenRPCDescriptor{
+				Name:     param.Name,
+				Required: !param.Optional,
+				Schema:   jsonSchema(argType, make(map[reflect.Type]bool)),
+			})
+		}
+		if resultType := callb.resultType(); resultType != nil {
+			method.Result = &OpenRPCDescriptor{Name: "result", Schema: jsonSchema(resultType, make(map[reflect.Type]bool))}
+		}
+		doc.Methods = append(doc.Methods, method)
+	}
+	return doc
+}
+
+// jsonSchema derives the JSON schema of values of type t as encoded by encoding/json.
+// Types with custom JSON encoders are described as strings when they implement
+// encoding.TextMarshaler and left unconstrained otherwise.
+func jsonSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchema(t.Elem(), seen)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] { // recursive type
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag := field.Tag.Get("json"); tag != "" {
+				if tag == "-" {
+					continue
+				}
+				if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+					name = tagName
+				}
+			}
+			properties[name] = jsonSchema(field.Type, seen)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestModulesVersion(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(ConformanceTestService), WithVersion("2.1")); err != nil {
+		t.Fatal(err)
+	}
+	modules := (&RPCService{server}).Modules()
+	if modules["test"] != "2.1" || modules[MetadataApi] != DefaultApiVersion {
+		t.Errorf("unexpected module versions %v", modules)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(ConformanceTestService), WithParamNames("greet", "name", "greeting")); err != nil {
+		t.Fatal(err)
+	}
+	var greet, add *MethodDescription
+	methods := server.Describe()
+	for i := range methods {
+		switch methods[i].Name {
+		case "test_greet":
+			greet = &methods[i]
+		case "test_add":
+			add = &methods[i]
+		}
+	}
+	if greet == nil || add == nil {
+		t.Fatalf("missing methods in description %v", methods)
+	}
+	want := []ParamDescription{{Name: "name", Type: "string"}, {Name: "greeting", Type: "*string", Optional: true}}
+	if !reflect.DeepEqual(greet.Params, want) || greet.Result != "string" || !greet.ByName {
+		t.Errorf("unexpected test_greet description %+v", greet)
+	}
+	if add.ByName || add.Params[0].Name != "arg0" || add.Result != "int" {
+		t.Errorf("unexpected test_add description %+v", add)
+	}
+}
+
+func TestOpenRPCDocument(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(ConformanceTestService), WithParamNames("add", "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	doc := server.OpenRPC(OpenRPCInfo{Title: "test", Version: "1.0"})
+	content, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		OpenRPC string `json:"openrpc"`
+		Methods []struct {
+			Name           string `json:"name"`
+			ParamStructure string `json:"paramStructure"`
+			Params         []struct {
+				Name     string                 `json:"name"`
+				Required bool                   `json:"required"`
+				Schema   map[string]interface{} `json:"schema"`
+			} `json:"params"`
+			Result struct {
+				Schema map[string]interface{} `json:"schema"`
+			} `json:"result"`
+		} `json:"methods"`
+	}
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.OpenRPC != OpenRPCVersion {
+		t.Errorf("unexpected openrpc version %q", decoded.OpenRPC)
+	}
+	for _, method := range decoded.Methods {
+		if method.Name != "test_add" {
+			continue
+		}
+		if method.ParamStructure != "either" || len(method.Params) != 2 || method.Params[1].Name != "b" ||
+			!method.Params[1].Required || method.Params[1].Schema["type"] != "integer" || method.Result.Schema["type"] != "integer" {
+			t.Errorf("unexpected test_add document %s", content)
+		}
+		return
+	}
+	t.Errorf("test_add missing from document %s", content)
+}
+
+func TestDescribeSubscriptions(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("nftest", new(NotificationTestService)); err != nil {
+		t.Fatal(err)
+	}
+	var some *MethodDescription
+	methods := server.Describe()
+	for i := range methods {
+		if methods[i].Subscription == "someSubscription" {
+			some = &methods[i]
+		}
+	}
+	// subscriptions are created by calling nftest_subscribe with their name
+	if some == nil || some.Name != "nftest_subscribe" || len(some.Params) != 3 || some.Params[0].Name != "subscription" || some.ByName {
+		t.Fatalf("unexpected someSubscription description %+v", some)
+	}
+
+	doc := server.OpenRPC(OpenRPCInfo{Title: "test", Version: "1.0"})
+	for _, method := range doc.Methods {
+		if method.Subscription != "someSubscription" {
+			continue
+		}
+		if method.Name != "nftest_subscribe" || len(method.Params) != 3 || !method.Params[0].Required ||
+			!reflect.DeepEqual(method.Params[0].Schema["enum"], []string{"someSubscription"}) || method.Params[1].Schema["type"] != "integer" {
+			t.Errorf("unexpected someSubscription document %+v", method)
+		}
+		return
+	}
+	t.Error("someSubscription missing from document")
+}
@@ -17,7 +17,10 @@ type MethodNotFoundError struct {
 func (e *MethodNotFoundError) ErrorCode() int { return -32601 }
 
 func (e *MethodNotFoundError) Error() string {
-	return fmt.Sprintf("The method %s%s%s does not exist/is not available", e.service, e.method)
+	if e.method == "" {
+		return fmt.Sprintf("The method %s does not exist/is not available", e.service)
+	}
+	return fmt.Sprintf("The method %s%s%s does not exist/is not available", e.service, ServiceMethodSeparator, e.method)
 }
 
 // received message isn't a valid request
@@ -41,7 +44,7 @@ func (e *InvalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *InvalidParamsError) Error() string { return e.message }
 
-// logic error, callback returned an error
+// logic error, callback returned an error without an error code
 type CallbackError struct{ message string }
 
 func (e *CallbackError) ErrorCode() int { return -32000 }
@@ -54,3 +57,10 @@ type ShutdownError struct{}
 func (e *ShutdownError) ErrorCode() int { return -32000 }
 
 func (e *ShutdownError) Error() string { return "server is shutting down" }
+
+// issued when the execution of a request exceeded its timeout.
+type TimeoutError struct{}
+
+func (e *TimeoutError) ErrorCode() int { return -32002 }
+
+func (e *TimeoutError) Error() string { return "request timed out" }
@@ -0,0 +1,140 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Forwarder serves every method of a namespace without local callbacks, e.g.
+// by relaying the call to an upstream node. Services implementing Forwarder are
+// registered as forwarded namespaces by RegisterName. Subscriptions are only
+// forwarded by a SubscriptionForwarder.
+type Forwarder interface {
+	// Forward performs the call of method (including the namespace) with the
+	// raw params of the request and returns the raw result. Errors implementing
+	// Error are sent to the client with their own error code.
+	Forward(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error)
+}
+
+// SubscriptionForwarder is a Forwarder which also serves the subscriptions of
+// its namespace. Without it subscriptions of forwarded namespaces don't exist.
+type SubscriptionForwarder interface {
+	Forwarder
+
+	// ForwardSubscribe creates a subscription with the raw params of the
+	// subscribe request, the subscription name being the first of them. The
+	// notifications are sent through the Notifier of ctx.
+	ForwardSubscribe(ctx context.Context, namespace string, params json.RawMessage) (*Subscription, error)
+}
+
+// MethodFilter reports whether the method, given as "namespace_method", may be
+// called. Subscriptions are given as "namespace_subscribe.name".
+type MethodFilter func(method string) bool
+
+// WithMethodFilter only exposes the methods and subscriptions of the service
+// accepted by filter. For forwarded namespaces the filter is applied to every
+// request.
+func WithMethodFilter(filter MethodFilter) RegisterOption {
+	return func(opts *registerOptions) {
+		opts.filter = filter
+	}
+}
+
+// registerForwarder registers a forwarded namespace.
+func (s *Server) registerForwarder(name string, forwarder Forwarder, options *registerOptions) error {
+	if _, present := s.services[name]; present {
+		return fmt.Errorf("namespace %s is already registered", name)
+	}
+	s.services[name] = &service{
+		name:          name,
+		version:       options.version,
+		callbacks:     make(callbacks),
+		subscriptions: make(subscriptions),
+		forwarder:     forwarder,
+		filter:        options.filter,
+	}
+	return nil
+}
+
+// filterCallbacks drops the callbacks rejected by filter.
+func filterCallbacks(namespace string, cbs map[string]*callback, separator string, filter MethodFilter) {
+	for name := range cbs {
+		if !filter(namespace + separator + name) {
+			delete(cbs, name)
+		}
+	}
+}
+
+// forwardedSubscription creates the request of a subscription to a forwarded
+// namespace.
+func (s *Server) forwardedSubscription(svc *service, r rpcRequest) *serverRequest {
+	if _, ok := svc.forwarder.(SubscriptionForwarder); !ok {
+		return &serverRequest{id: r.id, err: &MethodNotFoundError{r.service, r.method}}
+	}
+	if svc.filter != nil && !svc.filter(r.service+SubscribeMethodSuffix+"."+r.method) {
+		return &serverRequest{id: r.id, err: &MethodNotFoundError{r.service, r.method}}
+	}
+	return &serverRequest{id: r.id, svcname: svc.name, forward: svc.forwarder, isSubscribe: true}
+}
+
+// forwardSubscribe creates a subscription of a forwarded namespace, it is
+// activated once the response is sent like local subscriptions.
+func (s *Server) forwardSubscribe(ctx context.Context, codec ServerCodec, req *serverRequest) (interface{}, func()) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported { // interface doesn't support subscriptions (e.g. http)
+		return codec.CreateErrorResponse(&req.id, &CallbackError{ErrNotificationsUnsupported.Error()}), nil
+	}
+	params, _ := req.params.(json.RawMessage)
+	sub, err := req.forward.(SubscriptionForwarder).ForwardSubscribe(ctx, req.svcname, params)
+	if err != nil {
+		if rpcErr, ok := err.(Error); ok {
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil
+		}
+		return codec.CreateErrorResponse(&req.id, &CallbackError{err.Error()}), nil
+	}
+	activateSub := func() {
+		notifier.activate(sub.ID, req.svcname)
+	}
+	return codec.CreateResponse(req.id, sub.ID), activateSub
+}
+
+// forward relays a request of a forwarded namespace and creates the response.
+func (s *Server) forward(ctx context.Context, codec ServerCodec, req *serverRequest) interface{} {
+	params, _ := req.params.(json.RawMessage)
+	reply, rpcErr := invoke(ctx, reflect.ValueOf(req.forward.Forward), []reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req.method), reflect.ValueOf(params)})
+	if rpcErr != nil {
+		return codec.CreateErrorResponse(&req.id, rpcErr)
+	}
+	result, _ := reply[0].Interface().(json.RawMessage)
+	if err, _ := reply[1].Interface().(error); err != nil {
+		if ctx.Err() != nil { // gave up because the request is done
+			return codec.CreateErrorResponse(&req.id, contextError(ctx))
+		}
+		if rpcErr, ok := err.(Error); ok {
+			return codec.CreateErrorResponse(&req.id, rpcErr)
+		}
+		return codec.CreateErrorResponse(&req.id, &CallbackError{err.Error()})
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return codec.CreateResponse(req.id, result)
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoForwarder answers every call with the method name and raw params it got.
+type echoForwarder struct{}
+
+func (echoForwarder) Forward(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, error) {
+	if method == "up_fail" {
+		return nil, &jsonError{Code: -32000, Message: "upstream failure"}
+	}
+	return json.Marshal(map[string]interface{}{"method": method, "params": params})
+}
+
+func TestForwardedNamespace(t *testing.T) {
+	server := NewServer()
+	deny := func(method string) bool { return !strings.HasSuffix(method, "_secret") }
+	if err := server.RegisterName("up", echoForwarder{}, WithVersion("3.0"), WithMethodFilter(deny)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("up", new(ConformanceTestService)); err == nil {
+		t.Error("expected error when merging methods into a forwarded namespace")
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation|OptionSubscriptions)
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tests := []struct {
+		request, response string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"up_echo","params":[1,"a"]}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"method":"up_echo","params":[1,"a"]}}`},
+		{`{"jsonrpc":"2.0","id":2,"method":"up_echo","params":{"a":1}}`,
+			`{"jsonrpc":"2.0","id":2,"result":{"method":"up_echo","params":{"a":1}}}`},
+		{`{"jsonrpc":"2.0","id":3,"method":"up_fail","params":[]}`,
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32000}}`},
+		{`{"jsonrpc":"2.0","id":4,"method":"up_secret","params":[]}`,
+			`{"jsonrpc":"2.0","id":4,"error":{"code":-32601}}`},
+		{`{"jsonrpc":"2.0","id":5,"method":"up_subscribe","params":["blocks"]}`,
+			`{"jsonrpc":"2.0","id":5,"error":{"code":-32601}}`},
+		{`{"jsonrpc":"2.0","id":6,"method":"rpc_modules","params":[]}`,
+			`{"jsonrpc":"2.0","id":6,"result":{"rpc":"1.0","up":"3.0"}}`},
+	}
+	in := json.NewDecoder(clientConn)
+	for _, test := range tests {
+		if _, err := clientConn.Write([]byte(test.request)); err != nil {
+			t.Fatal(err)
+		}
+		var got, want interface{}
+		if err := in.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		json.Unmarshal([]byte(test.response), &want)
+		stripErrorMessages(got)
+		if !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			t.Errorf("%s:\ngot  %s\nwant %s", test.request, gotJSON, test.response)
+		}
+	}
+}
+
+func TestRegisterMethodFilter(t *testing.T) {
+	server := NewServer()
+	onlyAdd := func(method string) bool { return method == "test_add" }
+	err := server.RegisterName("test", new(ConformanceTestService), WithMethodFilter(onlyAdd), WithParamNames("greet", "name", "greeting"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, method := range server.Describe() {
+		if method.Namespace == "test" {
+			names = append(names, method.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"test_add"}) {
+		t.Errorf("unexpected methods after filtering: %v", names)
+	}
+}
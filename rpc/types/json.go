@@ -46,7 +46,7 @@ type jsonRequest struct {
 
 type jsonSuccessResponse struct {
 	Version string      `json:"jsonrpc"`
-	Id      interface{} `json:"id,omitempty"`
+	Id      interface{} `json:"id"`
 	Result  interface{} `json:"result"`
 }
 
@@ -58,7 +58,7 @@ type jsonError struct {
 
 type jsonErrResponse struct {
 	Version string      `json:"jsonrpc"`
-	Id      interface{} `json:"id,omitempty"`
+	Id      interface{} `json:"id"`
 	Error   jsonError   `json:"error"`
 }
 
@@ -166,6 +166,13 @@ func checkReqId(reqId json.RawMessage) error {
 	return fmt.Errorf("invalid request id")
 }
 
+// isNotification returns true when the request carries no id member at all. Per
+// JSON-RPC 2.0 such a request is a notification which must not be answered. An
+// explicit null id is a regular request.
+func isNotification(reqId json.RawMessage) bool {
+	return len(reqId) == 0
+}
+
 // parseRequest will parse a single request from the given RawMessage. It will return
 // the parsed request, an indication if the request was a batch or an error when
 // the request could not be parsed.
@@ -175,13 +182,16 @@ func parseRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error) {
 		return nil, false, &InvalidMessageError{err.Error()}
 	}
 
-	if err := checkReqId(in.Id); err != nil {
-		return nil, false, &InvalidMessageError{err.Error()}
+	notify := isNotification(in.Id)
+	if !notify {
+		if err := checkReqId(in.Id); err != nil {
+			return []rpcRequest{{err: &InvalidRequestError{err.Error()}}}, false, nil
+		}
 	}
 
 	// subscribe are special, they will always use `subscribeMethod` as first param in the payload
 	if strings.HasSuffix(in.Method, SubscribeMethodSuffix) {
-		reqs := []rpcRequest{{id: &in.Id, isPubSub: true}}
+		reqs := []rpcRequest{{id: &in.Id, isPubSub: true, isNotify: notify}}
 		if len(in.Payload) > 0 {
 			// first param must be subscription name
 			var subscribeMethod [1]string
@@ -198,21 +208,21 @@ func parseRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error) {
 	}
 
 	if strings.HasSuffix(in.Method, UnsubscribeMethodSuffix) {
-		return []rpcRequest{{id: &in.Id, isPubSub: true,
+		return []rpcRequest{{id: &in.Id, isPubSub: true, isNotify: notify,
 			method: in.Method, params: in.Payload}}, false, nil
 	}
 
 	elems := strings.Split(in.Method, ServiceMethodSeparator)
 	if len(elems) != 2 {
-		return nil, false, &MethodNotFoundError{in.Method, ""}
+		return []rpcRequest{{id: &in.Id, isNotify: notify, err: &MethodNotFoundError{in.Method, ""}}}, false, nil
 	}
 
 	// regular RPC call
 	if len(in.Payload) == 0 {
-		return []rpcRequest{{service: elems[0], method: elems[1], id: &in.Id}}, false, nil
+		return []rpcRequest{{service: elems[0], method: elems[1], id: &in.Id, isNotify: notify}}, false, nil
 	}
 
-	return []rpcRequest{{service: elems[0], method: elems[1], id: &in.Id, params: in.Payload}}, false, nil
+	return []rpcRequest{{service: elems[0], method: elems[1], id: &in.Id, isNotify: notify, params: in.Payload}}, false, nil
 }
 
 // parseBatchRequest will parse a batch request into a collection of requests from the given RawMessage, an indication
@@ -222,18 +232,25 @@ func parseBatchRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error)
 	if err := json.Unmarshal(incomingMsg, &in); err != nil {
 		return nil, false, &InvalidMessageError{err.Error()}
 	}
+	if len(in) == 0 {
+		return []rpcRequest{{err: &InvalidRequestError{"empty batch"}}}, false, nil
+	}
 
 	requests := make([]rpcRequest, len(in))
 	for i, r := range in {
-		if err := checkReqId(r.Id); err != nil {
-			return nil, false, &InvalidMessageError{err.Error()}
+		notify := isNotification(r.Id)
+		if !notify {
+			if err := checkReqId(r.Id); err != nil {
+				requests[i] = rpcRequest{err: &InvalidRequestError{err.Error()}}
+				continue
+			}
 		}
 
 		id := &in[i].Id
 
 		// subscribe are special, they will always use `subscriptionMethod` as first param in the payload
 		if strings.HasSuffix(r.Method, SubscribeMethodSuffix) {
-			requests[i] = rpcRequest{id: id, isPubSub: true}
+			requests[i] = rpcRequest{id: id, isPubSub: true, isNotify: notify}
 			if len(r.Payload) > 0 {
 				// first param must be subscription name
 				var subscribeMethod [1]string
@@ -251,14 +268,14 @@ func parseBatchRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error)
 		}
 
 		if strings.HasSuffix(r.Method, UnsubscribeMethodSuffix) {
-			requests[i] = rpcRequest{id: id, isPubSub: true, method: r.Method, params: r.Payload}
+			requests[i] = rpcRequest{id: id, isPubSub: true, isNotify: notify, method: r.Method, params: r.Payload}
 			continue
 		}
 
 		if len(r.Payload) == 0 {
-			requests[i] = rpcRequest{id: id, params: nil}
+			requests[i] = rpcRequest{id: id, isNotify: notify, params: nil}
 		} else {
-			requests[i] = rpcRequest{id: id, params: r.Payload}
+			requests[i] = rpcRequest{id: id, isNotify: notify, params: r.Payload}
 		}
 		if elem := strings.Split(r.Method, ServiceMethodSeparator); len(elem) == 2 {
 			requests[i].service, requests[i].method = elem[0], elem[1]
@@ -271,15 +288,73 @@ func parseBatchRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error)
 }
 
 // ParseRequestArguments tries to parse the given params (json.RawMessage) with the given
-// types. It returns the parsed values or an error when the parsing failed.
-func (c *jsonCodec) ParseRequestArguments(argTypes []reflect.Type, params interface{}) ([]reflect.Value, Error) {
-	if args, ok := params.(json.RawMessage); !ok {
+// types. Params are either positional (array) or, when argNames is given, by-name (object).
+// It returns the parsed values or an error when the parsing failed.
+func (c *jsonCodec) ParseRequestArguments(argTypes []reflect.Type, argNames []string, params interface{}) ([]reflect.Value, Error) {
+	args, ok := params.(json.RawMessage)
+	if !ok {
 		return nil, &InvalidParamsError{"Invalid params supplied"}
-	} else {
-		str := argTypes[0].String()
-		fmt.Println(str)
-		return parsePositionalArguments(args, argTypes)
 	}
+	if isObject(args) {
+		if argNames == nil {
+			return nil, &InvalidParamsError{"method does not support by-name params"}
+		}
+		return parseNamedArguments(args, argTypes, argNames)
+	}
+	return parsePositionalArguments(args, argTypes)
+}
+
+// isObject returns true when the first non-whitespace characters is '{'
+func isObject(msg json.RawMessage) bool {
+	for _, c := range msg {
+		// skip insignificant whitespace (http://www.ietf.org/rfc/rfc4627.txt)
+		if c == 0x20 || c == 0x09 || c == 0x0a || c == 0x0d {
+			continue
+		}
+		return c == '{'
+	}
+	return false
+}
+
+// isNull reports whether the raw argument is the JSON null, which only optional
+// (pointer) arguments accept.
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// parseNamedArguments tries to parse the given object args to values with the given types,
+// matching the object keys to the given parameter names. Unknown keys are rejected and
+// missing optional (pointer) arguments are returned as reflect.Zero values.
+func parseNamedArguments(rawArgs json.RawMessage, types []reflect.Type, names []string) ([]reflect.Value, Error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rawArgs, &fields); err != nil {
+		return nil, &InvalidParamsError{err.Error()}
+	}
+	args := make([]reflect.Value, len(types))
+	for i, name := range names {
+		raw, ok := fields[name]
+		if !ok {
+			if types[i].Kind() != reflect.Ptr {
+				return nil, &InvalidParamsError{fmt.Sprintf("missing value for required argument %s", name)}
+			}
+			args[i] = reflect.Zero(types[i])
+			continue
+		}
+		delete(fields, name)
+
+		if isNull(raw) && types[i].Kind() != reflect.Ptr {
+			return nil, &InvalidParamsError{fmt.Sprintf("missing value for required argument %s", name)}
+		}
+		argval := reflect.New(types[i])
+		if err := json.Unmarshal(raw, argval.Interface()); err != nil {
+			return nil, &InvalidParamsError{fmt.Sprintf("invalid argument %s: %v", name, err)}
+		}
+		args[i] = argval.Elem()
+	}
+	for name := range fields {
+		return nil, &InvalidParamsError{fmt.Sprintf("unknown argument %s", name)}
+	}
+	return args, nil
 }
 
 // parsePositionalArguments tries to parse the given args to an array of values with the
@@ -297,13 +372,17 @@ func parsePositionalArguments(rawArgs json.RawMessage, types []reflect.Type) ([]
 		if i >= len(types) {
 			return nil, &InvalidParamsError{fmt.Sprintf("too many arguments, want at most %d", len(types))}
 		}
-		argval := reflect.New(types[i])
-		if err := dec.Decode(argval.Interface()); err != nil {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
 			return nil, &InvalidParamsError{fmt.Sprintf("invalid argument %d: %v", i, err)}
 		}
-		if argval.IsNil() && types[i].Kind() != reflect.Ptr {
+		if isNull(raw) && types[i].Kind() != reflect.Ptr {
 			return nil, &InvalidParamsError{fmt.Sprintf("missing value for required argument %d", i)}
 		}
+		argval := reflect.New(types[i])
+		if err := json.Unmarshal(raw, argval.Interface()); err != nil {
+			return nil, &InvalidParamsError{fmt.Sprintf("invalid argument %d: %v", i, err)}
+		}
 		args = append(args, argval.Elem())
 	}
 	// Read end of args array.
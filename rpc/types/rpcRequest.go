@@ -7,5 +7,6 @@ type rpcRequest struct {
 	id       interface{}
 	isPubSub bool
 	params   interface{}
+	isNotify bool  // JSON-RPC 2.0 notification, the request has no id
 	err      Error // invalid batch element
 }
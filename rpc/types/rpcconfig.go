@@ -14,6 +14,8 @@ const (
 	DefaultHTTPPort = 15645       // Default TCP port for the HTTP RPC server
 	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
 	DefaultWSPort   = 15646       // Default TCP port for the websocket RPC server
+	DefaultTCPHost  = "localhost" // Default host interface for the raw TCP RPC server
+	DefaultTCPPort  = 15647       // Default TCP port for the raw TCP RPC server
 	DefaultRestHost = "localhost" // Default host interface for the REST RPC server
 	DefaultRestPort = 55550       // Default TCP port for the REST RPC server
 )
@@ -115,6 +117,23 @@ type RpcConfig struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `json:"WSExposeAll"`
 
+	// TCPEnabled
+	TCPEnabled bool `json:"TCPEnabled"`
+	// TCPHost is the host interface on which to start the raw TCP RPC server, which
+	// exchanges newline-delimited JSON-RPC messages. If this field is empty, no TCP
+	// API endpoint will be started.
+	TCPHost string `json:"TCPHost,omitempty"`
+
+	// TCPPort is the TCP port number on which to start the raw TCP RPC server. The
+	// default zero value is/ valid and will pick a port number randomly (useful for
+	// ephemeral nodes).
+	TCPPort int `json:"TCPPort"`
+
+	// TCPModules is a list of API modules to expose via the raw TCP RPC interface.
+	// If the module list is empty, all RPC API endpoints designated public will be
+	// exposed.
+	TCPModules []string `json:"TCPModules,omitempty"`
+
 	// RESTEnabled
 	RESTEnabled bool `json:"RESTEnabled"`
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
@@ -130,6 +149,14 @@ type RpcConfig struct {
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
 	RESTCors []string `json:"RESTCors,omitempty"`
+
+	// AccessLog configures the structured per call access log shared by all
+	// RPC endpoints.
+	AccessLog AccessLogConfig `json:"AccessLog"`
+
+	// ExecTimeouts limits the execution time of RPC methods on all endpoints. It
+	// overrides the timeouts the services declare for their own methods.
+	ExecTimeouts ExecTimeouts `json:"ExecTimeouts"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -201,3 +228,18 @@ func DefaultWSEndpoint() string {
 	config := &RpcConfig{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
 	return config.WSEndpoint()
 }
+
+// TCPEndpoint resolves a raw TCP endpoint based on the configured host interface
+// and port parameters.
+func (c *RpcConfig) TCPEndpoint() string {
+	if c.TCPHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.TCPHost, c.TCPPort)
+}
+
+// DefaultTCPEndpoint returns the raw TCP endpoint used by default.
+func DefaultTCPEndpoint() string {
+	config := &RpcConfig{TCPHost: DefaultTCPHost, TCPPort: DefaultTCPPort}
+	return config.TCPEndpoint()
+}
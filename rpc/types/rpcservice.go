@@ -9,8 +9,19 @@ type RPCService struct {
 // Modules returns the list of RPC services with their version number
 func (s *RPCService) Modules() map[string]string {
 	modules := make(map[string]string)
-	for name := range s.server.services {
-		modules[name] = "1.0"
+	for name, svc := range s.server.services {
+		modules[name] = svc.version
 	}
 	return modules
 }
+
+// Describe returns every registered method with its parameter and result types
+func (s *RPCService) Describe() []MethodDescription {
+	return s.server.Describe()
+}
+
+// Openrpc returns an OpenRPC document describing all registered methods, it is
+// exposed as rpc_openrpc.
+func (s *RPCService) Openrpc() *OpenRPCDocument {
+	return s.server.OpenRPC(OpenRPCInfo{Title: "drep", Version: DefaultApiVersion})
+}
@@ -32,6 +32,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/drep-project/drepcli/log"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
@@ -79,6 +80,7 @@ type callback struct {
 	rcvr        reflect.Value  // receiver of method
 	method      reflect.Method // callback
 	argTypes    []reflect.Type // input argument types
+	argNames    []string       // input argument names, nil when the method only accepts positional params
 	hasCtx      bool           // method's first argument is a context (not included in argTypes)
 	errPos      int            // err return idx, of -1 when method cannot return error
 	isSubscribe bool           // indication if the callback is a subscription
@@ -86,19 +88,29 @@ type callback struct {
 
 // service represents a registered object
 type service struct {
-	name          string        // name for service
-	typ           reflect.Type  // receiver type
-	callbacks     callbacks     // registered handlers
-	subscriptions subscriptions // available subscriptions/notifications
+	name          string                   // name for service
+	version       string                   // api version of the service
+	typ           reflect.Type             // receiver type
+	callbacks     callbacks                // registered handlers
+	subscriptions subscriptions            // available subscriptions/notifications
+	forwarder     Forwarder                // handles all methods of the namespace if set
+	filter        MethodFilter             // methods the forwarder may be called with, nil allows all
+	timeouts      map[string]time.Duration // method name => execution timeout, "" for all methods
 }
 
 // serverRequest is an incoming request
 type serverRequest struct {
 	id            interface{}
+	method        string      // full method name as requested, used for access logging
+	params        interface{} // raw request parameters
 	svcname       string
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
+	isNotify      bool          // request without id, no response is sent
+	isSubscribe   bool          // subscription request of a forwarded namespace
+	forward       Forwarder     // set for requests of forwarded namespaces
+	timeout       time.Duration // execution timeout, zero for none
 	err           Error
 }
 
@@ -108,7 +120,8 @@ type subscriptions map[string]*callback  // collection of subscription callbacks
 
 // Server represents a RPC server
 type Server struct {
-	services serviceRegistry
+	services  serviceRegistry
+	accessLog atomic.Value // *AccessLog
 
 	run      int32
 	codecsMu sync.Mutex
@@ -131,10 +144,41 @@ func NewServer() *Server {
 	return server
 }
 
+// RegisterOption customizes how the methods of a service are exposed.
+type RegisterOption func(*registerOptions)
+
+type registerOptions struct {
+	version    string                   // api version reported by rpc_modules
+	paramNames map[string][]string      // method name => parameter names
+	filter     MethodFilter             // methods to expose, nil exposes all
+	timeouts   map[string]time.Duration // method name => execution timeout, "" for all methods
+}
+
+// WithVersion sets the api version the service is reported with. Services
+// registered without a version report DefaultApiVersion.
+func WithVersion(version string) RegisterOption {
+	return func(opts *registerOptions) {
+		opts.version = version
+	}
+}
+
+// WithParamNames declares the parameter names of a method, which enables
+// calling it with by-name (object) params. Reflection doesn't expose parameter
+// names, so they have to be given in the order of the Go method signature,
+// excluding the optional context.Context argument.
+func WithParamNames(method string, names ...string) RegisterOption {
+	return func(opts *registerOptions) {
+		if opts.paramNames == nil {
+			opts.paramNames = make(map[string][]string)
+		}
+		opts.paramNames[formatName(method)] = names
+	}
+}
+
 // RegisterName will create a service for the given rcvr type under the given name. When no methods on the given rcvr
 // match the criteria to be either a RPC method or a subscription an error is returned. Otherwise a new service is
 // created and added to the service collection this server instance serves.
-func (s *Server) RegisterName(name string, rcvr interface{}) error {
+func (s *Server) RegisterName(name string, rcvr interface{}, opts ...RegisterOption) error {
 	if s.services == nil {
 		s.services = make(serviceRegistry)
 	}
@@ -146,6 +190,14 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 	if name == "" {
 		return fmt.Errorf("no service name for type %s", svc.typ.String())
 	}
+	options := &registerOptions{version: DefaultApiVersion}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if forwarder, ok := rcvr.(Forwarder); ok {
+		return s.registerForwarder(name, forwarder, options)
+	}
+
 	if !isExported(reflect.Indirect(rcvrVal).Type().Name()) {
 		return fmt.Errorf("%s is not exported", reflect.Indirect(rcvrVal).Type().Name())
 	}
@@ -155,20 +207,61 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 	if len(methods) == 0 && len(subscriptions) == 0 {
 		return fmt.Errorf("Service %T doesn't have any suitable methods/subscriptions to expose", rcvr)
 	}
+	if options.filter != nil {
+		filterCallbacks(name, methods, ServiceMethodSeparator, options.filter)
+		filterCallbacks(name, subscriptions, SubscribeMethodSuffix+".", options.filter)
+		if len(methods) == 0 && len(subscriptions) == 0 {
+			return nil // nothing left to expose
+		}
+	}
+
+	for method, names := range options.paramNames {
+		callb, ok := methods[method]
+		if !ok && options.filter != nil && !options.filter(name+ServiceMethodSeparator+method) {
+			continue // filtered out
+		}
+		if !ok {
+			return fmt.Errorf("parameter names given for unknown method %s%s%s", name, ServiceMethodSeparator, method)
+		}
+		if len(names) != len(callb.argTypes) {
+			return fmt.Errorf("%s%s%s has %d parameters, got %d names", name, ServiceMethodSeparator, method, len(callb.argTypes), len(names))
+		}
+		callb.argNames = names
+	}
+	for method := range options.timeouts {
+		if _, ok := methods[method]; ok || method == "" {
+			continue
+		}
+		if options.filter != nil && !options.filter(name+ServiceMethodSeparator+method) {
+			continue // filtered out
+		}
+		return fmt.Errorf("timeout given for unknown method %s%s%s", name, ServiceMethodSeparator, method)
+	}
 
 	// already a previous service register under given name, merge methods/subscriptions
 	if regsvc, present := s.services[name]; present {
+		if regsvc.forwarder != nil {
+			return fmt.Errorf("namespace %s is forwarded", name)
+		}
 		for _, m := range methods {
 			regsvc.callbacks[formatName(m.method.Name)] = m
 		}
 		for _, s := range subscriptions {
 			regsvc.subscriptions[formatName(s.method.Name)] = s
 		}
+		for method, timeout := range options.timeouts {
+			if regsvc.timeouts == nil {
+				regsvc.timeouts = make(map[string]time.Duration)
+			}
+			regsvc.timeouts[method] = timeout
+		}
 		return nil
 	}
 
 	svc.name = name
+	svc.version = options.version
 	svc.callbacks, svc.subscriptions = methods, subscriptions
+	svc.timeouts = options.timeouts
 
 	s.services[svc.name] = svc
 	return nil
@@ -199,6 +292,15 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// cancel pending requests once the codec is closed
+	go func(done <-chan struct{}) {
+		select {
+		case <-codec.Closed():
+			cancel()
+		case <-done:
+		}
+	}(ctx.Done())
+
 	// if the codec supports notification include a notifier that callbacks can use
 	// to send notification to clients. It is tied to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
@@ -222,7 +324,9 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 				log.Debug(fmt.Sprintf("read error %v\n", err))
 				codec.Write(codec.CreateErrorResponse(nil, err))
 			}
-			// Error or end of stream, wait for requests and tear down
+			// Error or end of stream, wait for requests and tear down. A client
+			// which only closed its side still receives the responses, pending
+			// requests are cancelled once the codec is closed.
 			pend.Wait()
 			return nil
 		}
@@ -270,8 +374,13 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(withTransport(context.Background(), TransportInProc, ""), codec, options)
+}
+
+// serveCodec is ServeCodec with a connection context carrying the transport details.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(context.Background(), codec, false, options)
+	s.serveRequest(ctx, codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
@@ -315,6 +424,15 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
 
+	if req.forward != nil && req.isSubscribe {
+		return s.forwardSubscribe(ctx, codec, req)
+	}
+	if req.forward != nil {
+		ctx, cancel := withTimeout(ctx, req.timeout)
+		defer cancel()
+		return s.forward(ctx, codec, req), nil
+	}
+
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
 			notifier, supported := NotifierFromContext(ctx)
@@ -355,6 +473,8 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	ctx, cancel := withTimeout(ctx, req.timeout)
+	defer cancel()
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -364,13 +484,23 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
-	reply := req.callb.method.Func.Call(arguments)
+	reply, rpcErr := invoke(ctx, req.callb.method.Func, arguments)
+	if rpcErr != nil {
+		return codec.CreateErrorResponse(&req.id, rpcErr), nil
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
+			if ctx.Err() != nil { // the method gave up because the request is done
+				return codec.CreateErrorResponse(&req.id, contextError(ctx)), nil
+			}
+			// errors carrying their own code are passed on as they are
 			e := reply[req.callb.errPos].Interface().(error)
+			if rpcErr, ok := e.(Error); ok {
+				return codec.CreateErrorResponse(&req.id, rpcErr), nil
+			}
 			res := codec.CreateErrorResponse(&req.id, &CallbackError{e.Error()})
 			return res, nil
 		}
@@ -382,15 +512,23 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
 	var callback func()
+	ctx = withRequestID(ctx)
+	start := time.Now()
 	if req.err != nil {
 		response = codec.CreateErrorResponse(&req.id, req.err)
 	} else {
 		response, callback = s.handle(ctx, codec, req)
 	}
+	if accessLog := s.getAccessLog(); accessLog != nil {
+		accessLog.record(ctx, req, start, response)
+	}
 
-	if err := codec.Write(response); err != nil {
-		log.Error(fmt.Sprintf("%v\n", err))
-		codec.Close()
+	// notifications are executed, but never answered
+	if !req.isNotify {
+		if err := codec.Write(response); err != nil {
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+		}
 	}
 
 	// when request was a subscribe request this allows these subscriptions to be actived
@@ -402,22 +540,34 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 // execBatch executes the given requests and writes the result back using the codec.
 // It will only write the response back when the last request is processed.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
-	responses := make([]interface{}, len(requests))
+	responses := make([]interface{}, 0, len(requests))
 	var callbacks []func()
-	for i, req := range requests {
+	for _, req := range requests {
+		var response interface{}
+		reqCtx := withRequestID(ctx)
+		start := time.Now()
 		if req.err != nil {
-			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
+			response = codec.CreateErrorResponse(&req.id, req.err)
 		} else {
 			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
+			if response, callback = s.handle(reqCtx, codec, req); callback != nil {
 				callbacks = append(callbacks, callback)
 			}
 		}
+		if accessLog := s.getAccessLog(); accessLog != nil {
+			accessLog.record(reqCtx, req, start, response)
+		}
+		if !req.isNotify {
+			responses = append(responses, response)
+		}
 	}
 
-	if err := codec.Write(responses); err != nil {
-		log.Error(fmt.Sprintf("%v\n", err))
-		codec.Close()
+	// a batch of notifications only is not answered at all
+	if len(responses) > 0 {
+		if err := codec.Write(responses); err != nil {
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+		}
 	}
 
 	// when request holds one of more subscribe requests this allows these subscriptions to be activated
@@ -450,7 +600,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		if r.isPubSub && strings.HasSuffix(r.method, UnsubscribeMethodSuffix) {
 			requests[i] = &serverRequest{id: r.id, isUnsubscribe: true}
 			argTypes := []reflect.Type{reflect.TypeOf("")} // expect subscription id as first arg
-			if args, err := codec.ParseRequestArguments(argTypes, r.params); err == nil {
+			if args, err := codec.ParseRequestArguments(argTypes, nil, r.params); err == nil {
 				requests[i].args = args
 			} else {
 				requests[i].err = &InvalidParamsError{err.Error()}
@@ -463,13 +613,24 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 			continue
 		}
 
+		if svc.forwarder != nil { // forwarded as is
+			if r.isPubSub {
+				requests[i] = s.forwardedSubscription(svc, r)
+			} else if svc.filter != nil && !svc.filter(r.service+ServiceMethodSeparator+r.method) {
+				requests[i] = &serverRequest{id: r.id, err: &MethodNotFoundError{r.service, r.method}}
+			} else {
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, forward: svc.forwarder, timeout: svc.timeout(r.method)}
+			}
+			continue
+		}
+
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
 				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
-					if args, err := codec.ParseRequestArguments(argTypes, r.params); err == nil {
+					if args, err := codec.ParseRequestArguments(argTypes, nil, r.params); err == nil {
 						requests[i].args = args[1:] // first one is service.method name which isn't an actual argument
 					} else {
 						requests[i].err = &InvalidParamsError{err.Error()}
@@ -482,9 +643,9 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb, timeout: svc.timeout(r.method)}
 			if r.params != nil && len(callb.argTypes) > 0 {
-				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
+				if args, err := codec.ParseRequestArguments(callb.argTypes, callb.argNames, r.params); err == nil {
 					requests[i].args = args
 				} else {
 					requests[i].err = &InvalidParamsError{err.Error()}
@@ -496,9 +657,28 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		requests[i] = &serverRequest{id: r.id, err: &MethodNotFoundError{r.service, r.method}}
 	}
 
+	// keep the requested name and raw params around for the access log
+	for i, r := range reqs {
+		requests[i].method, requests[i].params = requestMethodName(r), r.params
+		requests[i].isNotify = r.isNotify
+	}
 	return requests, batch, nil
 }
 
+// requestMethodName returns the full method name of a raw request as the client sent it.
+func requestMethodName(r rpcRequest) string {
+	switch {
+	case r.isPubSub && strings.HasSuffix(r.method, UnsubscribeMethodSuffix):
+		return r.method
+	case r.isPubSub:
+		return r.service + SubscribeMethodSuffix
+	case r.service == "":
+		return r.method
+	default:
+		return r.service + ServiceMethodSeparator + r.method
+	}
+}
+
 // ServeHTTP serves JSON-RPC requests over HTTP.
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Permit dumb empty requests for remote health-checks (AWS)
@@ -512,8 +692,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
-	ctx := r.Context()
-	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
+	ctx := withTransport(r.Context(), TransportHTTP, r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
 	if ua := r.Header.Get("User-Agent"); ua != "" {
@@ -524,13 +703,28 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	body := io.LimitReader(r.Body, maxRequestContentLength)
-	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
+	var codec ServerCodec
+	if requestContentType(r) == ContentTypeCBOR {
+		codec = newCBORHTTPCodec(&httpReadWriteNopCloser{body, w})
+		w.Header().Set("content-type", ContentTypeCBOR)
+	} else {
+		codec = NewJSONCodec(&httpReadWriteNopCloser{body, w})
+		w.Header().Set("content-type", ContentType)
+	}
 	defer codec.Close()
 
-	w.Header().Set("content-type", ContentType)
 	srv.ServeSingleRequest(ctx, codec, OptionMethodInvocation)
 }
 
+// requestContentType returns the media type of the request body.
+func requestContentType(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("content-type"))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
 // validateRequest returns a non-zero response code and error message if the
 // request is invalid.
 func validateRequest(r *http.Request) (int, error) {
@@ -541,9 +735,9 @@ func validateRequest(r *http.Request) (int, error) {
 		err := fmt.Errorf("content length too large (%d>%d)", r.ContentLength, maxRequestContentLength)
 		return http.StatusRequestEntityTooLarge, err
 	}
-	mt, _, err := mime.ParseMediaType(r.Header.Get("content-type"))
-	if r.Method != http.MethodOptions && (err != nil || mt != ContentType) {
-		err := fmt.Errorf("invalid content type, only %s is supported", ContentType)
+	mt := requestContentType(r)
+	if r.Method != http.MethodOptions && mt != ContentType && mt != ContentTypeCBOR {
+		err := fmt.Errorf("invalid content type, only %s and %s are supported", ContentType, ContentTypeCBOR)
 		return http.StatusUnsupportedMediaType, err
 	}
 	return 0, nil
@@ -571,8 +765,24 @@ func (srv *Server) ServeListener(l net.Listener) error {
 			return err
 		}
 		log.Trace("Accepted connection", "addr", conn.RemoteAddr())
-		go srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+		transport := TransportIPC
+		if _, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			transport = TransportTCP
+		}
+		ctx := withTransport(context.Background(), transport, conn.RemoteAddr().String())
+		go srv.serveStream(ctx, conn)
+	}
+}
+
+// serveStream serves a stream connection with the codec the client opened it with.
+func (srv *Server) serveStream(ctx context.Context, conn net.Conn) {
+	codec, err := newStreamCodec(conn)
+	if err != nil {
+		log.Debug("RPC codec negotiation failed", "addr", conn.RemoteAddr(), "err", err)
+		conn.Close()
+		return
 	}
+	srv.serveCodec(ctx, codec, OptionMethodInvocation|OptionSubscriptions)
 }
 
 // WebsocketHandler returns a handler that serves JSON-RPC to WebSocket connections.
@@ -586,13 +796,18 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			// Create a custom encode/decode pair to enforce payload size and number encoding
 			conn.MaxPayloadBytes = maxRequestContentLength
 
+			codec := websocketJSONCodec
+			if protocols := conn.Config().Protocol; len(protocols) == 1 && protocols[0] == CBORSubprotocol {
+				codec = websocketCBORCodec
+			}
 			encoder := func(v interface{}) error {
-				return websocketJSONCodec.Send(conn, v)
+				return codec.Send(conn, v)
 			}
 			decoder := func(v interface{}) error {
-				return websocketJSONCodec.Receive(conn, v)
+				return codec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			ctx := withTransport(context.Background(), TransportWS, conn.Request().RemoteAddr)
+			srv.serveCodec(ctx, NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -626,6 +841,7 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 	f := func(cfg *websocket.Config, req *http.Request) error {
 		origin := strings.ToLower(req.Header.Get("Origin"))
 		if allowAllOrigins || origins.Contains(origin) {
+			selectSubprotocol(cfg)
 			return nil
 		}
 		log.Warn(fmt.Sprintf("origin '%s' not allowed on WS-RPC interface\n", origin))
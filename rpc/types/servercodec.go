@@ -10,8 +10,8 @@ import (
 type ServerCodec interface {
 	// Read next request
 	ReadRequestHeaders() ([]rpcRequest, bool, Error)
-	// Parse request argument to the given types
-	ParseRequestArguments(argTypes []reflect.Type, params interface{}) ([]reflect.Value, Error)
+	// Parse request argument to the given types, argNames is nil if the method doesn't accept by-name params
+	ParseRequestArguments(argTypes []reflect.Type, argNames []string, params interface{}) ([]reflect.Value, Error)
 	// Assemble success response, expects response id and payload
 	CreateResponse(id interface{}, reply interface{}) interface{}
 	// Assemble error response, expects response id and error
@@ -295,16 +295,16 @@ func TestSubscriptionMultipleNamespaces(t *testing.T) {
 		case err := <-errors:
 			t.Fatal(err)
 		case failure := <-failures:
-			t.Error("received error: %v", failure.Error)
+			t.Errorf("received error: %v", failure.Error)
 		case <-timeout:
 			for _, namespace := range namespaces {
 				subid, found := subids[namespace]
 				if !found {
-					t.Error("subscription for %q not created", namespace)
+					t.Errorf("subscription for %q not created", namespace)
 					continue
 				}
 				if count, found := count[subid]; !found || count < notificationCount {
-					t.Error("didn't receive all notifications (%d<%d) in time for namespace %q", count, notificationCount, namespace)
+					t.Errorf("didn't receive all notifications (%d<%d) in time for namespace %q", count, notificationCount, namespace)
 				}
 			}
 			t.Fatal("timed out")
@@ -0,0 +1,118 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/drep-project/drepcli/log"
+)
+
+// ExecTimeouts limits how long service methods may run before the call is
+// answered with a TimeoutError. Zero durations mean no limit.
+type ExecTimeouts struct {
+	// Default applies to every method without a more specific timeout.
+	Default time.Duration `json:"Default"`
+
+	// Methods maps namespaces ("chain") and methods ("chain_getBlock") to their
+	// timeout. A method's own timeout wins over the one of its namespace.
+	Methods map[string]time.Duration `json:"Methods,omitempty"`
+}
+
+// WithTimeout limits the execution time of a method of the service. An empty
+// method sets the timeout of all methods without their own. Subscriptions are
+// not limited, they live as long as the connection.
+func WithTimeout(method string, timeout time.Duration) RegisterOption {
+	return func(opts *registerOptions) {
+		if opts.timeouts == nil {
+			opts.timeouts = make(map[string]time.Duration)
+		}
+		if method != "" {
+			method = formatName(method)
+		}
+		opts.timeouts[method] = timeout
+	}
+}
+
+// timeout returns the execution timeout of the method, zero if there is none.
+func (s *service) timeout(method string) time.Duration {
+	if timeout, ok := s.timeouts[method]; ok {
+		return timeout
+	}
+	return s.timeouts[""]
+}
+
+// withTimeout derives the context a request is executed with.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// invoke calls the method. Without a deadline on ctx it runs inline. With one
+// it runs in its own goroutine, so a method which doesn't observe its context
+// can't hold the request past the timeout. Such a method keeps running in the
+// background until it returns, its goroutine is leaked until then and its
+// result is dropped.
+func invoke(ctx context.Context, method reflect.Value, args []reflect.Value) ([]reflect.Value, Error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return call(method, args)
+	}
+	type result struct {
+		reply []reflect.Value
+		err   Error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := call(method, args)
+		done <- result{reply, err}
+	}()
+	select {
+	case res := <-done:
+		return res.reply, res.err
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+}
+
+// call calls the method and turns a panic into an error.
+func call(method reflect.Value, args []reflect.Value) (reply []reflect.Value, rpcErr Error) {
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Error(string(buf))
+			rpcErr = &CallbackError{fmt.Sprintf("method handler crashed: %v", err)}
+		}
+	}()
+	return method.Call(args), nil
+}
+
+// contextError converts the error of a done request context to the error sent
+// to the client.
+func contextError(ctx context.Context) Error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return &TimeoutError{}
+	}
+	return &CallbackError{"request canceled"}
+}
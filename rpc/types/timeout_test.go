@@ -0,0 +1,192 @@
+// Copyright 2018 DREP Foundation Ltd.
+// This file is part of the drep-cli library.
+//
+// The drep-cli library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The drep-cli library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the drep-cli library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type TimeoutTestService struct {
+	cancelled chan error // receives the context error of interrupted Wait calls
+	unblock   chan struct{}
+}
+
+// Wait returns after ms milliseconds or when the request is done.
+func (s *TimeoutTestService) Wait(ctx context.Context, ms int) (bool, error) {
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return true, nil
+	case <-ctx.Done():
+		s.cancelled <- ctx.Err()
+		return false, ctx.Err()
+	}
+}
+
+// Hang blocks until the test unblocks it, ignoring the request context.
+func (s *TimeoutTestService) Hang() bool {
+	<-s.unblock
+	return true
+}
+
+func newTimeoutTestService() *TimeoutTestService {
+	return &TimeoutTestService{cancelled: make(chan error, 10), unblock: make(chan struct{})}
+}
+
+func TestExecTimeout(t *testing.T) {
+	service := newTimeoutTestService()
+	defer close(service.unblock)
+	server := NewServer()
+	err := server.RegisterName("test", service, WithTimeout("", 50*time.Millisecond), WithTimeout("wait", 500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tests := []struct {
+		request, response string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"test_hang","params":[]}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32002}}`},
+		{`{"jsonrpc":"2.0","id":2,"method":"test_wait","params":[100]}`,
+			`{"jsonrpc":"2.0","id":2,"result":true}`},
+		{`{"jsonrpc":"2.0","id":3,"method":"test_wait","params":[2000]}`,
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32002}}`},
+	}
+	in := json.NewDecoder(clientConn)
+	for _, test := range tests {
+		if _, err := clientConn.Write([]byte(test.request)); err != nil {
+			t.Fatal(err)
+		}
+		var got, want interface{}
+		if err := in.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		json.Unmarshal([]byte(test.response), &want)
+		stripErrorMessages(got)
+		if !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			t.Errorf("%s:\ngot  %s\nwant %s", test.request, gotJSON, test.response)
+		}
+	}
+	select {
+	case err := <-service.cancelled:
+		if err != context.DeadlineExceeded {
+			t.Errorf("method context ended with %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Error("method context wasn't cancelled on timeout")
+	}
+}
+
+func TestCancelOnClose(t *testing.T) {
+	service := newTimeoutTestService()
+	server := NewServer()
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	if _, err := clientConn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"test_wait","params":[60000]}`)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond) // let the call start
+	server.Stop()
+
+	select {
+	case err := <-service.cancelled:
+		if err != context.Canceled {
+			t.Errorf("method context ended with %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("method context wasn't cancelled when the codec was closed")
+	}
+}
+
+func TestHalfCloseAnswersPending(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", newTimeoutTestService()); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"test_wait","params":[100]}`)); err != nil {
+		t.Fatal(err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+
+	var got map[string]interface{}
+	if err := json.NewDecoder(conn).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got["result"] != true {
+		t.Errorf("got %v, want the result of the pending request", got)
+	}
+}
+
+func TestInvokeWithoutDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	method := reflect.ValueOf(func() bool {
+		time.Sleep(10 * time.Millisecond)
+		return true
+	})
+	// without a deadline the method runs inline and isn't given up on
+	reply, err := invoke(ctx, method, nil)
+	if err != nil || len(reply) != 1 || !reply[0].Bool() {
+		t.Errorf("got %v, %v, want the result of the method", reply, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := invoke(ctx, method, nil); err == nil || err.ErrorCode() != (&TimeoutError{}).ErrorCode() {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+}
+
+func TestRegisterTimeoutUnknownMethod(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", newTimeoutTestService(), WithTimeout("missing", time.Second)); err == nil {
+		t.Error("expected error for a timeout of an unknown method")
+	}
+}